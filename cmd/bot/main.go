@@ -2,19 +2,108 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
+	"time"
 
+	"github.com/brauni/booklore-tg-bot/internal/accounts"
+	"github.com/brauni/booklore-tg-bot/internal/admin"
 	"github.com/brauni/booklore-tg-bot/internal/bot"
 	"github.com/brauni/booklore-tg-bot/internal/config"
+	"github.com/brauni/booklore-tg-bot/internal/storage"
+	"github.com/brauni/booklore-tg-bot/internal/tracing"
 	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
 )
 
 func main() {
+	healthcheck := flag.Bool("healthcheck", false, "Check whether the running bot is ready and exit")
+	configFile := flag.String("config", "", "YAML config file, overridden by env variables (default $CONFIG_FILE)")
+	printConfig := flag.Bool("print-config", false, "Print the effective configuration with secrets redacted and exit")
+	checkConfig := flag.Bool("check-config", false, "Validate the configuration and exit")
+	reencrypt := flag.Bool("reencrypt", false, "Encrypt every state file in the data folder with the current key and exit")
+	flag.Parse()
+
+	// Used as the container healthcheck, since the image has no curl
+	if *healthcheck {
+		addr := config.AdminListenAddr()
+		if cfg, err := config.Read(*configFile); err == nil {
+			addr = cfg.AdminListenAddr
+		}
+		if addr == "" {
+			fmt.Println("Healthcheck needs the admin server, but ADMIN_LISTEN_ADDR is off")
+			os.Exit(1)
+		}
+		if err := admin.Probe(addr); err != nil {
+			fmt.Printf("Healthcheck failed: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if *printConfig || *checkConfig {
+		config.LoadDotEnv()
+		cfg, err := config.Read(*configFile)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if *printConfig {
+			out, err := yaml.Marshal(cfg.Redacted())
+			if err != nil {
+				fmt.Printf("Failed to print configuration: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Print(string(out))
+		} else {
+			fmt.Println("Configuration is valid")
+		}
+		os.Exit(0)
+	}
+
+	// Run with the bot stopped after rotating ENCRYPTION_KEY, then drop the previous keys
+	if *reencrypt {
+		cfg, err := config.Load(*configFile)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		cipher, err := cfg.Cipher()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if cipher == nil {
+			fmt.Println("ENCRYPTION_KEY is not set")
+			os.Exit(1)
+		}
+
+		storage.UseCipher(cipher)
+
+		// The account secrets are encrypted inside their file, so they need a pass of their own
+		accountsPath := filepath.Join(cfg.DataFolder, accounts.FileName)
+		accountCount, err := accounts.Reencrypt(cfg.Logger, accountsPath, cipher)
+		if err != nil {
+			fmt.Printf("Failed to re-encrypt the linked accounts in %s: %v\n", accountsPath, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Re-encrypted %d linked accounts\n", accountCount)
+
+		count, err := storage.ReencryptDir(cfg.DataFolder)
+		if err != nil {
+			fmt.Printf("Failed to re-encrypt %s: %v\n", cfg.DataFolder, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Re-encrypted %d files in %s\n", count, cfg.DataFolder)
+		os.Exit(0)
+	}
+
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := config.Load(*configFile)
 	if err != nil {
 		fmt.Printf("Failed to load configuration: %v\n", err)
 		os.Exit(1)
@@ -23,6 +112,24 @@ func main() {
 
 	cfg.Logger.Info("Starting Telegram File Downloader Bot")
 
+	// Export traces if an OTLP endpoint is configured
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Enabled:     cfg.Tracing.Enabled,
+		ServiceName: cfg.Tracing.ServiceName,
+	}, cfg.Logger)
+	if err != nil {
+		cfg.Logger.Fatal("Failed to set up tracing",
+			zap.Error(err))
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			cfg.Logger.Warn("Failed to flush traces",
+				zap.Error(err))
+		}
+	}()
+
 	// Create bot instance
 	botInstance, err := bot.NewBot(cfg)
 	if err != nil {
@@ -41,6 +148,16 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	// Reload the configuration on SIGHUP
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
+	go func() {
+		for range reloadChan {
+			cfg.Logger.Info("Received SIGHUP, reloading configuration")
+			botInstance.ReloadConfig()
+		}
+	}()
+
 	// Start bot in a goroutine
 	go func() {
 		if err := botInstance.Start(); err != nil {
@@ -0,0 +1,208 @@
+package accounts
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/brauni/booklore-tg-bot/internal/booklore"
+	"github.com/brauni/booklore-tg-bot/internal/storage"
+	"go.uber.org/zap"
+)
+
+// FileName is the file in the data folder holding the linked accounts
+const FileName = "booklore_accounts.json"
+
+// Account is a Booklore account a Telegram user linked with /link
+type Account struct {
+	// Username is empty when the account was linked with a personal API token
+	Username string `json:"username,omitempty"`
+	// Secret is the encrypted password, or the encrypted token if Username is empty
+	Secret   string    `json:"secret"`
+	LinkedAt time.Time `json:"linkedAt"`
+}
+
+// Store keeps the linked accounts with their secrets encrypted on disk and
+// the logged in providers in memory
+type Store struct {
+	accounts  map[int64]Account
+	providers map[int64]booklore.AuthProvider
+	mutex     sync.Mutex
+	file      *storage.JSONFile
+	cipher    *storage.Cipher
+	apiURL    string
+	logger    *zap.Logger
+}
+
+// NewStore creates an account store backed by the given file. An empty path
+// keeps the accounts in memory only.
+func NewStore(logger *zap.Logger, storagePath string, cipher *storage.Cipher, apiURL string) *Store {
+	s := &Store{
+		accounts:  make(map[int64]Account),
+		providers: make(map[int64]booklore.AuthProvider),
+		cipher:    cipher,
+		apiURL:    apiURL,
+		logger:    logger,
+	}
+
+	if storagePath == "" {
+		return s
+	}
+
+	s.file = storage.NewJSONFile(storagePath)
+	if _, err := s.file.Load(&s.accounts); err != nil {
+		logger.Error("Failed to load linked Booklore accounts",
+			zap.String("path", storagePath),
+			zap.Error(err))
+		s.accounts = make(map[int64]Account)
+	}
+	// Failures are logged per account, the other accounts keep working
+	s.reencrypt()
+
+	return s
+}
+
+// Reencrypt encrypts the secrets in the account file at path with the current
+// key of cipher and returns how many it encrypted again. Run it after a key
+// rotation, before the previous keys are dropped.
+func Reencrypt(logger *zap.Logger, path string, cipher *storage.Cipher) (int, error) {
+	s := &Store{
+		accounts: make(map[int64]Account),
+		file:     storage.NewJSONFile(path),
+		cipher:   cipher,
+		logger:   logger,
+	}
+	if _, err := s.file.Load(&s.accounts); err != nil {
+		return 0, err
+	}
+	return s.reencrypt()
+}
+
+// reencrypt encrypts secrets sealed with a previous key again with the current
+// one, so the previous key can be dropped after a rotation. It fails if a
+// secret can't be decrypted with any configured key.
+func (s *Store) reencrypt() (int, error) {
+	changed, failed := 0, 0
+	for userID, account := range s.accounts {
+		if s.cipher.IsCurrentString(account.Secret) {
+			continue
+		}
+
+		secret, err := s.cipher.DecryptString(account.Secret)
+		if err != nil {
+			s.logger.Error("Failed to decrypt linked Booklore account, the user has to link it again",
+				zap.Int64("user_id", userID),
+				zap.Error(err))
+			failed++
+			continue
+		}
+		if account.Secret, err = s.cipher.EncryptString(secret); err != nil {
+			return changed, fmt.Errorf("failed to encrypt credentials: %w", err)
+		}
+		s.accounts[userID] = account
+		changed++
+	}
+
+	if changed > 0 {
+		s.logger.Info("Encrypted linked Booklore accounts with the current key",
+			zap.Int("account_count", changed))
+		if err := s.save(); err != nil {
+			return 0, err
+		}
+	}
+	if failed > 0 {
+		return changed, fmt.Errorf("%d linked accounts can't be decrypted with the configured keys", failed)
+	}
+	return changed, nil
+}
+
+// NewProvider creates the provider for an account: a login if username is
+// set, otherwise the secret is used as an API token
+func NewProvider(apiURL, username, secret string, logger *zap.Logger) booklore.AuthProvider {
+	if username != "" {
+		return booklore.NewLoginAuth(apiURL, username, secret, logger)
+	}
+	return booklore.NewStaticToken(secret)
+}
+
+// Get returns the linked account of a user
+func (s *Store) Get(userID int64) (Account, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	account, ok := s.accounts[userID]
+	return account, ok
+}
+
+// Link stores a user's account, replacing one linked before
+func (s *Store) Link(userID int64, username, secret string) error {
+	encrypted, err := s.cipher.EncryptString(secret)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt credentials: %w", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.accounts[userID] = Account{
+		Username: username,
+		Secret:   encrypted,
+		LinkedAt: time.Now(),
+	}
+	delete(s.providers, userID)
+	return s.save()
+}
+
+// Unlink removes a user's account. It reports whether one was linked.
+func (s *Store) Unlink(userID int64) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.accounts[userID]; !ok {
+		return false, nil
+	}
+
+	delete(s.accounts, userID)
+	delete(s.providers, userID)
+	return true, s.save()
+}
+
+// Auth returns the provider acting as the user's linked account. It reports
+// false if the user has none.
+func (s *Store) Auth(userID int64) (booklore.AuthProvider, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if provider, ok := s.providers[userID]; ok {
+		return provider, true, nil
+	}
+
+	account, ok := s.accounts[userID]
+	if !ok {
+		return nil, false, nil
+	}
+
+	secret, err := s.cipher.DecryptString(account.Secret)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to decrypt credentials: %w", err)
+	}
+
+	provider := NewProvider(s.apiURL, account.Username, secret, s.logger)
+	s.providers[userID] = provider
+	return provider, true, nil
+}
+
+// save writes the accounts to disk; callers must hold the lock
+func (s *Store) save() error {
+	if s.file == nil {
+		return nil
+	}
+
+	if err := s.file.Save(s.accounts); err != nil {
+		s.logger.Error("Failed to save linked Booklore accounts",
+			zap.String("path", s.file.Path()),
+			zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/brauni/booklore-tg-bot/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// checkTimeout bounds a single readiness check
+const checkTimeout = 5 * time.Second
+
+// Check reports whether a dependency of the bot is usable
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Server serves health, readiness and metrics endpoints for monitoring
+type Server struct {
+	addr   string
+	checks []namedCheck
+	mutex  sync.RWMutex
+	logger *zap.Logger
+}
+
+// NewServer creates an admin server listening on addr, e.g. ":9090"
+func NewServer(addr string, logger *zap.Logger) *Server {
+	return &Server{
+		addr:   addr,
+		logger: logger,
+	}
+}
+
+// AddCheck registers a readiness check; /readyz fails while any check fails
+func (s *Server) AddCheck(name string, check Check) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.checks = append(s.checks, namedCheck{name: name, check: check})
+}
+
+// Handler returns the admin endpoints
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("/readyz", s.handleReady)
+	mux.Handle("/metrics", metrics.Handler())
+	return mux
+}
+
+// Run serves the admin endpoints until the context is cancelled
+func (s *Server) Run(ctx context.Context) error {
+	server := &http.Server{
+		Addr:              s.addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	s.logger.Info("Admin server listening",
+		zap.String("addr", s.addr))
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("admin server failed: %w", err)
+	}
+	return nil
+}
+
+// handleReady runs every check and reports their results
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	s.mutex.RLock()
+	checks := append([]namedCheck(nil), s.checks...)
+	s.mutex.RUnlock()
+
+	results := make(map[string]string, len(checks))
+	status := http.StatusOK
+	for _, c := range checks {
+		ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+		err := c.check(ctx)
+		cancel()
+
+		if err != nil {
+			status = http.StatusServiceUnavailable
+			results[c.name] = err.Error()
+			s.logger.Warn("Readiness check failed",
+				zap.String("check", c.name),
+				zap.Error(err))
+			continue
+		}
+		results[c.name] = "ok"
+	}
+
+	overall := "ok"
+	if status != http.StatusOK {
+		overall = "unavailable"
+	}
+	writeJSON(w, status, map[string]any{"status": overall, "checks": results})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// CheckWritable returns a check that creates and removes a file in dir
+func CheckWritable(dir string) Check {
+	return func(ctx context.Context) error {
+		file, err := os.CreateTemp(dir, ".readyz-*")
+		if err != nil {
+			return fmt.Errorf("%s is not writable: %w", dir, err)
+		}
+		file.Close()
+		return os.Remove(file.Name())
+	}
+}
+
+// Probe requests /readyz from a server listening on addr and fails unless it
+// reports ready. It lets the container image run its own healthcheck without curl.
+func Probe(addr string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid admin address '%s': %w", addr, err)
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+
+	client := &http.Client{Timeout: 2 * checkTimeout}
+	resp, err := client.Get(fmt.Sprintf("http://%s/readyz", net.JoinHostPort(host, port)))
+	if err != nil {
+		return fmt.Errorf("admin server unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Checks map[string]string `json:"checks"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return fmt.Errorf("not ready: %v", body.Checks)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/brauni/booklore-tg-bot/internal/storage"
+	"go.uber.org/zap"
+)
+
+// Actions recorded in the audit log
+const (
+	ActionImport       = "import"
+	ActionDiscard      = "discard"
+	ActionPreference   = "preference"
+	ActionDevice       = "device"
+	ActionSubscription = "subscription"
+	ActionRescan       = "rescan"
+	ActionDebug        = "debug"
+	ActionAuditExport  = "audit_export"
+	ActionConfigReload = "config_reload"
+	ActionAccount      = "account"
+)
+
+// SystemActor is the actor of changes the bot makes on its own, e.g. after a library was renamed
+const SystemActor int64 = 0
+
+// Outcome is the result of an audited action
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	OutcomeFailure Outcome = "failure"
+)
+
+// Entry is a single audited action
+type Entry struct {
+	Time    time.Time `json:"time"`
+	ActorID int64     `json:"actorId"`
+	Action  string    `json:"action"`
+	// Target is what the action was applied to, e.g. "bookdrop:12" or "library:3/path:7"
+	Target  string            `json:"target,omitempty"`
+	Params  map[string]string `json:"params,omitempty"`
+	Outcome Outcome           `json:"outcome"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// Filter selects entries from the audit log
+type Filter struct {
+	// ActorID limits entries to one user; 0 matches everyone
+	ActorID int64
+	// Since drops entries older than this time; the zero time keeps all
+	Since time.Time
+}
+
+func (f Filter) matches(e Entry) bool {
+	if f.ActorID != 0 && e.ActorID != f.ActorID {
+		return false
+	}
+	return f.Since.IsZero() || !e.Time.Before(f.Since)
+}
+
+// Log is an append-only audit log stored as JSON lines
+type Log struct {
+	file      *storage.JSONLinesFile
+	retention time.Duration
+	logger    *zap.Logger
+}
+
+// NewLog creates an audit log backed by the given file. Entries older than
+// retention are removed by Run; a retention of 0 keeps them forever.
+func NewLog(logger *zap.Logger, path string, retention time.Duration) *Log {
+	file := storage.NewJSONLinesFile(path)
+
+	// If this fails the entries are still readable, only left as they were
+	if err := file.Migrate(); err != nil {
+		logger.Warn("Failed to encrypt the audit log with the current key", zap.Error(err))
+	}
+
+	return &Log{
+		file:      file,
+		retention: retention,
+		logger:    logger,
+	}
+}
+
+// Record appends an entry, stamping it with the current time. Failures are
+// logged rather than returned, an audit problem must not stop the action itself.
+func (l *Log) Record(entry Entry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+
+	if err := l.file.Append(entry); err != nil {
+		l.logger.Error("Failed to write audit log entry",
+			zap.String("action", entry.Action),
+			zap.Error(err))
+	}
+}
+
+// Query returns matching entries, newest first, up to limit (0 means no limit)
+func (l *Log) Query(filter Filter, limit int) ([]Entry, error) {
+	var entries []Entry
+	err := l.each(filter, func(e Entry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Reverse to newest first
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
+
+// Export writes matching entries as JSON lines, oldest first
+func (l *Log) Export(w io.Writer, filter Filter) (int, error) {
+	encoder := json.NewEncoder(w)
+	count := 0
+	err := l.each(filter, func(e Entry) error {
+		count++
+		return encoder.Encode(e)
+	})
+	return count, err
+}
+
+// Prune removes entries older than the retention period
+func (l *Log) Prune() error {
+	if l.retention <= 0 {
+		return nil
+	}
+
+	cutoff := time.Now().Add(-l.retention)
+	removed, err := l.file.Filter(func(line []byte) bool {
+		var e Entry
+		// Unreadable lines are kept, they may be worth a look
+		if err := json.Unmarshal(line, &e); err != nil {
+			return true
+		}
+		return !e.Time.Before(cutoff)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to prune audit log: %w", err)
+	}
+
+	if removed > 0 {
+		l.logger.Info("Pruned audit log",
+			zap.Int("removed_entries", removed),
+			zap.Duration("retention", l.retention))
+	}
+	return nil
+}
+
+// Run prunes the audit log once a day until the context is cancelled
+func (l *Log) Run(ctx context.Context) {
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		if err := l.Prune(); err != nil {
+			l.logger.Warn("Failed to prune audit log",
+				zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// each decodes matching entries, oldest first
+func (l *Log) each(filter Filter, fn func(e Entry) error) error {
+	return l.file.Each(func(line []byte) error {
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			l.logger.Warn("Skipping unreadable audit log entry",
+				zap.Error(err))
+			return nil
+		}
+		if !filter.matches(e) {
+			return nil
+		}
+		return fn(e)
+	})
+}
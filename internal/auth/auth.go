@@ -2,41 +2,142 @@ package auth
 
 import (
 	"fmt"
+	"sync"
 
 	"go.uber.org/zap"
 )
 
 type Authenticator struct {
 	allowedUserIDs []int64
+	adminUserIDs   []int64
+	libraryAccess  map[int64][]int64
+	mutex          sync.RWMutex
 	logger         *zap.Logger
 }
 
-func NewAuthenticator(allowedUserIDs []int64, logger *zap.Logger) *Authenticator {
+func NewAuthenticator(allowedUserIDs, adminUserIDs []int64, libraryAccess map[int64][]int64, logger *zap.Logger) *Authenticator {
 	return &Authenticator{
 		allowedUserIDs: allowedUserIDs,
+		adminUserIDs:   adminUserIDs,
+		libraryAccess:  libraryAccess,
 		logger:         logger,
 	}
 }
 
+// Update replaces the allowed users, admins and library restrictions, e.g. after a config reload
+func (a *Authenticator) Update(allowedUserIDs, adminUserIDs []int64, libraryAccess map[int64][]int64) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.allowedUserIDs = allowedUserIDs
+	a.adminUserIDs = adminUserIDs
+	a.libraryAccess = libraryAccess
+}
+
 func (a *Authenticator) IsUserAllowed(userID int64) bool {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if a.isAllowed(userID) {
+		a.logger.Info("User access granted",
+			zap.Int64("user_id", userID))
+		return true
+	}
+
+	a.logger.Warn("Unauthorized access attempt",
+		zap.Int64("user_id", userID))
+	return false
+}
+
+// isAllowed is IsUserAllowed without locking or logging; callers must hold the lock
+func (a *Authenticator) isAllowed(userID int64) bool {
 	for _, allowedID := range a.allowedUserIDs {
 		if userID == allowedID {
-			a.logger.Info("User access granted",
-				zap.Int64("user_id", userID))
 			return true
 		}
 	}
+	return false
+}
 
-	a.logger.Warn("Unauthorized access attempt",
-		zap.Int64("user_id", userID))
+// IsAdmin returns true if the user is an authorized bot administrator
+func (a *Authenticator) IsAdmin(userID int64) bool {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	return a.isAdmin(userID)
+}
+
+func (a *Authenticator) isAdmin(userID int64) bool {
+	for _, adminID := range a.adminUserIDs {
+		if userID == adminID {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAdmins returns true if any bot administrators are configured
+func (a *Authenticator) HasAdmins() bool {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	return len(a.adminUserIDs) > 0
+}
+
+// AllowedLibraries returns the library IDs a user may access, or nil if the user may access every library
+func (a *Authenticator) AllowedLibraries(userID int64) []int64 {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if a.isAdmin(userID) {
+		return nil
+	}
+	if libraryIDs, restricted := a.libraryAccess[userID]; restricted {
+		return libraryIDs
+	}
+	return nil
+}
+
+// CanAccessLibrary returns true if the user may use the bot and read books from the library
+func (a *Authenticator) CanAccessLibrary(userID, libraryID int64) bool {
+	a.mutex.RLock()
+	allowedUser := a.isAllowed(userID)
+	a.mutex.RUnlock()
+	if !allowedUser {
+		a.logger.Warn("Library access denied to unauthorized user",
+			zap.Int64("user_id", userID),
+			zap.Int64("library_id", libraryID))
+		return false
+	}
+
+	allowed := a.AllowedLibraries(userID)
+	if allowed == nil {
+		return true
+	}
+
+	for _, id := range allowed {
+		if id == libraryID {
+			return true
+		}
+	}
+
+	a.logger.Warn("Library access denied",
+		zap.Int64("user_id", userID),
+		zap.Int64("library_id", libraryID))
 	return false
 }
 
 func (a *Authenticator) GetAllowedUsersCount() int {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
 	return len(a.allowedUserIDs)
 }
 
 func (a *Authenticator) GetUserInfo(userID int64) string {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
 	for _, allowedID := range a.allowedUserIDs {
 		if userID == allowedID {
 			return fmt.Sprintf("User %d (authorized)", userID)
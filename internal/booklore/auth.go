@@ -0,0 +1,275 @@
+package booklore
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// tokenRefreshMargin is how long before expiry an access token is refreshed
+const tokenRefreshMargin = time.Minute
+
+// AuthProvider supplies the bearer token for Booklore requests
+type AuthProvider interface {
+	// Token returns a token for the next request, logging in or refreshing first if needed
+	Token(ctx context.Context) (string, error)
+	// Invalidate reports that Booklore rejected the token, so the next call to Token
+	// gets a new one. Tokens that were already replaced are ignored.
+	Invalidate(token string)
+}
+
+// authContextKey carries an AuthProvider in a request context
+type authContextKey struct{}
+
+// WithAuth returns a context whose Booklore requests use auth instead of the
+// client's own provider, e.g. to act as a user's linked account
+func WithAuth(ctx context.Context, auth AuthProvider) context.Context {
+	return context.WithValue(ctx, authContextKey{}, auth)
+}
+
+// authFromContext returns the provider set with WithAuth, or fallback
+func authFromContext(ctx context.Context, fallback AuthProvider) AuthProvider {
+	if auth, ok := ctx.Value(authContextKey{}).(AuthProvider); ok && auth != nil {
+		return auth
+	}
+	return fallback
+}
+
+// StaticToken is an API token configured up front, e.g. from BOOKLORE_API_TOKEN
+type StaticToken struct {
+	token string
+	mutex sync.RWMutex
+}
+
+// NewStaticToken creates a provider for a fixed API token
+func NewStaticToken(token string) *StaticToken {
+	return &StaticToken{token: token}
+}
+
+// Token returns the configured token
+func (s *StaticToken) Token(ctx context.Context) (string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.token, nil
+}
+
+// Invalidate does nothing, a static token can only be replaced with SetToken
+func (s *StaticToken) Invalidate(token string) {}
+
+// SetToken replaces the token, e.g. after it was rotated. Requests already in
+// flight finish with the previous token.
+func (s *StaticToken) SetToken(token string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.token = token
+}
+
+// LoginAuth logs in to Booklore with a username and password and keeps the
+// resulting JWT access token fresh with the refresh token
+type LoginAuth struct {
+	baseURL    string
+	username   string
+	password   string
+	httpClient *http.Client
+	logger     *zap.Logger
+
+	// mutex is held while logging in or refreshing, so parallel requests wait
+	// for one new token instead of each asking for their own
+	mutex        sync.Mutex
+	accessToken  string
+	refreshToken string
+	// expiresAt is zero if the access token doesn't say when it expires
+	expiresAt time.Time
+}
+
+// authResponse is returned by the login and refresh endpoints
+type authResponse struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+// NewLoginAuth creates a provider that logs in with the given credentials on first use
+func NewLoginAuth(baseURL, username, password string, logger *zap.Logger) *LoginAuth {
+	return &LoginAuth{
+		baseURL:  baseURL,
+		username: username,
+		password: password,
+		httpClient: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: instrumentedTransport{next: http.DefaultTransport},
+		},
+		logger: logger,
+	}
+}
+
+// Token returns the current access token, refreshing it shortly before it expires
+func (l *LoginAuth) Token(ctx context.Context) (string, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.accessToken != "" && (l.expiresAt.IsZero() || time.Until(l.expiresAt) > tokenRefreshMargin) {
+		return l.accessToken, nil
+	}
+
+	if l.refreshToken != "" {
+		err := l.authenticate(ctx, "/api/v1/auth/refresh", map[string]string{
+			"refreshToken": l.refreshToken,
+		})
+		if err == nil {
+			l.logger.Debug("Refreshed Booklore access token",
+				zap.Time("expires_at", l.expiresAt))
+			return l.accessToken, nil
+		}
+
+		// The refresh token may have expired too, so start over
+		l.logger.Info("Failed to refresh Booklore access token, logging in again",
+			zap.Error(err))
+	}
+
+	err := l.authenticate(ctx, "/api/v1/auth/login", map[string]string{
+		"username": l.username,
+		"password": l.password,
+	})
+	if err != nil {
+		return "", fmt.Errorf("booklore login failed: %w", err)
+	}
+
+	l.logger.Info("Logged in to Booklore",
+		zap.String("username", l.username),
+		zap.Time("expires_at", l.expiresAt))
+	return l.accessToken, nil
+}
+
+// Invalidate drops a rejected access token so the next request refreshes it
+func (l *LoginAuth) Invalidate(token string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if token == l.accessToken {
+		l.accessToken = ""
+	}
+}
+
+// authenticate posts to the login or refresh endpoint and stores the returned tokens
+func (l *LoginAuth) authenticate(ctx context.Context, path string, payload map[string]string) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := l.httpClient.Do(req)
+	if err != nil {
+		return NewNetworkError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == http.StatusUnauthorized {
+			return NewAuthError("Booklore rejected the credentials")
+		}
+		return parseAPIError(resp)
+	}
+
+	var tokens authResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if tokens.AccessToken == "" {
+		return fmt.Errorf("response contains no access token")
+	}
+
+	l.accessToken = tokens.AccessToken
+	if tokens.RefreshToken != "" {
+		l.refreshToken = tokens.RefreshToken
+	}
+	l.expiresAt = tokenExpiry(tokens.AccessToken)
+	return nil
+}
+
+// tokenExpiry reads the expiry of a JWT without verifying it, the zero time if
+// the token has none
+func tokenExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if json.Unmarshal(payload, &claims) != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
+
+// authTransport adds the bearer token to every request and retries once with a
+// new token when Booklore answers 401, e.g. because the token expired early
+type authTransport struct {
+	auth AuthProvider
+	next http.RoundTripper
+}
+
+func (t authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	auth := authFromContext(req.Context(), t.auth)
+	token, err := auth.Token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(withBearer(req, token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	// Requests with a body can only be retried if the body can be read again
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+
+	auth.Invalidate(token)
+	fresh, err := auth.Token(req.Context())
+	if err != nil || fresh == token {
+		return resp, nil
+	}
+
+	retry := withBearer(req, fresh)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		retry.Body = body
+	}
+	resp.Body.Close()
+
+	return t.next.RoundTrip(retry)
+}
+
+// withBearer returns a copy of the request with the token, leaving the original untouched
+func withBearer(req *http.Request, token string) *http.Request {
+	clone := req.Clone(req.Context())
+	clone.Header.Set("Authorization", "Bearer "+token)
+	return clone
+}
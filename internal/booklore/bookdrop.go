@@ -0,0 +1,156 @@
+package booklore
+
+import (
+	"sort"
+	"strings"
+)
+
+// Bookdrop sort orders
+const (
+	BookdropSortDate = "date"
+	BookdropSortSize = "size"
+	BookdropSortName = "name"
+)
+
+// BookdropQuery describes a filtered, sorted page of bookdrop files
+type BookdropQuery struct {
+	// Status restricts results to one file status such as NEW; empty matches every status
+	Status string
+	// Filter matches files whose name contains every word
+	Filter string
+	// Sort is one of the BookdropSort constants; newest files come first by default
+	Sort string
+	Page int
+	Size int
+}
+
+func (q BookdropQuery) matches(file *BookdropFile) bool {
+	if q.Status != "" && !strings.EqualFold(file.Status, q.Status) {
+		return false
+	}
+
+	name := strings.ToLower(file.FileName)
+	for _, word := range strings.Fields(strings.ToLower(q.Filter)) {
+		if !strings.Contains(name, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterBookdropFiles applies a query to an already fetched list of bookdrop files
+func FilterBookdropFiles(files []BookdropFile, query BookdropQuery) *PageBookdropFile {
+	var matches []BookdropFile
+	for _, file := range files {
+		if query.matches(&file) {
+			matches = append(matches, file)
+		}
+	}
+
+	switch query.Sort {
+	case BookdropSortSize:
+		sort.SliceStable(matches, func(i, j int) bool {
+			return matches[i].FileSize > matches[j].FileSize
+		})
+	case BookdropSortName:
+		sort.SliceStable(matches, func(i, j int) bool {
+			return strings.ToLower(matches[i].FileName) < strings.ToLower(matches[j].FileName)
+		})
+	default:
+		// Timestamps are ISO 8601, so they sort correctly as strings
+		sort.SliceStable(matches, func(i, j int) bool {
+			return matches[i].DateAdded > matches[j].DateAdded
+		})
+	}
+
+	return paginateBookdropFiles(matches, query.Page, query.Size)
+}
+
+// paginateBookdropFiles slices files into the requested page
+func paginateBookdropFiles(files []BookdropFile, page, size int) *PageBookdropFile {
+	total := len(files)
+	totalPages := 0
+	if size > 0 {
+		totalPages = (total + size - 1) / size
+	}
+
+	start := page * size
+	if start > total {
+		start = total
+	}
+	end := start + size
+	if end > total {
+		end = total
+	}
+
+	return &PageBookdropFile{
+		Content:       files[start:end],
+		TotalElements: total,
+		TotalPages:    totalPages,
+		Size:          size,
+		Number:        page,
+		First:         page == 0,
+		Last:          end >= total,
+	}
+}
+
+// ReviewMetadata returns the metadata Booklore would import a bookdrop file
+// with: the metadata extracted from the file, overridden by every field an
+// online provider fetched.
+func (f *BookdropFile) ReviewMetadata() BookMetadata {
+	var merged BookMetadata
+	if f.OriginalMetadata != nil {
+		merged = *f.OriginalMetadata
+	}
+	if f.FetchedMetadata != nil {
+		merged.Merge(f.FetchedMetadata)
+	}
+	if merged.Title == "" {
+		merged.Title = f.FileName
+	}
+	return merged
+}
+
+// Merge copies every non-empty field of other into m
+func (m *BookMetadata) Merge(other *BookMetadata) {
+	if other.Title != "" {
+		m.Title = other.Title
+	}
+	if other.Subtitle != "" {
+		m.Subtitle = other.Subtitle
+	}
+	if len(other.Authors) > 0 {
+		m.Authors = other.Authors
+	}
+	if other.Publisher != "" {
+		m.Publisher = other.Publisher
+	}
+	if other.PublishedDate != "" {
+		m.PublishedDate = other.PublishedDate
+	}
+	if other.Description != "" {
+		m.Description = other.Description
+	}
+	if other.SeriesName != "" {
+		m.SeriesName = other.SeriesName
+		m.SeriesNumber = other.SeriesNumber
+	}
+	if other.Language != "" {
+		m.Language = other.Language
+	}
+	if other.ISBN10 != "" {
+		m.ISBN10 = other.ISBN10
+	}
+	if other.ISBN13 != "" {
+		m.ISBN13 = other.ISBN13
+	}
+	if other.PageCount > 0 {
+		m.PageCount = other.PageCount
+	}
+	if len(other.Categories) > 0 {
+		m.Categories = other.Categories
+	}
+	if other.ThumbnailURL != "" {
+		m.ThumbnailURL = other.ThumbnailURL
+	}
+}
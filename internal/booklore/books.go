@@ -0,0 +1,178 @@
+package booklore
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BookSearch describes a library search
+type BookSearch struct {
+	Query string
+	// LibraryIDs restricts results to these libraries; empty searches every library
+	LibraryIDs []int64
+	Page       int
+	Size       int
+}
+
+// BookDownload is a streaming book file download
+type BookDownload struct {
+	Body        io.ReadCloser
+	FileName    string
+	Size        int64 // -1 if unknown
+	ContentType string
+}
+
+func (s BookSearch) matches(book *Book) bool {
+	if len(s.LibraryIDs) > 0 {
+		allowed := false
+		for _, id := range s.LibraryIDs {
+			if id == book.LibraryID {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return book.Matches(s.Query)
+}
+
+// Title returns the book title, falling back to the file name
+func (b *Book) Title() string {
+	if b.Metadata != nil && b.Metadata.Title != "" {
+		return b.Metadata.Title
+	}
+	return b.FileName
+}
+
+// Authors returns the book authors as a comma-separated list
+func (b *Book) Authors() string {
+	if b.Metadata == nil {
+		return ""
+	}
+	return strings.Join(b.Metadata.Authors, ", ")
+}
+
+// SizeBytes returns the book file size in bytes as reported by Booklore
+func (b *Book) SizeBytes() int64 {
+	return int64(b.FileSizeKb * 1024)
+}
+
+// Format returns the book file format such as EPUB or PDF
+func (b *Book) Format() string {
+	if b.BookType != "" {
+		return strings.ToUpper(b.BookType)
+	}
+	return strings.ToUpper(strings.TrimPrefix(filepath.Ext(b.FileName), "."))
+}
+
+// Matches reports whether every word of the query occurs in the book's
+// title, authors, series, ISBN or file name
+func (b *Book) Matches(query string) bool {
+	fields := []string{b.FileName}
+	if b.Metadata != nil {
+		fields = append(fields,
+			b.Metadata.Title,
+			b.Metadata.Subtitle,
+			b.Metadata.SeriesName,
+			b.Metadata.ISBN10,
+			b.Metadata.ISBN13)
+		fields = append(fields, b.Metadata.Authors...)
+	}
+	haystack := strings.ToLower(strings.Join(fields, " "))
+
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		if !strings.Contains(haystack, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterBooks applies a search to an already fetched list of books
+func FilterBooks(books []Book, search BookSearch) *PageBook {
+	var matches []Book
+	for _, book := range books {
+		if search.matches(&book) {
+			matches = append(matches, book)
+		}
+	}
+
+	return paginateBooks(matches, search.Page, search.Size)
+}
+
+// paginateBooks slices books into the requested page
+func paginateBooks(books []Book, page, size int) *PageBook {
+	total := len(books)
+	totalPages := 0
+	if size > 0 {
+		totalPages = (total + size - 1) / size
+	}
+
+	start := page * size
+	if start > total {
+		start = total
+	}
+	end := start + size
+	if end > total {
+		end = total
+	}
+
+	return &PageBook{
+		Content:       books[start:end],
+		TotalElements: total,
+		TotalPages:    totalPages,
+		Size:          size,
+		Number:        page,
+		First:         page == 0,
+		Last:          end >= total,
+	}
+}
+
+// bookListTTL is how long a fetched book list is reused, so paging through
+// results or typing an inline query doesn't download the library every time
+const bookListTTL = time.Minute
+
+// bookListCache keeps the recently fetched book list of each account, as
+// accounts may see different libraries
+type bookListCache struct {
+	lists map[AuthProvider]*bookList
+	mutex sync.Mutex
+}
+
+type bookList struct {
+	books     []Book
+	fetchedAt time.Time
+}
+
+// get returns the cached book list of an account, calling fetch if it expired
+func (c *bookListCache) get(auth AuthProvider, fetch func() ([]Book, error)) ([]Book, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Drop expired lists, e.g. of accounts that were relinked since
+	for key, list := range c.lists {
+		if time.Since(list.fetchedAt) >= bookListTTL {
+			delete(c.lists, key)
+		}
+	}
+
+	if list, ok := c.lists[auth]; ok {
+		return list.books, nil
+	}
+
+	books, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	if c.lists == nil {
+		c.lists = make(map[AuthProvider]*bookList)
+	}
+	c.lists[auth] = &bookList{books: books, fetchedAt: time.Now()}
+	return books, nil
+}
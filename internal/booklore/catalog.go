@@ -0,0 +1,172 @@
+package booklore
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// LibraryCatalog caches the Booklore libraries and their paths. Entries expire after
+// a TTL and are refreshed in the background; the server's ETag is used so unchanged
+// libraries aren't downloaded again.
+type LibraryCatalog struct {
+	client    *Client
+	ttl       time.Duration
+	libraries []Library
+	etag      string
+	fetchedAt time.Time
+	onChange  func(libraries []Library)
+	mutex     sync.RWMutex
+	// refreshMutex lets only one refresh reach Booklore at a time
+	refreshMutex sync.Mutex
+	logger       *zap.Logger
+}
+
+// NewLibraryCatalog creates an empty catalog that loads the libraries on first use
+func NewLibraryCatalog(client *Client, logger *zap.Logger, ttl time.Duration) *LibraryCatalog {
+	return &LibraryCatalog{
+		client: client,
+		ttl:    ttl,
+		logger: logger,
+	}
+}
+
+// SetChangeHandler registers a function called after a refresh loaded new or different libraries
+func (lc *LibraryCatalog) SetChangeHandler(onChange func(libraries []Library)) {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+
+	lc.onChange = onChange
+}
+
+// Libraries returns the cached libraries, refreshing them first if they expired.
+// If Booklore can't be reached, expired libraries are returned rather than an error.
+func (lc *LibraryCatalog) Libraries(ctx context.Context) ([]Library, error) {
+	lc.mutex.RLock()
+	libraries, fresh := lc.libraries, lc.isFresh()
+	lc.mutex.RUnlock()
+
+	if fresh {
+		return libraries, nil
+	}
+
+	if err := lc.Refresh(ctx); err != nil {
+		if libraries == nil {
+			return nil, err
+		}
+		lc.logger.Warn("Failed to refresh library catalog, using cached libraries",
+			zap.Error(err))
+		return libraries, nil
+	}
+
+	lc.mutex.RLock()
+	defer lc.mutex.RUnlock()
+	return lc.libraries, nil
+}
+
+// Library returns a single library by ID
+func (lc *LibraryCatalog) Library(ctx context.Context, libraryID int64) (*Library, error) {
+	libraries, err := lc.Libraries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range libraries {
+		if libraries[i].ID == libraryID {
+			return &libraries[i], nil
+		}
+	}
+
+	return nil, NewAPIError(ErrNotFound, "library not found", http.StatusNotFound)
+}
+
+// Cached returns the libraries loaded so far without contacting Booklore, or nil if none were loaded
+func (lc *LibraryCatalog) Cached() []Library {
+	lc.mutex.RLock()
+	defer lc.mutex.RUnlock()
+
+	return lc.libraries
+}
+
+// Invalidate makes the next lookup fetch the libraries again
+func (lc *LibraryCatalog) Invalidate() {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+
+	lc.fetchedAt = time.Time{}
+	lc.logger.Debug("Library catalog invalidated")
+}
+
+// Refresh fetches the libraries from Booklore now
+func (lc *LibraryCatalog) Refresh(ctx context.Context) error {
+	lc.refreshMutex.Lock()
+	defer lc.refreshMutex.Unlock()
+
+	// Another caller may have refreshed while this one waited
+	lc.mutex.RLock()
+	etag, fresh := lc.etag, lc.isFresh()
+	lc.mutex.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	// The catalog is shared, so it is always loaded with the bot's own account
+	ctx = WithAuth(ctx, lc.client.Auth())
+	libraries, newETag, notModified, err := lc.client.GetLibrariesIfChanged(ctx, etag)
+	if err != nil {
+		return err
+	}
+
+	lc.mutex.Lock()
+	lc.fetchedAt = time.Now()
+	if notModified {
+		lc.mutex.Unlock()
+		lc.logger.Debug("Library catalog unchanged")
+		return nil
+	}
+
+	changed := !reflect.DeepEqual(lc.libraries, libraries)
+	lc.libraries = libraries
+	lc.etag = newETag
+	onChange := lc.onChange
+	lc.mutex.Unlock()
+
+	lc.logger.Debug("Library catalog refreshed",
+		zap.Int("library_count", len(libraries)),
+		zap.Bool("changed", changed))
+
+	if changed && onChange != nil {
+		onChange(libraries)
+	}
+	return nil
+}
+
+// Run refreshes the catalog whenever it expires until the context is cancelled
+func (lc *LibraryCatalog) Run(ctx context.Context) {
+	ticker := time.NewTicker(lc.ttl)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			lc.Invalidate()
+			refreshCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			if err := lc.Refresh(refreshCtx); err != nil {
+				lc.logger.Warn("Background refresh of library catalog failed",
+					zap.Error(err))
+			}
+			cancel()
+		}
+	}
+}
+
+// isFresh reports whether the cached libraries can be used; the caller must hold the mutex
+func (lc *LibraryCatalog) isFresh() bool {
+	return lc.libraries != nil && !lc.fetchedAt.IsZero() && time.Since(lc.fetchedAt) < lc.ttl
+}
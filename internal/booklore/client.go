@@ -6,28 +6,44 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/brauni/booklore-tg-bot/internal/metrics"
+	"github.com/brauni/booklore-tg-bot/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 )
 
 // Client represents the Booklore API client
 type Client struct {
-	baseURL    string
-	apiToken   string
-	httpClient *http.Client
-	logger     *zap.Logger
+	baseURL      string
+	auth         AuthProvider
+	httpClient   *http.Client
+	streamClient *http.Client
+	books        bookListCache
+	logger       *zap.Logger
 }
 
-// NewClient creates a new Booklore API client
-func NewClient(baseURL, apiToken string, logger *zap.Logger) *Client {
+// NewClient creates a new Booklore API client. A nil auth provider leaves the client disabled.
+func NewClient(baseURL string, auth AuthProvider, logger *zap.Logger) *Client {
+	var transport http.RoundTripper = instrumentedTransport{next: http.DefaultTransport}
+	if auth != nil {
+		transport = authTransport{auth: auth, next: transport}
+	}
+
 	return &Client{
-		baseURL:  baseURL,
-		apiToken: apiToken,
+		baseURL: baseURL,
+		auth:    auth,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: transport,
+		},
+		// File downloads are bounded by the request context instead of a fixed timeout
+		streamClient: &http.Client{
+			Transport: transport,
 		},
 		logger: logger,
 	}
@@ -35,11 +51,19 @@ func NewClient(baseURL, apiToken string, logger *zap.Logger) *Client {
 
 // IsEnabled returns true if the client is properly configured
 func (c *Client) IsEnabled() bool {
-	return c.baseURL != "" && c.apiToken != ""
+	return c.baseURL != "" && c.auth != nil
+}
+
+// Auth returns the provider of the client's bearer tokens
+func (c *Client) Auth() AuthProvider {
+	return c.auth
 }
 
 // RescanBookdrop triggers a rescan of the bookdrop folder
-func (c *Client) RescanBookdrop(ctx context.Context) error {
+func (c *Client) RescanBookdrop(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "booklore.RescanBookdrop")
+	defer func() { tracing.End(span, err) }()
+
 	if !c.IsEnabled() {
 		return NewAPIError(ErrInvalidToken, "Booklore API client is not configured", 0)
 	}
@@ -50,8 +74,6 @@ func (c *Client) RescanBookdrop(ctx context.Context) error {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	c.setAuthHeader(req)
-
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return NewNetworkError(err)
@@ -67,14 +89,22 @@ func (c *Client) RescanBookdrop(ctx context.Context) error {
 }
 
 // FinalizeImport finalizes the import of bookdrop files
-func (c *Client) FinalizeImport(ctx context.Context, fileIDs []int64, libraryID, pathID string) (*BookdropFinalizeResult, error) {
+func (c *Client) FinalizeImport(ctx context.Context, fileIDs []int64, libraryID, pathID string) (result *BookdropFinalizeResult, err error) {
+	ctx, span := tracing.Start(ctx, "booklore.FinalizeImport",
+		attribute.Int("booklore.file_count", len(fileIDs)),
+		attribute.String("booklore.library_id", libraryID),
+		attribute.String("booklore.path_id", pathID))
+	defer func() { tracing.End(span, err) }()
+
 	if !c.IsEnabled() {
 		return nil, NewAPIError(ErrInvalidToken, "Booklore API client is not configured", 0)
 	}
 
 	// Try different approaches to send file IDs
 	// Approach 1: JSON body with fileIds field (current approach)
-	result1, err1 := c.finalizeImportWithJSON(ctx, fileIDs, libraryID, pathID, "fileIds")
+	result1, err1 := finalizeAttempt(ctx, "json_fileIds", func(ctx context.Context) (*BookdropFinalizeResult, error) {
+		return c.finalizeImportWithJSON(ctx, fileIDs, libraryID, pathID, "fileIds")
+	})
 
 	// If first approach succeeds, return result
 	if err1 == nil && result1 != nil && (result1.ImportedCount > 0 || result1.FailedCount > 0 || result1.Success) {
@@ -85,7 +115,9 @@ func (c *Client) FinalizeImport(ctx context.Context, fileIDs []int64, libraryID,
 	c.logger.Info("JSON with 'fileIds' approach failed, trying alternative field names")
 
 	// Approach 1b: Try different field name
-	result1b, err1b := c.finalizeImportWithJSON(ctx, fileIDs, libraryID, pathID, "ids")
+	result1b, err1b := finalizeAttempt(ctx, "json_ids", func(ctx context.Context) (*BookdropFinalizeResult, error) {
+		return c.finalizeImportWithJSON(ctx, fileIDs, libraryID, pathID, "ids")
+	})
 	if err1b == nil && result1b != nil && (result1b.ImportedCount > 0 || result1b.FailedCount > 0 || result1b.Success) {
 		c.logger.Info("JSON with 'ids' approach succeeded")
 		return result1b, nil
@@ -94,7 +126,9 @@ func (c *Client) FinalizeImport(ctx context.Context, fileIDs []int64, libraryID,
 	c.logger.Info("Alternative JSON approach failed, trying string array approach")
 
 	// Approach 1c: Try string array instead of int64 array
-	result1c, err1c := c.finalizeImportWithJSONStringArray(ctx, fileIDs, libraryID, pathID, "fileIds")
+	result1c, err1c := finalizeAttempt(ctx, "json_string_array", func(ctx context.Context) (*BookdropFinalizeResult, error) {
+		return c.finalizeImportWithJSONStringArray(ctx, fileIDs, libraryID, pathID, "fileIds")
+	})
 	if err1c == nil && result1c != nil && (result1c.ImportedCount > 0 || result1c.FailedCount > 0 || result1c.Success) {
 		c.logger.Info("JSON string array approach succeeded")
 		return result1c, nil
@@ -103,7 +137,9 @@ func (c *Client) FinalizeImport(ctx context.Context, fileIDs []int64, libraryID,
 	c.logger.Info("String array approach failed, trying query parameter approach")
 
 	// Approach 2: Query parameters with file IDs
-	result2, err2 := c.finalizeImportWithQueryParams(ctx, fileIDs, libraryID, pathID)
+	result2, err2 := finalizeAttempt(ctx, "query_params", func(ctx context.Context) (*BookdropFinalizeResult, error) {
+		return c.finalizeImportWithQueryParams(ctx, fileIDs, libraryID, pathID)
+	})
 	if err2 == nil && result2 != nil {
 		c.logger.Info("Query parameter approach succeeded")
 		return result2, nil
@@ -112,7 +148,9 @@ func (c *Client) FinalizeImport(ctx context.Context, fileIDs []int64, libraryID,
 	c.logger.Info("Query parameter approach failed, trying PUT method")
 
 	// Approach 3: Try PUT method instead of POST
-	result3, err3 := c.finalizeImportWithPUT(ctx, fileIDs, libraryID, pathID)
+	result3, err3 := finalizeAttempt(ctx, "put", func(ctx context.Context) (*BookdropFinalizeResult, error) {
+		return c.finalizeImportWithPUT(ctx, fileIDs, libraryID, pathID)
+	})
 	if err3 == nil && result3 != nil {
 		c.logger.Info("PUT method approach succeeded")
 		return result3, nil
@@ -124,6 +162,21 @@ func (c *Client) FinalizeImport(ctx context.Context, fileIDs []int64, libraryID,
 	return nil, err1
 }
 
+// finalizeAttempt runs one approach of FinalizeImport in its own span
+func finalizeAttempt(ctx context.Context, approach string, attempt func(ctx context.Context) (*BookdropFinalizeResult, error)) (*BookdropFinalizeResult, error) {
+	ctx, span := tracing.Start(ctx, "booklore.FinalizeImport.attempt",
+		attribute.String("booklore.approach", approach))
+
+	result, err := attempt(ctx)
+	if result != nil {
+		span.SetAttributes(
+			attribute.Int("booklore.imported_count", result.ImportedCount),
+			attribute.Int("booklore.failed_count", result.FailedCount))
+	}
+	tracing.End(span, err)
+	return result, err
+}
+
 // finalizeImportWithJSON sends file IDs in JSON body
 func (c *Client) finalizeImportWithJSON(ctx context.Context, fileIDs []int64, libraryID, pathID, fieldName string) (*BookdropFinalizeResult, error) {
 	// Add query parameters for library and path
@@ -161,7 +214,6 @@ func (c *Client) finalizeImportWithJSON(ctx context.Context, fileIDs []int64, li
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	c.setAuthHeader(req)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -229,7 +281,6 @@ func (c *Client) finalizeImportWithJSONStringArray(ctx context.Context, fileIDs
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	c.setAuthHeader(req)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -308,7 +359,6 @@ func (c *Client) finalizeImportWithQueryParams(ctx context.Context, fileIDs []in
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	c.setAuthHeader(req)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -371,7 +421,6 @@ func (c *Client) finalizeImportWithNoBody(ctx context.Context, fileIDs []int64,
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	c.setAuthHeader(req)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -433,7 +482,6 @@ func (c *Client) finalizeImportWithPUT(ctx context.Context, fileIDs []int64, lib
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	c.setAuthHeader(req)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -490,6 +538,98 @@ func (c *Client) FinalizeAllImports(ctx context.Context, libraryID, pathID strin
 	return c.FinalizeImport(ctx, fileIDs, libraryID, pathID)
 }
 
+// FinalizeImportWithMetadata finalizes bookdrop files using reviewed metadata
+// instead of what Booklore would pick on its own
+func (c *Client) FinalizeImportWithMetadata(ctx context.Context, files []BookdropFinalizeFile, libraryID, pathID int64) (*BookdropFinalizeResult, error) {
+	if !c.IsEnabled() {
+		return nil, NewAPIError(ErrInvalidToken, "Booklore API client is not configured", 0)
+	}
+
+	url := fmt.Sprintf("%s/api/v1/bookdrop/imports/finalize", c.baseURL)
+
+	jsonData, err := json.Marshal(BookdropFinalizeMetadataRequest{
+		Files:            files,
+		DefaultLibraryID: libraryID,
+		DefaultPathID:    pathID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	c.logger.Info("Finalizing bookdrop import with metadata",
+		zap.Int("file_count", len(files)),
+		zap.Int64("library_id", libraryID),
+		zap.Int64("path_id", pathID))
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, NewNetworkError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.handleAPIError(resp)
+	}
+
+	var result BookdropFinalizeResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	c.logger.Info("Finalize with metadata result",
+		zap.Int("imported_count", result.ImportedCount),
+		zap.Int("failed_count", result.FailedCount),
+		zap.Bool("success", result.Success))
+
+	return &result, nil
+}
+
+// DiscardFiles removes files from the bookdrop without importing them
+func (c *Client) DiscardFiles(ctx context.Context, fileIDs []int64) error {
+	if !c.IsEnabled() {
+		return NewAPIError(ErrInvalidToken, "Booklore API client is not configured", 0)
+	}
+
+	url := fmt.Sprintf("%s/api/v1/bookdrop/files/discard", c.baseURL)
+
+	jsonData, err := json.Marshal(BookdropSelectionRequest{
+		SelectAll:   false,
+		SelectedIDs: fileIDs,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return NewNetworkError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return c.handleAPIError(resp)
+	}
+
+	c.logger.Info("Discarded bookdrop files",
+		zap.Int("file_count", len(fileIDs)),
+		zap.Any("file_ids", fileIDs))
+	return nil
+}
+
 // GetBookdropFiles retrieves bookdrop files by status
 func (c *Client) GetBookdropFiles(ctx context.Context, status string, page, size int) (*PageBookdropFile, error) {
 	if !c.IsEnabled() {
@@ -504,8 +644,6 @@ func (c *Client) GetBookdropFiles(ctx context.Context, status string, page, size
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	c.setAuthHeader(req)
-
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, NewNetworkError(err)
@@ -543,8 +681,6 @@ func (c *Client) GetBookdropFilesNoStatus(ctx context.Context, page, size int) (
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	c.setAuthHeader(req)
-
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, NewNetworkError(err)
@@ -572,6 +708,49 @@ func (c *Client) GetBookdropFilesNoStatus(ctx context.Context, page, size int) (
 	return &result, nil
 }
 
+// GetBookdropFile retrieves a single bookdrop file including its original and fetched metadata
+func (c *Client) GetBookdropFile(ctx context.Context, fileID int64) (*BookdropFile, error) {
+	// Booklore has no single-file endpoint, so look the file up in the listing
+	files, err := c.GetBookdropFilesNoStatus(ctx, 0, 1000)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files.Content {
+		if file.ID == fileID {
+			return &file, nil
+		}
+	}
+
+	return nil, NewAPIError(ErrNotFound, fmt.Sprintf("bookdrop file %d not found", fileID), http.StatusNotFound)
+}
+
+// DownloadBookdropCover streams the cover extracted from a bookdrop file. The caller must close the body.
+func (c *Client) DownloadBookdropCover(ctx context.Context, fileID int64) (io.ReadCloser, error) {
+	if !c.IsEnabled() {
+		return nil, NewAPIError(ErrInvalidToken, "Booklore API client is not configured", 0)
+	}
+
+	url := fmt.Sprintf("%s/api/v1/media/bookdrop/%d/cover", c.baseURL, fileID)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, NewNetworkError(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, c.handleAPIError(resp)
+	}
+
+	return resp.Body, nil
+}
+
 // GetBookdropNotification gets bookdrop notification summary
 func (c *Client) GetBookdropNotification(ctx context.Context) (*BookdropNotification, error) {
 	if !c.IsEnabled() {
@@ -585,8 +764,6 @@ func (c *Client) GetBookdropNotification(ctx context.Context) (*BookdropNotifica
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	c.setAuthHeader(req)
-
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, NewNetworkError(err)
@@ -607,18 +784,93 @@ func (c *Client) GetBookdropNotification(ctx context.Context) (*BookdropNotifica
 
 // GetLibraries retrieves all libraries available to the user
 func (c *Client) GetLibraries(ctx context.Context) ([]Library, error) {
+	libraries, _, _, err := c.GetLibrariesIfChanged(ctx, "")
+	return libraries, err
+}
+
+// GetLibrariesIfChanged retrieves all libraries unless they still match etag.
+// It returns the new ETag, if the server sent one, and whether the libraries were unchanged.
+func (c *Client) GetLibrariesIfChanged(ctx context.Context, etag string) ([]Library, string, bool, error) {
 	if !c.IsEnabled() {
-		return nil, NewAPIError(ErrInvalidToken, "Booklore API client is not configured", 0)
+		return nil, "", false, NewAPIError(ErrInvalidToken, "Booklore API client is not configured", 0)
 	}
 
 	url := fmt.Sprintf("%s/api/v1/libraries", c.baseURL)
 
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, "", false, NewNetworkError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, etag, true, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", false, c.handleAPIError(resp)
+	}
+
+	var libraries []Library
+	if err := json.NewDecoder(resp.Body).Decode(&libraries); err != nil {
+		return nil, "", false, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return libraries, resp.Header.Get("ETag"), false, nil
+}
+
+// ListBooks retrieves all books the user can access
+func (c *Client) ListBooks(ctx context.Context) ([]Book, error) {
+	if !c.IsEnabled() {
+		return nil, NewAPIError(ErrInvalidToken, "Booklore API client is not configured", 0)
+	}
+
+	url := fmt.Sprintf("%s/api/v1/books", c.baseURL)
+
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	c.setAuthHeader(req)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, NewNetworkError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.handleAPIError(resp)
+	}
+
+	var books []Book
+	if err := json.NewDecoder(resp.Body).Decode(&books); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return books, nil
+}
+
+// GetBook retrieves a single book by ID
+func (c *Client) GetBook(ctx context.Context, bookID int64) (*Book, error) {
+	if !c.IsEnabled() {
+		return nil, NewAPIError(ErrInvalidToken, "Booklore API client is not configured", 0)
+	}
+
+	url := fmt.Sprintf("%s/api/v1/books/%d", c.baseURL, bookID)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -630,21 +882,86 @@ func (c *Client) GetLibraries(ctx context.Context) ([]Library, error) {
 		return nil, c.handleAPIError(resp)
 	}
 
-	var libraries []Library
-	if err := json.NewDecoder(resp.Body).Decode(&libraries); err != nil {
+	var book Book
+	if err := json.NewDecoder(resp.Body).Decode(&book); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	return libraries, nil
+	return &book, nil
 }
 
-// setAuthHeader sets the authorization header for API requests
-func (c *Client) setAuthHeader(req *http.Request) {
-	req.Header.Set("Authorization", "Bearer "+c.apiToken)
+// SearchBooks searches the library by title, author, series, ISBN or file name.
+// Booklore filters its book list client-side, so the bot does the same on a
+// book list cached for a short while.
+func (c *Client) SearchBooks(ctx context.Context, search BookSearch) (*PageBook, error) {
+	books, err := c.books.get(authFromContext(ctx, c.auth), func() ([]Book, error) {
+		return c.ListBooks(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := FilterBooks(books, search)
+
+	c.logger.Debug("Searched Booklore library",
+		zap.String("query", search.Query),
+		zap.Int("total_books", len(books)),
+		zap.Int("matches", result.TotalElements))
+
+	return result, nil
+}
+
+// DownloadBook streams a book file from the library. The caller must close the body.
+func (c *Client) DownloadBook(ctx context.Context, bookID int64) (*BookDownload, error) {
+	if !c.IsEnabled() {
+		return nil, NewAPIError(ErrInvalidToken, "Booklore API client is not configured", 0)
+	}
+
+	url := fmt.Sprintf("%s/api/v1/books/%d/download", c.baseURL, bookID)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Use the streaming client so large files aren't cut off by the request timeout
+	resp, err := c.streamClient.Do(req)
+	if err != nil {
+		return nil, NewNetworkError(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, c.handleAPIError(resp)
+	}
+
+	fileName := ""
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		fileName = params["filename"]
+	}
+
+	c.logger.Info("Streaming book download from Booklore",
+		zap.Int64("book_id", bookID),
+		zap.String("file_name", fileName),
+		zap.Int64("content_length", resp.ContentLength))
+
+	return &BookDownload{
+		Body:        resp.Body,
+		FileName:    fileName,
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+	}, nil
 }
 
 // handleAPIError processes API error responses
 func (c *Client) handleAPIError(resp *http.Response) error {
+	apiErr := parseAPIError(resp)
+	metrics.ObserveAPIError(apiErr.Type.String())
+	return apiErr
+}
+
+// parseAPIError turns an error response into a BookloreAPIError
+func parseAPIError(resp *http.Response) *BookloreAPIError {
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return NewAPIError(ErrNetworkError, "Failed to read error response", resp.StatusCode)
@@ -1,6 +1,7 @@
 package booklore
 
 import (
+	"errors"
 	"fmt"
 )
 
@@ -18,6 +19,30 @@ const (
 	ErrServiceUnavailable
 )
 
+// String returns a stable name for the error type, used in metrics
+func (t APIErrorType) String() string {
+	switch t {
+	case ErrInvalidToken:
+		return "invalid_token"
+	case ErrNetworkError:
+		return "network_error"
+	case ErrBadRequest:
+		return "bad_request"
+	case ErrUnauthorized:
+		return "unauthorized"
+	case ErrForbidden:
+		return "forbidden"
+	case ErrNotFound:
+		return "not_found"
+	case ErrInternalServer:
+		return "internal_server"
+	case ErrServiceUnavailable:
+		return "service_unavailable"
+	default:
+		return "unknown"
+	}
+}
+
 // BookloreAPIError represents a custom error for Booklore API interactions
 type BookloreAPIError struct {
 	Type    APIErrorType
@@ -38,8 +63,13 @@ func NewAPIError(errorType APIErrorType, message string, status int) *BookloreAP
 	}
 }
 
-// NewNetworkError creates a new network-related error
+// NewNetworkError creates a new network-related error. Errors of the auth
+// provider, e.g. a failed login, are passed through as they are.
 func NewNetworkError(err error) *BookloreAPIError {
+	var apiErr *BookloreAPIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
 	return NewAPIError(ErrNetworkError, fmt.Sprintf("Network error: %v", err), 0)
 }
 
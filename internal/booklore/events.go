@@ -0,0 +1,376 @@
+package booklore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// Destinations Booklore pushes events to, the same ones its web UI subscribes to
+const (
+	TopicBookAdded     = "/user/queue/book-add"
+	TopicBooksRemoved  = "/user/queue/books-remove"
+	TopicBookUpdated   = "/user/queue/book-metadata-update"
+	TopicBookdropFiles = "/user/queue/bookdrop-file"
+	TopicLog           = "/user/queue/log"
+)
+
+// eventTopics are the destinations an EventStream subscribes to
+var eventTopics = []string{TopicBookAdded, TopicBooksRemoved, TopicBookUpdated, TopicBookdropFiles, TopicLog}
+
+// Event is a notification pushed by Booklore. It is one of BookAddedEvent,
+// BooksRemovedEvent, BookUpdatedEvent, BookdropEvent or LogEvent.
+type Event interface {
+	// Topic returns the destination the event was received on
+	Topic() string
+}
+
+// BookAddedEvent is sent when a book was added to a library, e.g. by a bookdrop import
+type BookAddedEvent struct {
+	Book Book
+}
+
+// BooksRemovedEvent is sent when books were deleted
+type BooksRemovedEvent struct {
+	BookIDs []int64
+}
+
+// BookUpdatedEvent is sent when the metadata of a book changed
+type BookUpdatedEvent struct {
+	Book Book
+}
+
+// BookdropEvent is sent when files were added to or removed from the bookdrop
+type BookdropEvent struct {
+	Notification BookdropFileNotification
+}
+
+// LogEvent carries a status message Booklore shows in its web UI
+type LogEvent struct {
+	Log LogNotification
+}
+
+func (BookAddedEvent) Topic() string    { return TopicBookAdded }
+func (BooksRemovedEvent) Topic() string { return TopicBooksRemoved }
+func (BookUpdatedEvent) Topic() string  { return TopicBookUpdated }
+func (BookdropEvent) Topic() string     { return TopicBookdropFiles }
+func (LogEvent) Topic() string          { return TopicLog }
+
+// Reconnect backoff and heart-beat settings
+const (
+	eventMinBackoff = time.Second
+	eventMaxBackoff = time.Minute
+	eventHeartBeat  = 10 * time.Second
+	eventBufferSize = 16
+)
+
+// EventStream subscribes to Booklore's STOMP-over-WebSocket endpoint, reconnects
+// when the connection drops and fans events out to subscribers
+type EventStream struct {
+	url         string
+	auth        AuthProvider
+	dialer      *websocket.Dialer
+	subscribers map[int]chan Event
+	nextID      int
+	mutex       sync.Mutex
+	connected   atomic.Bool
+	heartBeat   time.Duration
+	logger      *zap.Logger
+}
+
+// EventStreamURL derives the WebSocket endpoint from the Booklore API URL
+func EventStreamURL(apiURL string) string {
+	switch {
+	case strings.HasPrefix(apiURL, "https://"):
+		return "wss://" + strings.TrimPrefix(apiURL, "https://") + "/ws"
+	case strings.HasPrefix(apiURL, "http://"):
+		return "ws://" + strings.TrimPrefix(apiURL, "http://") + "/ws"
+	}
+	return apiURL + "/ws"
+}
+
+// NewEventStream creates a stream for a ws:// or wss:// URL. Call Run to connect.
+func NewEventStream(wsURL string, auth AuthProvider, logger *zap.Logger) *EventStream {
+	return &EventStream{
+		url:  wsURL,
+		auth: auth,
+		dialer: &websocket.Dialer{
+			HandshakeTimeout: 30 * time.Second,
+			Subprotocols:     []string{"v12.stomp", "v11.stomp"},
+		},
+		subscribers: make(map[int]chan Event),
+		heartBeat:   eventHeartBeat,
+		logger:      logger,
+	}
+}
+
+// Connected reports whether the stream is currently receiving events
+func (s *EventStream) Connected() bool {
+	return s.connected.Load()
+}
+
+// Subscribe returns a channel receiving every event and a function that ends the
+// subscription. Events are dropped for subscribers that don't keep up.
+func (s *EventStream) Subscribe() (<-chan Event, func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	id := s.nextID
+	s.nextID++
+	ch := make(chan Event, eventBufferSize)
+	s.subscribers[id] = ch
+
+	return ch, func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		if _, ok := s.subscribers[id]; ok {
+			delete(s.subscribers, id)
+			close(ch)
+		}
+	}
+}
+
+// publish hands an event to every subscriber without blocking
+func (s *EventStream) publish(event Event) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for id, ch := range s.subscribers {
+		select {
+		case ch <- event:
+		default:
+			s.logger.Debug("Dropped Booklore event for slow subscriber",
+				zap.Int("subscriber", id),
+				zap.String("topic", event.Topic()))
+		}
+	}
+}
+
+// Run keeps the stream connected until the context is cancelled
+func (s *EventStream) Run(ctx context.Context) {
+	backoff := eventMinBackoff
+
+	for {
+		connected, err := s.session(ctx)
+		s.connected.Store(false)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			backoff = eventMinBackoff
+		}
+
+		s.logger.Warn("Booklore event stream disconnected, reconnecting",
+			zap.Duration("delay", backoff),
+			zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > eventMaxBackoff {
+			backoff = eventMaxBackoff
+		}
+	}
+}
+
+// session connects, subscribes and reads events until the connection fails.
+// It reports whether the broker accepted the connection.
+func (s *EventStream) session(ctx context.Context) (bool, error) {
+	apiToken, err := s.auth.Token(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+apiToken)
+
+	conn, _, err := s.dialer.DialContext(ctx, s.url, header)
+	if err != nil {
+		return false, fmt.Errorf("failed to connect to %s: %w", s.url, err)
+	}
+	defer conn.Close()
+
+	// Unblock the read loop when the bot shuts down
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	host := s.url
+	if u, err := url.Parse(s.url); err == nil {
+		host = u.Hostname()
+	}
+
+	connect := newStompFrame("CONNECT",
+		"accept-version", "1.2,1.1",
+		"host", host,
+		"heart-beat", fmt.Sprintf("%d,%d", s.heartBeat.Milliseconds(), s.heartBeat.Milliseconds()),
+		"Authorization", "Bearer "+apiToken)
+	if err := conn.WriteMessage(websocket.TextMessage, connect.Marshal()); err != nil {
+		return false, fmt.Errorf("failed to send CONNECT: %w", err)
+	}
+
+	frame, err := s.readFrame(conn, 30*time.Second)
+	if err != nil {
+		return false, err
+	}
+	if frame.Command != "CONNECTED" {
+		// The token may have been revoked or expired, get a new one for the next attempt
+		s.auth.Invalidate(apiToken)
+		return false, fmt.Errorf("broker refused connection: %s %s", frame.Headers["message"], frame.Body)
+	}
+
+	// The broker sends heart-beats at the slower of both intervals; allow a few to go missing
+	readTimeout := time.Duration(0)
+	if serverBeat := parseHeartBeat(frame.Headers["heart-beat"]); serverBeat > 0 {
+		readTimeout = 3 * max(serverBeat, s.heartBeat)
+	}
+
+	var writeMutex sync.Mutex
+	write := func(data []byte) error {
+		writeMutex.Lock()
+		defer writeMutex.Unlock()
+		return conn.WriteMessage(websocket.TextMessage, data)
+	}
+
+	for i, topic := range eventTopics {
+		subscribe := newStompFrame("SUBSCRIBE", "id", fmt.Sprintf("sub-%d", i), "destination", topic, "ack", "auto")
+		if err := write(subscribe.Marshal()); err != nil {
+			return true, fmt.Errorf("failed to subscribe to %s: %w", topic, err)
+		}
+	}
+
+	s.connected.Store(true)
+	s.logger.Info("Connected to Booklore event stream",
+		zap.String("url", s.url),
+		zap.String("version", frame.Headers["version"]))
+
+	go func() {
+		ticker := time.NewTicker(s.heartBeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := write([]byte("\n")); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		frame, err := s.readFrame(conn, readTimeout)
+		if err != nil {
+			return true, err
+		}
+
+		switch frame.Command {
+		case "MESSAGE":
+			s.handleMessage(frame)
+		case "ERROR":
+			return true, fmt.Errorf("broker error: %s %s", frame.Headers["message"], frame.Body)
+		}
+	}
+}
+
+// readFrame reads the next frame, skipping heart-beats. The broker must send
+// something at least every timeout, a heart-beat or a frame; 0 waits forever.
+func (s *EventStream) readFrame(conn *websocket.Conn, timeout time.Duration) (*stompFrame, error) {
+	for {
+		if timeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(timeout))
+		} else {
+			conn.SetReadDeadline(time.Time{})
+		}
+
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read from event stream: %w", err)
+		}
+
+		frame, err := parseStompFrame(data)
+		if err != nil {
+			return nil, err
+		}
+		if frame != nil {
+			return frame, nil
+		}
+	}
+}
+
+// handleMessage decodes a MESSAGE frame into a typed event and publishes it
+func (s *EventStream) handleMessage(frame *stompFrame) {
+	destination := frame.Headers["destination"]
+
+	var event Event
+	var err error
+	switch destination {
+	case TopicBookAdded:
+		var e BookAddedEvent
+		err = json.Unmarshal(frame.Body, &e.Book)
+		event = e
+	case TopicBooksRemoved:
+		var e BooksRemovedEvent
+		err = json.Unmarshal(frame.Body, &e.BookIDs)
+		event = e
+	case TopicBookUpdated:
+		var e BookUpdatedEvent
+		err = json.Unmarshal(frame.Body, &e.Book)
+		event = e
+	case TopicBookdropFiles:
+		var e BookdropEvent
+		err = json.Unmarshal(frame.Body, &e.Notification)
+		event = e
+	case TopicLog:
+		var e LogEvent
+		err = json.Unmarshal(frame.Body, &e.Log)
+		event = e
+	default:
+		s.logger.Debug("Ignoring Booklore event for unknown destination",
+			zap.String("destination", destination))
+		return
+	}
+
+	if err != nil {
+		s.logger.Warn("Failed to decode Booklore event",
+			zap.String("destination", destination),
+			zap.Error(err))
+		return
+	}
+
+	s.logger.Debug("Received Booklore event",
+		zap.String("destination", destination))
+	s.publish(event)
+}
+
+// parseHeartBeat returns the interval a broker sends heart-beats at, or 0 if it sends none
+func parseHeartBeat(value string) time.Duration {
+	send, _, _ := strings.Cut(value, ",")
+	ms, err := strconv.Atoi(strings.TrimSpace(send))
+	if err != nil || ms <= 0 {
+		return 0
+	}
+	return time.Duration(ms) * time.Millisecond
+}
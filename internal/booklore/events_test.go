@@ -0,0 +1,194 @@
+package booklore
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap/zaptest"
+)
+
+// fakeBroker is a minimal STOMP broker. Each connection is accepted, subscribed
+// and then handed to the test's script.
+type fakeBroker struct {
+	t           *testing.T
+	connections atomic.Int32
+	mutex       sync.Mutex
+	subscribed  []string
+	script      func(n int, conn *websocket.Conn)
+}
+
+func (b *fakeBroker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{Subprotocols: []string{"v12.stomp"}}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		b.t.Errorf("upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+	n := int(b.connections.Add(1))
+
+	if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+		b.t.Errorf("handshake Authorization = %q, want Bearer secret", got)
+	}
+
+	connect := b.read(conn)
+	if connect == nil || connect.Command != "CONNECT" {
+		b.t.Errorf("first frame = %+v, want CONNECT", connect)
+		return
+	}
+	if got := connect.Headers["Authorization"]; got != "Bearer secret" {
+		b.t.Errorf("CONNECT Authorization = %q, want Bearer secret", got)
+	}
+	b.send(conn, newStompFrame("CONNECTED", "version", "1.2", "heart-beat", "50,50"))
+
+	for range eventTopics {
+		subscribe := b.read(conn)
+		if subscribe == nil || subscribe.Command != "SUBSCRIBE" {
+			b.t.Errorf("frame = %+v, want SUBSCRIBE", subscribe)
+			return
+		}
+		b.mutex.Lock()
+		b.subscribed = append(b.subscribed, subscribe.Headers["destination"])
+		b.mutex.Unlock()
+	}
+
+	b.script(n, conn)
+}
+
+// read returns the next frame from the client, skipping heart-beats
+func (b *fakeBroker) read(conn *websocket.Conn) *stompFrame {
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return nil
+		}
+		frame, err := parseStompFrame(data)
+		if err != nil {
+			b.t.Errorf("invalid frame from client: %v", err)
+			return nil
+		}
+		if frame != nil {
+			return frame
+		}
+	}
+}
+
+func (b *fakeBroker) send(conn *websocket.Conn, frame *stompFrame) {
+	if err := conn.WriteMessage(websocket.TextMessage, frame.Marshal()); err != nil {
+		b.t.Errorf("failed to send %s: %v", frame.Command, err)
+	}
+}
+
+func message(destination, body string) *stompFrame {
+	frame := newStompFrame("MESSAGE", "destination", destination, "subscription", "sub-0", "message-id", "1")
+	frame.Body = []byte(body)
+	return frame
+}
+
+func nextEvent(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an event")
+		return nil
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	broker := &fakeBroker{t: t}
+	broker.script = func(n int, conn *websocket.Conn) {
+		// Only heart-beats for longer than the read timeout of 150ms; the stream
+		// has to stay connected because each one extends the deadline
+		for i := 0; i < 15; i++ {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte("\n")); err != nil {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+
+		if n == 1 {
+			broker.send(conn, message(TopicBookdropFiles, `{"pendingCount":3,"totalCount":5}`))
+			// Drop the connection, the stream has to reconnect
+			return
+		}
+
+		broker.send(conn, message("/user/queue/unknown", `{}`))
+		broker.send(conn, message(TopicBookAdded, `{"id":42,"libraryId":7}`))
+		broker.read(conn)
+	}
+
+	server := httptest.NewServer(broker)
+	defer server.Close()
+
+	stream := NewEventStream("ws"+strings.TrimPrefix(server.URL, "http"), NewStaticToken("secret"), zaptest.NewLogger(t))
+	stream.heartBeat = 50 * time.Millisecond
+
+	events, unsubscribe := stream.Subscribe()
+	defer unsubscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		stream.Run(ctx)
+		close(done)
+	}()
+
+	bookdrop, ok := nextEvent(t, events).(BookdropEvent)
+	if !ok || bookdrop.Notification.PendingCount != 3 || bookdrop.Notification.TotalCount != 5 {
+		t.Fatalf("first event = %+v, want bookdrop event with 3 of 5 pending", bookdrop)
+	}
+
+	added, ok := nextEvent(t, events).(BookAddedEvent)
+	if !ok || added.Book.ID != 42 || added.Book.LibraryID != 7 {
+		t.Fatalf("second event = %+v, want book 42 added to library 7", added)
+	}
+	if !stream.Connected() {
+		t.Error("stream reports disconnected while receiving events")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after the context was cancelled")
+	}
+
+	// One connection per script run: the heart-beats never timed out the stream
+	if got := broker.connections.Load(); got != 2 {
+		t.Errorf("connections = %d, want 2", got)
+	}
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+	if len(broker.subscribed) != 2*len(eventTopics) {
+		t.Errorf("subscribed to %v, want every topic on both connections", broker.subscribed)
+	}
+	for i, topic := range broker.subscribed {
+		if want := eventTopics[i%len(eventTopics)]; topic != want {
+			t.Errorf("subscription %d = %s, want %s", i, topic, want)
+		}
+	}
+}
+
+func TestParseHeartBeat(t *testing.T) {
+	tests := map[string]time.Duration{
+		"10000,10000": 10 * time.Second,
+		"0,0":         0,
+		"":            0,
+		"500, 0":      500 * time.Millisecond,
+		"nonsense":    0,
+	}
+	for value, want := range tests {
+		if got := parseHeartBeat(value); got != want {
+			t.Errorf("parseHeartBeat(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
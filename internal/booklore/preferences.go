@@ -2,10 +2,9 @@ package booklore
 
 import (
 	"encoding/json"
-	"os"
-	"path/filepath"
 	"sync"
 
+	"github.com/brauni/booklore-tg-bot/internal/storage"
 	"go.uber.org/zap"
 )
 
@@ -17,12 +16,45 @@ type UserPreferences struct {
 	pathName    string
 }
 
+// storedPreferences is the on-disk representation of UserPreferences
+type storedPreferences struct {
+	LibraryID   int64  `json:"libraryId"`
+	PathID      int64  `json:"pathId"`
+	LibraryName string `json:"libraryName"`
+	PathName    string `json:"pathName"`
+}
+
+// MarshalJSON stores the unexported preference fields
+func (up *UserPreferences) MarshalJSON() ([]byte, error) {
+	return json.Marshal(storedPreferences{
+		LibraryID:   up.libraryID,
+		PathID:      up.pathID,
+		LibraryName: up.libraryName,
+		PathName:    up.pathName,
+	})
+}
+
+// UnmarshalJSON restores preferences written by MarshalJSON
+func (up *UserPreferences) UnmarshalJSON(data []byte) error {
+	var stored storedPreferences
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return err
+	}
+
+	up.libraryID = stored.LibraryID
+	up.pathID = stored.PathID
+	up.libraryName = stored.LibraryName
+	up.pathName = stored.PathName
+	return nil
+}
+
 // PreferenceManager manages user preferences with persistent storage
 type PreferenceManager struct {
 	preferences map[int64]*UserPreferences
 	mutex       sync.RWMutex
 	logger      *zap.Logger
 	storagePath string
+	file        *storage.JSONFile
 }
 
 // NewPreferenceManager creates a new preference manager
@@ -32,6 +64,9 @@ func NewPreferenceManager(logger *zap.Logger, storagePath string) *PreferenceMan
 		logger:      logger,
 		storagePath: storagePath,
 	}
+	if storagePath != "" {
+		pm.file = storage.NewJSONFile(storagePath)
+	}
 
 	// Load existing preferences from file
 	pm.loadPreferences()
@@ -46,38 +81,23 @@ func (pm *PreferenceManager) loadPreferences() {
 		return
 	}
 
-	// Ensure directory exists
-	dir := filepath.Dir(pm.storagePath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		pm.logger.Error("Failed to create preferences directory",
-			zap.String("path", dir),
-			zap.Error(err))
-		return
-	}
-
-	// Check if file exists
-	if _, err := os.Stat(pm.storagePath); os.IsNotExist(err) {
-		pm.logger.Info("Preferences file does not exist, starting fresh",
-			zap.String("path", pm.storagePath))
-		return
-	}
-
-	// Read and parse file
-	data, err := os.ReadFile(pm.storagePath)
+	// Read and parse file; a plain file is encrypted here if encryption is enabled
+	var storedPrefs map[int64]*UserPreferences
+	found, err := pm.file.Load(&storedPrefs)
 	if err != nil {
 		pm.logger.Error("Failed to read preferences file",
 			zap.String("path", pm.storagePath),
 			zap.Error(err))
 		return
 	}
-
-	var storedPrefs map[int64]*UserPreferences
-	if err := json.Unmarshal(data, &storedPrefs); err != nil {
-		pm.logger.Error("Failed to parse preferences file",
-			zap.String("path", pm.storagePath),
-			zap.Error(err))
+	if !found {
+		pm.logger.Info("Preferences file does not exist, starting fresh",
+			zap.String("path", pm.storagePath))
 		return
 	}
+	if storedPrefs == nil {
+		storedPrefs = make(map[int64]*UserPreferences)
+	}
 
 	pm.mutex.Lock()
 	defer pm.mutex.Unlock()
@@ -97,14 +117,7 @@ func (pm *PreferenceManager) savePreferences() {
 	pm.mutex.RLock()
 	defer pm.mutex.RUnlock()
 
-	data, err := json.MarshalIndent(pm.preferences, "", "  ")
-	if err != nil {
-		pm.logger.Error("Failed to marshal preferences",
-			zap.Error(err))
-		return
-	}
-
-	if err := os.WriteFile(pm.storagePath, data, 0644); err != nil {
+	if err := pm.file.Save(pm.preferences); err != nil {
 		pm.logger.Error("Failed to save preferences file",
 			zap.String("path", pm.storagePath),
 			zap.Error(err))
@@ -129,6 +142,18 @@ func (pm *PreferenceManager) GetUserPreference(userID int64) *UserPreferences {
 	return &UserPreferences{}
 }
 
+// UserIDs returns the users that have a preference set
+func (pm *PreferenceManager) UserIDs() []int64 {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+
+	userIDs := make([]int64, 0, len(pm.preferences))
+	for userID := range pm.preferences {
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs
+}
+
 // GetLibraryID returns the library ID
 func (up *UserPreferences) GetLibraryID() int64 {
 	return up.libraryID
@@ -0,0 +1,92 @@
+package booklore
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// stompFrame is a single STOMP 1.2 frame
+type stompFrame struct {
+	Command string
+	Headers map[string]string
+	Body    []byte
+}
+
+// newStompFrame creates a frame from a command and header name/value pairs
+func newStompFrame(command string, headers ...string) *stompFrame {
+	frame := &stompFrame{Command: command, Headers: make(map[string]string)}
+	for i := 0; i+1 < len(headers); i += 2 {
+		frame.Headers[headers[i]] = headers[i+1]
+	}
+	return frame
+}
+
+// stompHeaderEscaper escapes header values as STOMP 1.2 requires
+var stompHeaderEscaper = strings.NewReplacer("\\", "\\\\", "\r", "\\r", "\n", "\\n", ":", "\\c")
+
+// stompHeaderUnescaper reverses stompHeaderEscaper
+var stompHeaderUnescaper = strings.NewReplacer("\\\\", "\\", "\\r", "\r", "\\n", "\n", "\\c", ":")
+
+// Marshal encodes the frame including its terminating NUL byte
+func (f *stompFrame) Marshal() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(f.Command)
+	buf.WriteByte('\n')
+
+	for name, value := range f.Headers {
+		// CONNECT frames are sent unescaped for compatibility with STOMP 1.0 brokers
+		if f.Command != "CONNECT" {
+			name = stompHeaderEscaper.Replace(name)
+			value = stompHeaderEscaper.Replace(value)
+		}
+		buf.WriteString(name)
+		buf.WriteByte(':')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+	}
+
+	buf.WriteByte('\n')
+	buf.Write(f.Body)
+	buf.WriteByte(0)
+	return buf.Bytes()
+}
+
+// parseStompFrame decodes a frame. It returns nil for heart-beats, which are bare newlines.
+func parseStompFrame(data []byte) (*stompFrame, error) {
+	data = bytes.TrimLeft(data, "\r\n")
+	if len(data) == 0 {
+		return nil, nil
+	}
+	data = bytes.TrimRight(data, "\r\n")
+	data = bytes.TrimSuffix(data, []byte{0})
+
+	head, body, found := bytes.Cut(data, []byte("\n\n"))
+	if !found {
+		head, body, found = bytes.Cut(data, []byte("\r\n\r\n"))
+	}
+	if !found {
+		return nil, fmt.Errorf("invalid STOMP frame: missing header terminator")
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(head), "\r\n", "\n"), "\n")
+	frame := &stompFrame{
+		Command: lines[0],
+		Headers: make(map[string]string),
+		Body:    body,
+	}
+
+	for _, line := range lines[1:] {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid STOMP header '%s'", line)
+		}
+		name = stompHeaderUnescaper.Replace(name)
+		// Repeated headers keep their first value
+		if _, exists := frame.Headers[name]; !exists {
+			frame.Headers[name] = stompHeaderUnescaper.Replace(value)
+		}
+	}
+
+	return frame, nil
+}
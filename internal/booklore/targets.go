@@ -0,0 +1,120 @@
+package booklore
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// TargetSpec names an import target. Library and path are given by ID or name;
+// an empty path picks the library's first path.
+type TargetSpec struct {
+	Library string
+	Path    string
+}
+
+// ImportTarget is a library and path files are imported into
+type ImportTarget struct {
+	LibraryID   int64
+	PathID      int64
+	LibraryName string
+	PathName    string
+}
+
+// String describes the target for display
+func (t *ImportTarget) String() string {
+	if t.PathName == "" {
+		return t.LibraryName
+	}
+	return fmt.Sprintf("%s / %s", t.LibraryName, t.PathName)
+}
+
+// ParseTargetSpec parses "lib:<library>" or "lib:<library>/path:<path>"
+func ParseTargetSpec(spec string) (TargetSpec, error) {
+	spec = strings.TrimSpace(spec)
+	if !strings.HasPrefix(strings.ToLower(spec), "lib:") {
+		return TargetSpec{}, fmt.Errorf("invalid target '%s', expected lib:<library>[/path:<path>]", spec)
+	}
+	spec = spec[len("lib:"):]
+
+	var target TargetSpec
+	if i := strings.Index(strings.ToLower(spec), "/path:"); i >= 0 {
+		target.Library = strings.TrimSpace(spec[:i])
+		target.Path = strings.TrimSpace(spec[i+len("/path:"):])
+		if target.Path == "" {
+			return TargetSpec{}, fmt.Errorf("invalid target 'lib:%s', the path is empty", spec)
+		}
+	} else {
+		target.Library = strings.TrimSpace(spec)
+	}
+
+	if target.Library == "" {
+		return TargetSpec{}, fmt.Errorf("invalid target 'lib:%s', the library is empty", spec)
+	}
+	return target, nil
+}
+
+// String formats the spec in the syntax ParseTargetSpec accepts
+func (s TargetSpec) String() string {
+	if s.Path == "" {
+		return "lib:" + s.Library
+	}
+	return fmt.Sprintf("lib:%s/path:%s", s.Library, s.Path)
+}
+
+// ResolveTarget finds the library and path a spec names. Names are matched
+// case-insensitively; paths also match by their last directory.
+func ResolveTarget(libraries []Library, spec TargetSpec) (*ImportTarget, error) {
+	var library *Library
+	for i := range libraries {
+		if matchesIDOrName(spec.Library, libraries[i].ID, libraries[i].Name) {
+			library = &libraries[i]
+			break
+		}
+	}
+	if library == nil {
+		return nil, fmt.Errorf("library '%s' not found", spec.Library)
+	}
+
+	if len(library.Paths) == 0 {
+		return nil, fmt.Errorf("library '%s' has no paths", library.Name)
+	}
+
+	if spec.Path == "" {
+		return targetFor(library, &library.Paths[0]), nil
+	}
+
+	for i := range library.Paths {
+		p := &library.Paths[i]
+		if matchesIDOrName(spec.Path, p.ID, p.Name) || strings.EqualFold(spec.Path, path.Base(p.Name)) {
+			return targetFor(library, p), nil
+		}
+	}
+	return nil, fmt.Errorf("path '%s' not found in library '%s'", spec.Path, library.Name)
+}
+
+// FindTarget returns the target for a library and path ID
+func FindTarget(libraries []Library, libraryID, pathID int64) (*ImportTarget, error) {
+	spec := TargetSpec{Library: strconv.FormatInt(libraryID, 10)}
+	if pathID != 0 {
+		spec.Path = strconv.FormatInt(pathID, 10)
+	}
+	return ResolveTarget(libraries, spec)
+}
+
+func targetFor(library *Library, p *LibraryPath) *ImportTarget {
+	return &ImportTarget{
+		LibraryID:   library.ID,
+		PathID:      p.ID,
+		LibraryName: library.Name,
+		PathName:    p.Name,
+	}
+}
+
+func matchesIDOrName(value string, id int64, name string) bool {
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil && n == id {
+		return true
+	}
+	return strings.EqualFold(value, name)
+}
@@ -0,0 +1,49 @@
+package booklore
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brauni/booklore-tg-bot/internal/metrics"
+	"github.com/brauni/booklore-tg-bot/internal/tracing"
+)
+
+// instrumentedTransport records the latency of every Booklore API request and
+// traces it as part of the caller's trace
+type instrumentedTransport struct {
+	next http.RoundTripper
+}
+
+func (t instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := endpointLabel(req.URL.Path)
+	req, span := tracing.StartRequest(req, endpoint)
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	tracing.EndRequest(span, resp, err)
+
+	code := 0
+	if resp != nil {
+		code = resp.StatusCode
+	}
+	metrics.ObserveAPIRequest(req.Method, endpoint, code, time.Since(start))
+	if err != nil {
+		metrics.ObserveAPIError(ErrNetworkError.String())
+	}
+
+	return resp, err
+}
+
+// endpointLabel replaces IDs in a path so requests for different books share a label,
+// e.g. "/api/v1/books/42/download" becomes "/api/v1/books/{id}/download"
+func endpointLabel(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if _, err := strconv.ParseInt(segment, 10, 64); err == nil {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -9,6 +9,10 @@ type BookdropFile struct {
 	Status      string `json:"status"`
 	DateAdded   string `json:"dateAdded"`
 	DateScanned string `json:"dateScanned"`
+
+	// Metadata extracted from the file and metadata fetched from online providers
+	OriginalMetadata *BookMetadata `json:"originalMetadata,omitempty"`
+	FetchedMetadata  *BookMetadata `json:"fetchedMetadata,omitempty"`
 }
 
 // BookdropFinalizeRequest represents a request to finalize bookdrop imports
@@ -16,6 +20,28 @@ type BookdropFinalizeRequest struct {
 	FileIDs []int64 `json:"fileIds"`
 }
 
+// BookdropFinalizeFile is a bookdrop file finalized with reviewed metadata
+type BookdropFinalizeFile struct {
+	FileID    int64         `json:"fileId"`
+	LibraryID int64         `json:"libraryId,omitempty"`
+	PathID    int64         `json:"pathId,omitempty"`
+	Metadata  *BookMetadata `json:"metadata"`
+}
+
+// BookdropFinalizeMetadataRequest finalizes bookdrop files with per-file metadata
+type BookdropFinalizeMetadataRequest struct {
+	Files            []BookdropFinalizeFile `json:"files"`
+	DefaultLibraryID int64                  `json:"defaultLibraryId,omitempty"`
+	DefaultPathID    int64                  `json:"defaultPathId,omitempty"`
+}
+
+// BookdropSelectionRequest selects bookdrop files, either by ID or all except some
+type BookdropSelectionRequest struct {
+	SelectAll   bool    `json:"selectAll"`
+	SelectedIDs []int64 `json:"selectedIds,omitempty"`
+	ExcludedIDs []int64 `json:"excludedIds,omitempty"`
+}
+
 // BookdropFinalizeResult represents the result of finalizing bookdrop imports
 type BookdropFinalizeResult struct {
 	Success       bool    `json:"success"`
@@ -35,6 +61,19 @@ type BookdropNotification struct {
 	FailedFiles    int `json:"failedFiles"`
 }
 
+// BookdropFileNotification is pushed over the event stream when the bookdrop changes
+type BookdropFileNotification struct {
+	PendingCount  int    `json:"pendingCount"`
+	TotalCount    int    `json:"totalCount"`
+	LastUpdatedAt string `json:"lastUpdatedAt"`
+}
+
+// LogNotification is a status message pushed over the event stream
+type LogNotification struct {
+	Timestamp string `json:"timestamp"`
+	Message   string `json:"message"`
+}
+
 // APIError represents an API error response
 type APIError struct {
 	Message   string `json:"message"`
@@ -81,3 +120,46 @@ type UserLibraryPreference struct {
 	LibraryName string `json:"libraryName"`
 	PathName    string `json:"pathName"`
 }
+
+// Book represents a book in a Booklore library
+type Book struct {
+	ID          int64         `json:"id"`
+	BookType    string        `json:"bookType"`
+	LibraryID   int64         `json:"libraryId"`
+	FileName    string        `json:"fileName"`
+	FilePath    string        `json:"filePath"`
+	FileSubPath string        `json:"fileSubPath"`
+	FileSizeKb  float64       `json:"fileSizeKb"`
+	AddedOn     string        `json:"addedOn"`
+	Metadata    *BookMetadata `json:"metadata"`
+}
+
+// BookMetadata represents the descriptive metadata of a book
+type BookMetadata struct {
+	Title         string   `json:"title"`
+	Subtitle      string   `json:"subtitle,omitempty"`
+	Authors       []string `json:"authors,omitempty"`
+	Publisher     string   `json:"publisher,omitempty"`
+	PublishedDate string   `json:"publishedDate,omitempty"`
+	Description   string   `json:"description,omitempty"`
+	SeriesName    string   `json:"seriesName,omitempty"`
+	SeriesNumber  float64  `json:"seriesNumber,omitempty"`
+	Language      string   `json:"language,omitempty"`
+	ISBN10        string   `json:"isbn10,omitempty"`
+	ISBN13        string   `json:"isbn13,omitempty"`
+	PageCount     int      `json:"pageCount,omitempty"`
+	Categories    []string `json:"categories,omitempty"`
+	// ThumbnailURL is the cover to use; empty keeps the cover embedded in the file
+	ThumbnailURL string `json:"thumbnailUrl,omitempty"`
+}
+
+// PageBook represents a paginated list of books
+type PageBook struct {
+	Content       []Book `json:"content"`
+	TotalElements int    `json:"totalElements"`
+	TotalPages    int    `json:"totalPages"`
+	Size          int    `json:"size"`
+	Number        int    `json:"number"`
+	First         bool   `json:"first"`
+	Last          bool   `json:"last"`
+}
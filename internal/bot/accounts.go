@@ -0,0 +1,168 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/brauni/booklore-tg-bot/internal/accounts"
+	"github.com/brauni/booklore-tg-bot/internal/audit"
+	"github.com/brauni/booklore-tg-bot/internal/booklore"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// errAccountNotLinked is returned when a user needs their own Booklore account
+var errAccountNotLinked = errors.New("link your Booklore account with /link first")
+
+// bookloreContext makes the Booklore requests made with ctx act as the user's
+// linked account. Users without one act through the bot's account if the
+// admin allows it, otherwise errAccountNotLinked is returned.
+func (b *Bot) bookloreContext(ctx context.Context, userID int64) (context.Context, error) {
+	if b.accounts == nil {
+		return ctx, nil
+	}
+
+	provider, linked, err := b.accounts.Auth(userID)
+	if err != nil {
+		b.config.Logger.Error("Failed to use linked Booklore account",
+			zap.Int64("user_id", userID),
+			zap.Error(err))
+		return ctx, fmt.Errorf("your linked Booklore account can't be used, link it again with /link")
+	}
+	if linked {
+		return booklore.WithAuth(ctx, provider), nil
+	}
+
+	if !b.settings().BookloreAPI.SharedFallback {
+		return ctx, errAccountNotLinked
+	}
+	return ctx, nil
+}
+
+// handleLinkCommand links the user's Booklore account: /link <username> <password> or /link <token>
+func (b *Bot) handleLinkCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	userID := message.From.ID
+	args := strings.Fields(message.CommandArguments())
+
+	if b.accounts == nil {
+		b.api.Send(tgbotapi.NewMessage(chatID, "❌ Linking Booklore accounts is not enabled."))
+		return
+	}
+
+	// Credentials must not stay readable in a chat history, least of all a shared one
+	if len(args) > 0 {
+		b.api.Request(tgbotapi.NewDeleteMessage(chatID, message.MessageID))
+	}
+	if !message.Chat.IsPrivate() {
+		text := "🔒 Send /link to me in a private chat."
+		if len(args) > 0 {
+			text += " Your message was deleted, but change your Booklore password if others saw it."
+		}
+		b.api.Send(tgbotapi.NewMessage(chatID, text))
+		return
+	}
+
+	var username, secret string
+	switch len(args) {
+	case 0:
+		b.sendAccountStatus(chatID, userID)
+		return
+	case 1:
+		secret = args[0]
+	case 2:
+		username, secret = args[0], args[1]
+	default:
+		b.api.Send(tgbotapi.NewMessage(chatID, "❌ Usage: /link <username> <password> or /link <API token>"))
+		return
+	}
+
+	method := "token"
+	if username != "" {
+		method = "password"
+	}
+	params := map[string]string{"account": "linked", "method": method}
+	if username != "" {
+		params["username"] = username
+	}
+
+	// Make sure the credentials work before storing them
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	provider := accounts.NewProvider(b.config.BookloreAPI.APIURL, username, secret, b.config.Logger)
+	_, err := b.booklore.GetLibraries(booklore.WithAuth(ctx, provider))
+	if err == nil {
+		err = b.accounts.Link(userID, username, secret)
+	}
+	b.recordAudit(userID, audit.ActionAccount, "", params, err)
+	if err != nil {
+		b.config.Logger.Warn("Failed to link Booklore account",
+			zap.Int64("user_id", userID),
+			zap.String("method", method),
+			zap.Error(err))
+		b.sendErrorMessage(chatID, fmt.Sprintf("Failed to link your Booklore account: %s", err.Error()))
+		return
+	}
+
+	b.config.Logger.Info("Booklore account linked",
+		zap.Int64("user_id", userID),
+		zap.String("method", method))
+
+	text := "✅ Booklore account linked."
+	if username != "" {
+		text = fmt.Sprintf("✅ Booklore account '%s' linked.", username)
+	}
+	text += " Imports, searches and downloads now use your account.\n\n🔒 Your message with the credentials was deleted. Use /unlink to remove them from the bot."
+	b.api.Send(tgbotapi.NewMessage(chatID, text))
+}
+
+// handleUnlinkCommand removes the user's linked Booklore account
+func (b *Bot) handleUnlinkCommand(chatID int64, userID int64) {
+	if b.accounts == nil {
+		b.api.Send(tgbotapi.NewMessage(chatID, "❌ Linking Booklore accounts is not enabled."))
+		return
+	}
+
+	removed, err := b.accounts.Unlink(userID)
+	if !removed {
+		b.api.Send(tgbotapi.NewMessage(chatID, "ℹ️ No Booklore account is linked."))
+		return
+	}
+	b.recordAudit(userID, audit.ActionAccount, "", map[string]string{"account": "unlinked"}, err)
+	if err != nil {
+		b.sendErrorMessage(chatID, fmt.Sprintf("Failed to unlink your Booklore account: %s", err.Error()))
+		return
+	}
+
+	b.config.Logger.Info("Booklore account unlinked",
+		zap.Int64("user_id", userID))
+
+	text := "✅ Booklore account unlinked, your credentials were removed."
+	if !b.settings().BookloreAPI.SharedFallback {
+		text += " Link an account again to use Booklore."
+	}
+	b.api.Send(tgbotapi.NewMessage(chatID, text))
+}
+
+// sendAccountStatus tells the user which Booklore account they act as
+func (b *Bot) sendAccountStatus(chatID int64, userID int64) {
+	var text string
+	account, ok := b.accounts.Get(userID)
+	switch {
+	case ok && account.Username != "":
+		text = fmt.Sprintf("🔗 Linked to Booklore account '%s' since %s.", account.Username, account.LinkedAt.Local().Format("2006-01-02"))
+	case ok:
+		text = fmt.Sprintf("🔗 Linked to a Booklore API token since %s.", account.LinkedAt.Local().Format("2006-01-02"))
+	case b.settings().BookloreAPI.SharedFallback:
+		text = "🔗 No Booklore account linked, the bot's account is used."
+	default:
+		text = "🔗 No Booklore account linked. Link one to import and download books."
+	}
+
+	text += "\n\nUsage:\n/link <username> <password> - Log in with your Booklore account\n/link <API token> - Use a personal API token\n/unlink - Remove your credentials"
+	b.api.Send(tgbotapi.NewMessage(chatID, text))
+}
@@ -0,0 +1,39 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/brauni/booklore-tg-bot/internal/admin"
+	"github.com/brauni/booklore-tg-bot/internal/metrics"
+)
+
+// newAdminServer sets up the health, readiness and metrics endpoints
+func (b *Bot) newAdminServer() *admin.Server {
+	server := admin.NewServer(b.config.AdminListenAddr, b.config.Logger)
+
+	server.AddCheck("telegram", func(ctx context.Context) error {
+		if _, err := b.api.GetMe(); err != nil {
+			return fmt.Errorf("getMe failed: %w", err)
+		}
+		return nil
+	})
+
+	if b.booklore.IsEnabled() {
+		// The bookdrop summary is the cheapest authenticated request, so it
+		// also catches a revoked token
+		server.AddCheck("booklore", func(ctx context.Context) error {
+			_, err := b.booklore.GetBookdropNotification(ctx)
+			return err
+		})
+	}
+
+	server.AddCheck("data_folder", admin.CheckWritable(b.config.DataFolder))
+	server.AddCheck("download_folder", admin.CheckWritable(b.config.DownloadFolder))
+
+	if b.outbox != nil {
+		metrics.RegisterQueue("delivery", b.outbox.Pending)
+	}
+
+	return server
+}
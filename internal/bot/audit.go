@@ -0,0 +1,213 @@
+package bot
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brauni/booklore-tg-bot/internal/audit"
+	"github.com/brauni/booklore-tg-bot/internal/booklore"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// auditPageSize is the number of entries /audit shows
+const auditPageSize = 20
+
+// recordAudit adds an action to the audit log; err marks it as failed
+func (b *Bot) recordAudit(actorID int64, action, target string, params map[string]string, err error) {
+	entry := audit.Entry{
+		ActorID: actorID,
+		Action:  action,
+		Target:  target,
+		Params:  params,
+		Outcome: audit.OutcomeSuccess,
+	}
+	if err != nil {
+		entry.Outcome = audit.OutcomeFailure
+		entry.Error = err.Error()
+	}
+	b.audit.Record(entry)
+}
+
+// handleAuditCommand shows or exports the audit log: /audit [export] [user] [since]
+func (b *Bot) handleAuditCommand(chatID int64, userID int64, args string) {
+	if !b.auth.IsAdmin(userID) {
+		b.api.Send(tgbotapi.NewMessage(chatID, "⛔ Only admins can read the audit log."))
+		return
+	}
+
+	fields := strings.Fields(args)
+	export := len(fields) > 0 && strings.EqualFold(fields[0], "export")
+	if export {
+		fields = fields[1:]
+	}
+
+	filter, err := parseAuditFilter(fields, time.Now())
+	if err != nil {
+		b.api.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ %s\n\nUsage:\n/audit [user ID] [since] - Show recent actions\n/audit export [user ID] [since] - Download the log as JSON lines\n\nExamples: /audit 123456789 7d, /audit 2026-01-31", err)))
+		return
+	}
+
+	if export {
+		b.exportAuditLog(chatID, userID, filter)
+		return
+	}
+
+	entries, err := b.audit.Query(filter, auditPageSize)
+	if err != nil {
+		b.api.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Failed to read the audit log: %s", err)))
+		return
+	}
+	if len(entries) == 0 {
+		b.api.Send(tgbotapi.NewMessage(chatID, "📋 No matching audit log entries."))
+		return
+	}
+
+	var text strings.Builder
+	text.WriteString("📋 Audit log (newest first)\n\n")
+	for _, e := range entries {
+		text.WriteString(formatAuditEntry(e))
+		text.WriteString("\n")
+	}
+	if len(entries) == auditPageSize {
+		text.WriteString("\n💡 Use /audit export for the full log.")
+	}
+
+	b.api.Send(tgbotapi.NewMessage(chatID, text.String()))
+}
+
+// exportAuditLog sends matching entries as a JSON lines document
+func (b *Bot) exportAuditLog(chatID int64, userID int64, filter audit.Filter) {
+	var buf bytes.Buffer
+	count, err := b.audit.Export(&buf, filter)
+	b.recordAudit(userID, audit.ActionAuditExport, "", auditFilterParams(filter), err)
+	if err != nil {
+		b.api.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Failed to export the audit log: %s", err)))
+		return
+	}
+	if count == 0 {
+		b.api.Send(tgbotapi.NewMessage(chatID, "📋 No matching audit log entries."))
+		return
+	}
+
+	name := fmt.Sprintf("audit-%s.jsonl", time.Now().UTC().Format("20060102-150405"))
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{Name: name, Bytes: buf.Bytes()})
+	doc.Caption = fmt.Sprintf("📋 %d audit log entries", count)
+	b.api.Send(doc)
+}
+
+// parseAuditFilter reads an optional user ID and an optional start, given as a
+// duration like "24h" or "7d" or as a date like "2026-01-31"
+func parseAuditFilter(fields []string, now time.Time) (audit.Filter, error) {
+	var filter audit.Filter
+	for _, field := range fields {
+		if since, ok := parseAuditSince(field, now); ok {
+			filter.Since = since
+			continue
+		}
+		if id, err := strconv.ParseInt(field, 10, 64); err == nil && id > 0 && filter.ActorID == 0 {
+			filter.ActorID = id
+			continue
+		}
+		return audit.Filter{}, fmt.Errorf("'%s' is neither a user ID nor a time", field)
+	}
+	return filter, nil
+}
+
+// parseAuditSince parses "7d", "24h", "30m" or a date
+func parseAuditSince(value string, now time.Time) (time.Time, bool) {
+	if date, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return date, true
+	}
+	if days, found := strings.CutSuffix(value, "d"); found {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return now.AddDate(0, 0, -n), true
+		}
+		return time.Time{}, false
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return now.Add(-d), true
+	}
+	return time.Time{}, false
+}
+
+// auditFilterParams describes a filter for the audit log itself
+func auditFilterParams(filter audit.Filter) map[string]string {
+	params := map[string]string{}
+	if filter.ActorID != 0 {
+		params["user"] = strconv.FormatInt(filter.ActorID, 10)
+	}
+	if !filter.Since.IsZero() {
+		params["since"] = filter.Since.UTC().Format(time.RFC3339)
+	}
+	return params
+}
+
+// formatAuditEntry renders an entry on one line
+func formatAuditEntry(e audit.Entry) string {
+	icon := "✅"
+	if e.Outcome == audit.OutcomeFailure {
+		icon = "❌"
+	}
+
+	actor := "system"
+	if e.ActorID != audit.SystemActor {
+		actor = strconv.FormatInt(e.ActorID, 10)
+	}
+
+	line := fmt.Sprintf("%s %s %s %s", icon, e.Time.Local().Format("2006-01-02 15:04"), actor, e.Action)
+	if e.Target != "" {
+		line += " " + e.Target
+	}
+
+	if len(e.Params) > 0 {
+		keys := make([]string, 0, len(e.Params))
+		for key := range e.Params {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		var params []string
+		for _, key := range keys {
+			params = append(params, key+"="+truncateString(e.Params[key], 40))
+		}
+		line += " (" + strings.Join(params, ", ") + ")"
+	}
+
+	if e.Error != "" {
+		line += ": " + truncateString(e.Error, 80)
+	}
+	return line
+}
+
+// recordImportAudit adds a Booklore import to the audit log
+func (b *Bot) recordImportAudit(actorID int64, fileIDs []int64, libraryID, pathID string, params map[string]string, result *booklore.BookdropFinalizeResult, err error) {
+	if params == nil {
+		params = map[string]string{}
+	}
+
+	if len(fileIDs) > 0 {
+		params["files"] = auditFileIDs(fileIDs)
+	} else {
+		params["files"] = "all"
+	}
+
+	if result != nil {
+		params["imported"] = strconv.Itoa(result.ImportedCount)
+		params["failed"] = strconv.Itoa(result.FailedCount)
+	}
+
+	b.recordAudit(actorID, audit.ActionImport, fmt.Sprintf("library:%s/path:%s", libraryID, pathID), params, err)
+}
+
+// auditFileIDs joins bookdrop file IDs for an audit entry
+func auditFileIDs(fileIDs []int64) string {
+	ids := make([]string, len(fileIDs))
+	for i, id := range fileIDs {
+		ids[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(ids, ",")
+}
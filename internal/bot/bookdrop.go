@@ -0,0 +1,707 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/brauni/booklore-tg-bot/internal/audit"
+	"github.com/brauni/booklore-tg-bot/internal/booklore"
+	"github.com/brauni/booklore-tg-bot/internal/history"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// bookdropPageSize is the number of files listed per /bookdrop page
+	bookdropPageSize = 10
+	// importPageSize is the number of file buttons per /import page
+	importPageSize = 8
+	// bookdropFetchSize is the number of bookdrop files fetched for browsing
+	bookdropFetchSize = 1000
+)
+
+// bookdropFilterStatuses are the statuses offered as filter buttons, "" meaning all
+var bookdropFilterStatuses = []string{"", "NEW", "FAILED", "PENDING_REVIEW"}
+
+// bookdropSorts are the sort orders offered as buttons
+var bookdropSorts = []string{booklore.BookdropSortDate, booklore.BookdropSortSize, booklore.BookdropSortName}
+
+// bookdropView holds the state of a /bookdrop or /import browser message
+type bookdropView struct {
+	userID int64
+	// importMode shows file buttons that import instead of a plain listing
+	importMode bool
+	status     string
+	sort       string
+	filter     string
+	page       int
+	// selected holds the files ticked for import, in the order they were ticked
+	selected []bookdropSelection
+	// names remembers the names of the files on the current page
+	names map[int64]string
+	// discard holds the files waiting for the user to confirm discarding them
+	discard []bookdropSelection
+}
+
+// bookdropSelection is a file ticked in the /import browser
+type bookdropSelection struct {
+	id   int64
+	name string
+}
+
+// isSelected reports whether a file is ticked
+func (v *bookdropView) isSelected(fileID int64) bool {
+	for _, sel := range v.selected {
+		if sel.id == fileID {
+			return true
+		}
+	}
+	return false
+}
+
+// toggle ticks or unticks a file
+func (v *bookdropView) toggle(fileID int64, name string) {
+	for i, sel := range v.selected {
+		if sel.id == fileID {
+			v.selected = append(v.selected[:i], v.selected[i+1:]...)
+			return
+		}
+	}
+	v.selected = append(v.selected, bookdropSelection{id: fileID, name: name})
+}
+
+// parseBookdropArgs reads "status:<status>", "sort:<order>" and free text filter words
+func parseBookdropArgs(args string) (status, sortOrder, filter string) {
+	var words []string
+	for _, word := range strings.Fields(args) {
+		key, value, found := strings.Cut(word, ":")
+		switch {
+		case found && strings.EqualFold(key, "status"):
+			status = strings.ToUpper(value)
+		case found && strings.EqualFold(key, "sort"):
+			sortOrder = strings.ToLower(value)
+		default:
+			words = append(words, word)
+		}
+	}
+	return status, sortOrder, strings.Join(words, " ")
+}
+
+// openBookdropBrowser sends a new bookdrop browser message
+func (b *Bot) openBookdropBrowser(chatID int64, userID int64, importMode bool, args string) {
+	status, sortOrder, filter := parseBookdropArgs(args)
+	view := &bookdropView{
+		userID:     userID,
+		importMode: importMode,
+		status:     status,
+		sort:       sortOrder,
+		filter:     filter,
+	}
+
+	// Send typing indicator to show we're working
+	action := tgbotapi.NewChatAction(chatID, "typing")
+	b.api.Send(action)
+
+	text, markup, err := b.buildBookdropPage(view)
+	if err != nil {
+		b.config.Logger.Error("Failed to get bookdrop files",
+			zap.Error(err),
+			zap.String("api_url", b.config.BookloreAPI.APIURL))
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Failed to retrieve bookdrop files: %s", err.Error()))
+		b.api.Send(msg)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	if markup != nil {
+		msg.ReplyMarkup = *markup
+	}
+	sent, err := b.api.Send(msg)
+	if err != nil {
+		b.config.Logger.Error("Failed to send bookdrop browser",
+			zap.Error(err))
+		return
+	}
+
+	b.sessions.Set(chatID, sent.MessageID, view)
+}
+
+// handleBookdropCallback handles the paging, filter and sort buttons of the bookdrop browsers
+func (b *Bot) handleBookdropCallback(callback *tgbotapi.CallbackQuery) {
+	chatID := callback.Message.Chat.ID
+	messageID := callback.Message.MessageID
+	data := callback.Data
+
+	view, ok := b.sessions.Get(chatID, messageID).(*bookdropView)
+	if !ok {
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "This list has expired, please run the command again"))
+		return
+	}
+
+	// The browser holds its owner's selection and pending discard, so in groups
+	// nobody else may press its buttons except an admin
+	if callback.From.ID != view.userID && !b.auth.IsAdmin(callback.From.ID) {
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "This list belongs to someone else, run the command yourself"))
+		return
+	}
+
+	// Any other button abandons a pending discard confirmation
+	if data != "bookdrop_discard_confirm" {
+		view.discard = nil
+	}
+
+	switch {
+	case strings.HasPrefix(data, "bookdrop_discard_"):
+		if !b.canManageBookdrop(callback.From.ID) {
+			b.api.Request(tgbotapi.NewCallback(callback.ID, "Only admins can discard bookdrop files"))
+			return
+		}
+		b.api.Request(tgbotapi.NewCallback(callback.ID, ""))
+		b.handleBookdropDiscard(chatID, messageID, callback.From.ID, view, data)
+		return
+	case strings.HasPrefix(data, "bookdrop_page_"):
+		var page int
+		if _, err := fmt.Sscanf(data, "bookdrop_page_%d", &page); err != nil || page < 0 {
+			b.api.Request(tgbotapi.NewCallback(callback.ID, "Invalid page"))
+			return
+		}
+		view.page = page
+	case strings.HasPrefix(data, "bookdrop_status_"):
+		view.status = strings.TrimPrefix(data, "bookdrop_status_")
+		view.page = 0
+	case strings.HasPrefix(data, "bookdrop_sort_"):
+		view.sort = strings.TrimPrefix(data, "bookdrop_sort_")
+		view.page = 0
+	case data == "bookdrop_clear_filter":
+		view.filter = ""
+		view.page = 0
+	case strings.HasPrefix(data, "bookdrop_toggle_"):
+		var fileID int64
+		if _, err := fmt.Sscanf(data, "bookdrop_toggle_%d", &fileID); err != nil {
+			b.api.Request(tgbotapi.NewCallback(callback.ID, "Invalid file ID"))
+			return
+		}
+		name, ok := view.names[fileID]
+		if !ok {
+			name = fmt.Sprintf("File #%d", fileID)
+		}
+		view.toggle(fileID, name)
+	case data == "bookdrop_clear_selection":
+		view.selected = nil
+	case data == "bookdrop_import_selected":
+		if len(view.selected) == 0 {
+			b.api.Request(tgbotapi.NewCallback(callback.ID, "No files selected"))
+			return
+		}
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "Importing selected files..."))
+		b.importBookdropFiles(chatID, messageID, callback.From.ID, view, view.selected)
+		return
+	case data == "bookdrop_import_filtered":
+		matching, err := b.matchingBookdropFiles(view)
+		if err != nil {
+			b.api.Request(tgbotapi.NewCallback(callback.ID, "Failed to get files"))
+			b.api.Send(tgbotapi.NewEditMessageText(chatID, messageID, fmt.Sprintf("❌ Failed to retrieve bookdrop files: %s", err.Error())))
+			return
+		}
+		if len(matching) == 0 {
+			// The files are gone already, show what is left
+			break
+		}
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "Importing matching files..."))
+		b.importBookdropFiles(chatID, messageID, callback.From.ID, view, matching)
+		return
+	case data == "bookdrop_refresh":
+	default:
+		b.api.Request(tgbotapi.NewCallback(callback.ID, ""))
+		return
+	}
+
+	b.api.Request(tgbotapi.NewCallback(callback.ID, ""))
+	b.editBookdropPage(chatID, messageID, view)
+}
+
+// editBookdropPage re-renders a bookdrop browser in place
+func (b *Bot) editBookdropPage(chatID int64, messageID int, view *bookdropView) {
+	text, markup, err := b.buildBookdropPage(view)
+	if err != nil {
+		b.config.Logger.Error("Failed to get bookdrop files",
+			zap.Error(err))
+		text = fmt.Sprintf("❌ Failed to retrieve bookdrop files: %s", err.Error())
+		markup = nil
+	}
+
+	editMsg := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	if markup != nil {
+		editMsg.ReplyMarkup = markup
+	}
+	b.api.Send(editMsg)
+}
+
+// buildBookdropPage renders the current page of a bookdrop browser
+func (b *Bot) buildBookdropPage(view *bookdropView) (string, *tgbotapi.InlineKeyboardMarkup, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Booklore's paging can't filter by name or sort, so page through the full list locally
+	files, err := b.booklore.GetBookdropFilesNoStatus(ctx, 0, bookdropFetchSize)
+	if err != nil {
+		return "", nil, err
+	}
+
+	pageSize := bookdropPageSize
+	if view.importMode {
+		pageSize = importPageSize
+	}
+
+	result := booklore.FilterBookdropFiles(files.Content, booklore.BookdropQuery{
+		Status: view.status,
+		Filter: view.filter,
+		Sort:   view.sort,
+		Page:   view.page,
+		Size:   pageSize,
+	})
+
+	// Step back if the current page disappeared, e.g. after an import
+	if len(result.Content) == 0 && view.page > 0 && result.TotalPages > 0 {
+		view.page = result.TotalPages - 1
+		result = booklore.FilterBookdropFiles(files.Content, booklore.BookdropQuery{
+			Status: view.status,
+			Filter: view.filter,
+			Sort:   view.sort,
+			Page:   view.page,
+			Size:   pageSize,
+		})
+	}
+
+	b.config.Logger.Debug("Rendering bookdrop page",
+		zap.Bool("import_mode", view.importMode),
+		zap.String("status", view.status),
+		zap.String("sort", view.sort),
+		zap.String("filter", view.filter),
+		zap.Int("page", view.page),
+		zap.Int("matches", result.TotalElements),
+		zap.Int("total_files", len(files.Content)))
+
+	var sb strings.Builder
+	if view.importMode {
+		sb.WriteString("📥 Select files to import\n")
+	} else {
+		sb.WriteString("📂 Bookdrop Contents\n")
+	}
+	sb.WriteString(bookdropViewSummary(view))
+	sb.WriteString("\n")
+
+	if view.importMode {
+		sb.WriteString("💡 Tap files to select them, then import them together.\n")
+	}
+
+	if result.TotalElements == 0 {
+		if len(files.Content) == 0 {
+			sb.WriteString("\nBookdrop is empty. No files found.\n\n💡 Use /rescan to check for new files.")
+		} else {
+			sb.WriteString("\nNo files match the current filter.")
+		}
+	} else {
+		sb.WriteString(fmt.Sprintf("Page %d/%d • %d files\n\n", result.Number+1, result.TotalPages, result.TotalElements))
+	}
+
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+
+	view.names = make(map[int64]string, len(result.Content))
+	for i, file := range result.Content {
+		view.names[file.ID] = file.FileName
+
+		if view.importMode {
+			marker := bookdropStatusEmoji(file.Status)
+			if view.isSelected(file.ID) {
+				marker = "☑️"
+			}
+			buttonText := fmt.Sprintf("%s %s (%.1f MB)",
+				marker,
+				truncateString(file.FileName, 40),
+				float64(file.FileSize)/1024/1024)
+			keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(buttonText, fmt.Sprintf("bookdrop_toggle_%d", file.ID)),
+			))
+			continue
+		}
+
+		sb.WriteString(fmt.Sprintf("%d. %s %s\n   📄 %s\n   📏 %d KB • 📅 %s\n\n",
+			view.page*bookdropPageSize+i+1, bookdropStatusEmoji(file.Status), file.Status,
+			file.FileName, file.FileSize/1024, file.DateAdded))
+	}
+
+	// One discard button per listed file, plus one for everything matching the filter
+	if !view.importMode && len(result.Content) > 0 && b.canManageBookdrop(view.userID) {
+		var discardRows [][]tgbotapi.InlineKeyboardButton
+		var discardRow []tgbotapi.InlineKeyboardButton
+		for i, file := range result.Content {
+			discardRow = append(discardRow, tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("🗑️ %d", view.page*bookdropPageSize+i+1),
+				fmt.Sprintf("bookdrop_discard_%d", file.ID)))
+			if len(discardRow) == 5 {
+				discardRows = append(discardRows, discardRow)
+				discardRow = nil
+			}
+		}
+		if len(discardRow) > 0 {
+			discardRows = append(discardRows, discardRow)
+		}
+		keyboard = append(keyboard, discardRows...)
+		keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🗑️ Discard all %d matching", result.TotalElements), "bookdrop_discard_matching"),
+		))
+	}
+
+	var navRow []tgbotapi.InlineKeyboardButton
+	if !result.First {
+		navRow = append(navRow, tgbotapi.NewInlineKeyboardButtonData("⬅️ Prev", fmt.Sprintf("bookdrop_page_%d", view.page-1)))
+	}
+	navRow = append(navRow, tgbotapi.NewInlineKeyboardButtonData("🔄", "bookdrop_refresh"))
+	if !result.Last {
+		navRow = append(navRow, tgbotapi.NewInlineKeyboardButtonData("Next ➡️", fmt.Sprintf("bookdrop_page_%d", view.page+1)))
+	}
+	keyboard = append(keyboard, navRow)
+
+	var statusRow []tgbotapi.InlineKeyboardButton
+	for _, status := range bookdropFilterStatuses {
+		label := bookdropStatusLabel(status)
+		if strings.EqualFold(status, view.status) {
+			label = "• " + label
+		}
+		statusRow = append(statusRow, tgbotapi.NewInlineKeyboardButtonData(label, "bookdrop_status_"+status))
+	}
+	keyboard = append(keyboard, statusRow)
+
+	var sortRow []tgbotapi.InlineKeyboardButton
+	for _, order := range bookdropSorts {
+		label := bookdropSortLabel(order)
+		if order == view.sort || (view.sort == "" && order == booklore.BookdropSortDate) {
+			label = "• " + label
+		}
+		sortRow = append(sortRow, tgbotapi.NewInlineKeyboardButtonData(label, "bookdrop_sort_"+order))
+	}
+	keyboard = append(keyboard, sortRow)
+
+	if view.filter != "" {
+		keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("✖️ Clear filter \"%s\"", truncateString(view.filter, 20)), "bookdrop_clear_filter"),
+		))
+	}
+
+	if view.importMode {
+		if len(view.selected) > 0 {
+			keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("📥 Import selected (%d)", len(view.selected)), "bookdrop_import_selected"),
+				tgbotapi.NewInlineKeyboardButtonData("✖️ Clear selection", "bookdrop_clear_selection"),
+			))
+		}
+
+		// Only the files the status and filter show, not everything in the bookdrop
+		var actionRow []tgbotapi.InlineKeyboardButton
+		if result.TotalElements > 0 {
+			actionRow = append(actionRow, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("📥 Import all %d matching", result.TotalElements), "bookdrop_import_filtered"))
+		}
+		actionRow = append(actionRow, tgbotapi.NewInlineKeyboardButtonData("❌ Cancel", "import_cancel"))
+		keyboard = append(keyboard, actionRow)
+	}
+
+	markup := tgbotapi.NewInlineKeyboardMarkup(keyboard...)
+	return sb.String(), &markup, nil
+}
+
+// matchingBookdropFiles returns every file matching a view's status and filter, across all pages
+func (b *Bot) matchingBookdropFiles(view *bookdropView) ([]bookdropSelection, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	files, err := b.booklore.GetBookdropFilesNoStatus(ctx, 0, bookdropFetchSize)
+	if err != nil {
+		return nil, err
+	}
+	matching := booklore.FilterBookdropFiles(files.Content, booklore.BookdropQuery{
+		Status: view.status,
+		Filter: view.filter,
+		Sort:   view.sort,
+		Size:   bookdropFetchSize,
+	})
+
+	selections := make([]bookdropSelection, len(matching.Content))
+	for i, file := range matching.Content {
+		selections[i] = bookdropSelection{id: file.ID, name: file.FileName}
+	}
+	return selections, nil
+}
+
+// importBookdropFiles imports files in one request and reports the outcome per file
+func (b *Bot) importBookdropFiles(chatID int64, messageID int, userID int64, view *bookdropView, files []bookdropSelection) {
+	libraryID, pathID := b.getLibraryIDsForUser(chatID, userID)
+	if libraryID == "" {
+		b.api.Send(tgbotapi.NewEditMessageText(chatID, messageID,
+			"📚 Library configuration required\n\nUse /set_library to choose where books are imported, then run /import again."))
+		b.sessions.Delete(chatID, messageID)
+		return
+	}
+
+	fileIDs := make([]int64, len(files))
+	for i, sel := range files {
+		fileIDs[i] = sel.id
+	}
+
+	b.config.Logger.Info("Importing bookdrop files",
+		zap.Int64("user_id", userID),
+		zap.Int("file_count", len(fileIDs)),
+		zap.Any("file_ids", fileIDs))
+
+	b.api.Send(tgbotapi.NewEditMessageText(chatID, messageID,
+		fmt.Sprintf("📥 Importing %d files... This may take a moment.", len(fileIDs))))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// Imports are made as the user's linked Booklore account
+	var result *booklore.BookdropFinalizeResult
+	ctx, err := b.bookloreContext(ctx, userID)
+	if err == nil {
+		result, err = b.booklore.FinalizeImport(ctx, fileIDs, libraryID, pathID)
+	}
+	b.recordImportAudit(userID, fileIDs, libraryID, pathID, nil, result, err)
+
+	var sb strings.Builder
+	if err != nil {
+		b.config.Logger.Error("Failed to import bookdrop files",
+			zap.Any("file_ids", fileIDs),
+			zap.Error(err))
+		sb.WriteString(fmt.Sprintf("❌ Import failed: %s\n\nGo back to try again.", err.Error()))
+	} else {
+		sb.WriteString(fmt.Sprintf("✅ Import completed!\n\n📊 Results:\n📥 Imported: %d\n❌ Failed: %d\n\n",
+			result.ImportedCount, result.FailedCount))
+		for i, sel := range files {
+			if i == 30 {
+				sb.WriteString(fmt.Sprintf("… and %d more\n", len(files)-i))
+				break
+			}
+			sb.WriteString(fmt.Sprintf("%s %s\n", importOutcomeEmoji(result, sel.id), truncateString(sel.name, 60)))
+		}
+		if len(result.ImportedIDs) == 0 && len(result.FailedIDs) == 0 {
+			sb.WriteString("\nℹ️ Booklore didn't report results per file.")
+		}
+		view.selected = nil
+	}
+
+	editMsg := tgbotapi.NewEditMessageText(chatID, messageID, sb.String())
+	markup := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🔙 Back to files", "bookdrop_refresh"),
+	))
+	editMsg.ReplyMarkup = &markup
+	b.api.Send(editMsg)
+}
+
+// canManageBookdrop reports whether a user may discard files from the shared bookdrop.
+// Without configured admins every allowed user may.
+func (b *Bot) canManageBookdrop(userID int64) bool {
+	return b.auth.IsUserAllowed(userID) && (!b.auth.HasAdmins() || b.auth.IsAdmin(userID))
+}
+
+// handleBookdropDiscard asks to confirm discarding files and discards them once confirmed
+func (b *Bot) handleBookdropDiscard(chatID int64, messageID int, userID int64, view *bookdropView, data string) {
+	switch {
+	case data == "bookdrop_discard_confirm":
+		if len(view.discard) == 0 {
+			b.editBookdropPage(chatID, messageID, view)
+			return
+		}
+		b.discardBookdropFiles(chatID, messageID, userID, view)
+		return
+
+	case data == "bookdrop_discard_matching":
+		matching, err := b.matchingBookdropFiles(view)
+		if err != nil {
+			b.api.Send(tgbotapi.NewEditMessageText(chatID, messageID, fmt.Sprintf("❌ Failed to retrieve bookdrop files: %s", err.Error())))
+			return
+		}
+		view.discard = matching
+
+	default:
+		var fileID int64
+		if _, err := fmt.Sscanf(data, "bookdrop_discard_%d", &fileID); err != nil {
+			return
+		}
+		name, ok := view.names[fileID]
+		if !ok {
+			name = fmt.Sprintf("File #%d", fileID)
+		}
+		view.discard = []bookdropSelection{{id: fileID, name: name}}
+	}
+
+	if len(view.discard) == 0 {
+		b.editBookdropPage(chatID, messageID, view)
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🗑️ Discard %d files from the bookdrop?\n\n", len(view.discard)))
+	for i, sel := range view.discard {
+		if i == 10 {
+			sb.WriteString(fmt.Sprintf("… and %d more\n", len(view.discard)-i))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("• %s\n", truncateString(sel.name, 60)))
+	}
+	sb.WriteString("\n⚠️ The files won't be imported and are deleted. This can't be undone.")
+
+	editMsg := tgbotapi.NewEditMessageText(chatID, messageID, sb.String())
+	markup := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🗑️ Discard", "bookdrop_discard_confirm"),
+		tgbotapi.NewInlineKeyboardButtonData("↩️ Keep", "bookdrop_refresh"),
+	))
+	editMsg.ReplyMarkup = &markup
+	b.api.Send(editMsg)
+}
+
+// discardBookdropFiles discards the confirmed files in Booklore and removes leftover local copies
+func (b *Bot) discardBookdropFiles(chatID int64, messageID int, userID int64, view *bookdropView) {
+	fileIDs := make([]int64, len(view.discard))
+	for i, sel := range view.discard {
+		fileIDs[i] = sel.id
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	ctx, err := b.bookloreContext(ctx, userID)
+	if err == nil {
+		err = b.booklore.DiscardFiles(ctx, fileIDs)
+	}
+	b.recordAudit(userID, audit.ActionDiscard, "bookdrop", map[string]string{"files": auditFileIDs(fileIDs)}, err)
+	if err != nil {
+		b.config.Logger.Error("Failed to discard bookdrop files",
+			zap.Int64("user_id", userID),
+			zap.Any("file_ids", fileIDs),
+			zap.Error(err))
+		editMsg := tgbotapi.NewEditMessageText(chatID, messageID, fmt.Sprintf("❌ Discard failed: %s", err.Error()))
+		markup := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔙 Back to files", "bookdrop_refresh"),
+		))
+		editMsg.ReplyMarkup = &markup
+		b.api.Send(editMsg)
+		return
+	}
+
+	b.config.Logger.Info("Discarded bookdrop files",
+		zap.Int64("user_id", userID),
+		zap.Int("file_count", len(fileIDs)))
+
+	// Booklore deletes the file from its own bookdrop, but a copy may remain in
+	// the download folder if it isn't the folder Booklore watches. Only copies
+	// the upload history links to the file are removed, a file that merely has
+	// the same name may be someone else's.
+	removed := 0
+	for _, sel := range view.discard {
+		rec, ok := b.history.FindByBookloreFileID(sel.id)
+		if !ok {
+			continue
+		}
+		b.setUploadImportResult(rec.ID, history.ImportDeleted, "Discarded from bookdrop")
+
+		deleted, err := b.removeFromDownloadFolder(rec.SavedPath)
+		if err != nil {
+			b.config.Logger.Warn("Failed to remove discarded file from download folder",
+				zap.Int64("file_id", sel.id),
+				zap.String("path", rec.SavedPath),
+				zap.Error(err))
+			continue
+		}
+		if deleted {
+			removed++
+		}
+	}
+
+	text := fmt.Sprintf("🗑️ Discarded %d files from the bookdrop.", len(fileIDs))
+	if removed > 0 {
+		text += fmt.Sprintf("\n🧹 Removed %d leftover files from the download folder.", removed)
+	}
+	view.discard = nil
+	view.selected = nil
+
+	editMsg := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	markup := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🔙 Back to files", "bookdrop_refresh"),
+	))
+	editMsg.ReplyMarkup = &markup
+	b.api.Send(editMsg)
+}
+
+// importOutcomeEmoji tells whether a file was imported according to a finalize result
+func importOutcomeEmoji(result *booklore.BookdropFinalizeResult, fileID int64) string {
+	for _, id := range result.ImportedIDs {
+		if id == fileID {
+			return "✅"
+		}
+	}
+	for _, id := range result.FailedIDs {
+		if id == fileID {
+			return "❌"
+		}
+	}
+	return "❔"
+}
+
+// bookdropViewSummary describes the active status, sort and filter of a view
+func bookdropViewSummary(view *bookdropView) string {
+	parts := []string{"Status: " + bookdropStatusLabel(view.status)}
+	sortOrder := view.sort
+	if sortOrder == "" {
+		sortOrder = booklore.BookdropSortDate
+	}
+	parts = append(parts, "Sort: "+bookdropSortLabel(sortOrder))
+	if view.filter != "" {
+		parts = append(parts, fmt.Sprintf("Filter: \"%s\"", view.filter))
+	}
+	return "🔍 " + strings.Join(parts, " • ") + "\n"
+}
+
+func bookdropStatusEmoji(status string) string {
+	switch status {
+	case "NEW":
+		return "🆕"
+	case "PENDING_REVIEW":
+		return "⏳"
+	case "PROCESSED":
+		return "🔍"
+	case "IMPORTED":
+		return "✅"
+	case "FAILED":
+		return "❌"
+	default:
+		return "📄"
+	}
+}
+
+func bookdropStatusLabel(status string) string {
+	switch status {
+	case "":
+		return "All"
+	case "NEW":
+		return "🆕 New"
+	case "FAILED":
+		return "❌ Failed"
+	case "PENDING_REVIEW":
+		return "⏳ Review"
+	default:
+		return status
+	}
+}
+
+func bookdropSortLabel(order string) string {
+	switch order {
+	case booklore.BookdropSortSize:
+		return "📏 Size"
+	case booklore.BookdropSortName:
+		return "🔤 Name"
+	default:
+		return "📅 Date"
+	}
+}
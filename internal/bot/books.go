@@ -0,0 +1,200 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brauni/booklore-tg-bot/internal/booklore"
+	"github.com/brauni/booklore-tg-bot/internal/filecache"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// errUploadTooLarge is returned when a book exceeds the Telegram upload limit mid-stream
+var errUploadTooLarge = errors.New("file exceeds the Telegram upload limit")
+
+func (b *Bot) handleBookCommand(chatID int64, userID int64, args string) {
+	if !b.booklore.IsEnabled() {
+		msg := tgbotapi.NewMessage(chatID, "❌ Booklore integration is not enabled.")
+		b.api.Send(msg)
+		return
+	}
+
+	bookID, err := strconv.ParseInt(strings.TrimSpace(args), 10, 64)
+	if err != nil || bookID <= 0 {
+		msg := tgbotapi.NewMessage(chatID, "📖 Usage: /book <id>\n\n💡 Use /search to find book IDs.")
+		b.api.Send(msg)
+		return
+	}
+
+	b.sendLibraryBook(chatID, userID, bookID)
+}
+
+// handleBookCallback handles download buttons on search results
+func (b *Bot) handleBookCallback(callback *tgbotapi.CallbackQuery) {
+	var bookID int64
+	if _, err := fmt.Sscanf(callback.Data, "book_get_%d", &bookID); err != nil {
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "Invalid book ID"))
+		return
+	}
+
+	b.api.Request(tgbotapi.NewCallback(callback.ID, "Sending book..."))
+	b.sendLibraryBook(callback.Message.Chat.ID, callback.From.ID, bookID)
+}
+
+// sendLibraryBook sends a book from the Booklore library as a Telegram document
+func (b *Bot) sendLibraryBook(chatID int64, userID int64, bookID int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	ctx, err := b.bookloreContext(ctx, userID)
+	if err != nil {
+		b.sendErrorMessage(chatID, err.Error())
+		return
+	}
+
+	book, err := b.booklore.GetBook(ctx, bookID)
+	if err != nil {
+		b.config.Logger.Error("Failed to get book",
+			zap.Int64("book_id", bookID),
+			zap.Error(err))
+		var apiErr *booklore.BookloreAPIError
+		if errors.As(err, &apiErr) && apiErr.Type == booklore.ErrNotFound {
+			b.sendErrorMessage(chatID, fmt.Sprintf("Book %d not found", bookID))
+			return
+		}
+		b.sendErrorMessage(chatID, fmt.Sprintf("Failed to get book: %s", err.Error()))
+		return
+	}
+
+	if !b.auth.CanAccessLibrary(userID, book.LibraryID) {
+		msg := tgbotapi.NewMessage(chatID, "🚫 You don't have access to the library this book belongs to.")
+		b.api.Send(msg)
+		return
+	}
+
+	caption := bookCaption(book)
+
+	// Reuse a previous upload of this book if Telegram still knows it
+	if entry, ok := b.fileCache.Get(bookID); ok {
+		doc := tgbotapi.NewDocument(chatID, tgbotapi.FileID(entry.FileID))
+		doc.Caption = caption
+		_, err := b.api.Send(doc)
+		if err == nil {
+			b.config.Logger.Info("Sent book from Telegram file cache",
+				zap.Int64("book_id", bookID),
+				zap.Int64("user_id", userID))
+			return
+		}
+
+		b.config.Logger.Warn("Cached Telegram file ID rejected, uploading again",
+			zap.Int64("book_id", bookID),
+			zap.Error(err))
+		b.fileCache.Delete(bookID)
+	}
+
+	maxBytes := b.settings().MaxUploadSizeMB * 1024 * 1024
+	if book.SizeBytes() > maxBytes {
+		msg := tgbotapi.NewMessage(chatID,
+			fmt.Sprintf("❌ '%s' is %.1f MB, which exceeds the Telegram upload limit of %d MB.",
+				book.Title(), float64(book.SizeBytes())/1024/1024, b.settings().MaxUploadSizeMB))
+		b.api.Send(msg)
+		return
+	}
+
+	action := tgbotapi.NewChatAction(chatID, "upload_document")
+	b.api.Send(action)
+
+	download, err := b.booklore.DownloadBook(ctx, bookID)
+	if err != nil {
+		b.config.Logger.Error("Failed to download book from Booklore",
+			zap.Int64("book_id", bookID),
+			zap.Error(err))
+		b.sendErrorMessage(chatID, fmt.Sprintf("Failed to download book: %s", err.Error()))
+		return
+	}
+	defer download.Body.Close()
+
+	if download.Size > maxBytes {
+		msg := tgbotapi.NewMessage(chatID,
+			fmt.Sprintf("❌ '%s' exceeds the Telegram upload limit of %d MB.", book.Title(), b.settings().MaxUploadSizeMB))
+		b.api.Send(msg)
+		return
+	}
+
+	fileName := download.FileName
+	if fileName == "" {
+		fileName = book.FileName
+	}
+
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileReader{
+		Name:   fileName,
+		Reader: &maxBytesReader{reader: download.Body, remaining: maxBytes},
+	})
+	doc.Caption = caption
+
+	sent, err := b.api.Send(doc)
+	if err != nil {
+		b.config.Logger.Error("Failed to send book to Telegram",
+			zap.Int64("book_id", bookID),
+			zap.String("file_name", fileName),
+			zap.Error(err))
+		if errors.Is(err, errUploadTooLarge) {
+			b.sendErrorMessage(chatID, fmt.Sprintf("Book exceeds the Telegram upload limit of %d MB", b.settings().MaxUploadSizeMB))
+			return
+		}
+		b.sendErrorMessage(chatID, fmt.Sprintf("Failed to send book: %s", err.Error()))
+		return
+	}
+
+	b.config.Logger.Info("Sent book from Booklore library",
+		zap.Int64("book_id", bookID),
+		zap.Int64("user_id", userID),
+		zap.String("file_name", fileName))
+
+	if sent.Document != nil {
+		b.fileCache.Put(bookID, filecache.Entry{
+			FileID:       sent.Document.FileID,
+			FileUniqueID: sent.Document.FileUniqueID,
+			FileName:     fileName,
+			Size:         int64(sent.Document.FileSize),
+		})
+	}
+}
+
+// bookCaption formats the document caption for a library book
+func bookCaption(book *booklore.Book) string {
+	caption := "📖 " + book.Title()
+	if authors := book.Authors(); authors != "" {
+		caption += "\n✍️ " + authors
+	}
+	return caption
+}
+
+// maxBytesReader fails once more than the allowed number of bytes was read,
+// so uploads of unknown size can't exceed the Telegram limit
+type maxBytesReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+func (r *maxBytesReader) Read(p []byte) (int, error) {
+	if r.remaining < 0 {
+		return 0, errUploadTooLarge
+	}
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+
+	n, err := r.reader.Read(p)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		return n, errUploadTooLarge
+	}
+	return n, err
+}
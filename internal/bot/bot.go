@@ -1,24 +1,61 @@
 package bot
 
 import (
+	"context"
 	"fmt"
+	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/brauni/booklore-tg-bot/internal/accounts"
+	"github.com/brauni/booklore-tg-bot/internal/admin"
+	"github.com/brauni/booklore-tg-bot/internal/audit"
 	"github.com/brauni/booklore-tg-bot/internal/auth"
 	"github.com/brauni/booklore-tg-bot/internal/booklore"
 	"github.com/brauni/booklore-tg-bot/internal/config"
+	"github.com/brauni/booklore-tg-bot/internal/delivery"
 	"github.com/brauni/booklore-tg-bot/internal/downloader"
+	"github.com/brauni/booklore-tg-bot/internal/filecache"
+	"github.com/brauni/booklore-tg-bot/internal/history"
+	"github.com/brauni/booklore-tg-bot/internal/routing"
+	"github.com/brauni/booklore-tg-bot/internal/storage"
+	"github.com/brauni/booklore-tg-bot/internal/subscriptions"
+	"github.com/brauni/booklore-tg-bot/internal/tracing"
+	"github.com/brauni/booklore-tg-bot/internal/watcher"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 )
 
 type Bot struct {
 	api          *tgbotapi.BotAPI
 	config       *config.Config
+	live         atomic.Pointer[config.Config]
+	reloadMutex  sync.Mutex
 	auth         *auth.Authenticator
 	downloader   *downloader.Downloader
 	booklore     *booklore.Client
+	catalog      *booklore.LibraryCatalog
+	events       *booklore.EventStream
 	preferences  *booklore.PreferenceManager
+	history      *history.Store
+	sessions     *sessionStore
+	prompts      *promptStore
+	fileCache    *filecache.Cache
+	devices      *delivery.DeviceStore
+	outbox       *delivery.Outbox
+	router       *routing.Router
+	staleNotices *staleNotices
+	watcher      *watcher.BookdropWatcher
+	subscribers  *subscriptions.Store
+	heldNotices  *heldNotices
+	admin        *admin.Server
+	audit        *audit.Log
+	accounts     *accounts.Store
+	ctx          context.Context
+	cancel       context.CancelFunc
 }
 
 func NewBot(cfg *config.Config) (*Bot, error) {
@@ -31,26 +68,144 @@ func NewBot(cfg *config.Config) (*Bot, error) {
 	}
 
 	// Initialize authenticator
-	authenticator := auth.NewAuthenticator(cfg.AllowedUserIDs, cfg.Logger)
+	authenticator := auth.NewAuthenticator(cfg.AllowedUserIDs, cfg.AdminUserIDs, cfg.LibraryAccess, cfg.Logger)
 
 	// Initialize downloader
 	dl := downloader.NewDownloader(cfg.DownloadFolder, cfg.AllowedFileTypes, cfg.MaxFileSizeMB, cfg.Logger)
 
-	// Initialize Booklore client
-	bookloreClient := booklore.NewClient(cfg.BookloreAPI.APIURL, cfg.BookloreAPI.APIToken, cfg.Logger)
+	// Initialize Booklore client, logging in with a username and password if configured
+	var bookloreAuth booklore.AuthProvider
+	if cfg.BookloreAPI.Username != "" {
+		bookloreAuth = booklore.NewLoginAuth(cfg.BookloreAPI.APIURL, cfg.BookloreAPI.Username, cfg.BookloreAPI.Password, cfg.Logger)
+	} else if cfg.BookloreAPI.APIToken != "" {
+		bookloreAuth = booklore.NewStaticToken(cfg.BookloreAPI.APIToken)
+	}
+	bookloreClient := booklore.NewClient(cfg.BookloreAPI.APIURL, bookloreAuth, cfg.Logger)
+
+	// Encrypt the state in the data folder if a key is configured. Stores start
+	// empty when they can't read their file, so refuse to start without the right key.
+	cipher, err := cfg.Cipher()
+	if err != nil {
+		return nil, err
+	}
+	storage.UseCipher(cipher)
+	if err := storage.VerifyDir(cfg.DataFolder); err != nil {
+		return nil, fmt.Errorf("failed to read the state in %s, check ENCRYPTION_KEY and ENCRYPTION_PREVIOUS_KEYS: %w", cfg.DataFolder, err)
+	}
 
 	// Initialize preference manager with persistent storage
-	preferencesPath := "/app/data/user_preferences.json"
+	preferencesPath := filepath.Join(cfg.DataFolder, "user_preferences.json")
 	preferenceManager := booklore.NewPreferenceManager(cfg.Logger, preferencesPath)
 
-	return &Bot{
+	// Initialize upload history with persistent storage
+	historyPath := filepath.Join(cfg.DataFolder, "upload_history.json")
+	historyStore := history.NewStore(cfg.Logger, historyPath)
+
+	// Initialize cache of Telegram file IDs for books sent from the library
+	fileCachePath := filepath.Join(cfg.DataFolder, "telegram_file_cache.json")
+	fileCache := filecache.NewCache(cfg.Logger, fileCachePath)
+
+	// Initialize device addresses for e-mail delivery
+	devicesPath := filepath.Join(cfg.DataFolder, "devices.json")
+	deviceStore := delivery.NewDeviceStore(cfg.Logger, devicesPath)
+
+	// Initialize the audit log of privileged actions
+	auditPath := filepath.Join(cfg.DataFolder, "audit.jsonl")
+	auditLog := audit.NewLog(cfg.Logger, auditPath, time.Duration(cfg.AuditRetentionDays)*24*time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	b := &Bot{
 		api:         api,
 		config:      cfg,
 		auth:        authenticator,
 		downloader:  dl,
 		booklore:    bookloreClient,
+		catalog:     booklore.NewLibraryCatalog(bookloreClient, cfg.Logger, time.Duration(cfg.BookloreAPI.LibraryCacheTTL)*time.Second),
 		preferences: preferenceManager,
-	}, nil
+		history:     historyStore,
+		sessions:    newSessionStore(),
+		prompts:     newPromptStore(),
+		fileCache:   fileCache,
+		devices:     deviceStore,
+		audit:       auditLog,
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+
+	b.live.Store(cfg)
+
+	// Load routing rules; an invalid rules file stops the bot from starting
+	if cfg.BookloreAPI.RoutingRulesFile != "" {
+		b.router, err = routing.NewRouter(cfg.Logger, cfg.BookloreAPI.RoutingRulesFile)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to load routing rules: %w", err)
+		}
+	}
+
+	// Subscribe to Booklore events so imports react as soon as the bookdrop changes
+	if cfg.BookloreAPI.Events {
+		eventsURL := cfg.BookloreAPI.EventsURL
+		if eventsURL == "" {
+			eventsURL = booklore.EventStreamURL(cfg.BookloreAPI.APIURL)
+		}
+		b.events = booklore.NewEventStream(eventsURL, bookloreClient.Auth(), cfg.Logger)
+	}
+
+	// Notify subscribers about files reaching the bookdrop outside the bot
+	if cfg.BookloreAPI.WatchBookdrop {
+		b.subscribers = subscriptions.NewStore(cfg.Logger, filepath.Join(cfg.DataFolder, "subscriptions.json"))
+		b.heldNotices = newHeldNotices()
+		b.watcher = watcher.NewBookdropWatcher(bookloreClient, b.events, cfg.Logger, watcher.Config{
+			PollInterval: time.Duration(cfg.BookloreAPI.WatchInterval) * time.Second,
+			Folder:       cfg.DownloadFolder,
+		})
+		b.watcher.SetNotifier(b.notifyBookdropChanges)
+	}
+
+	// Tell users when their preferred library disappears from Booklore
+	b.staleNotices = newStaleNotices()
+	b.catalog.SetChangeHandler(b.checkStalePreferences)
+
+	// Make sure the configured default libraries exist before accepting uploads
+	if err := b.validateDefaultTargets(); err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid default library: %w", err)
+	}
+
+	// Let users link their own Booklore account, with the credentials encrypted at rest
+	if cfg.BookloreAPI.UserAccounts {
+		b.accounts = accounts.NewStore(cfg.Logger, filepath.Join(cfg.DataFolder, accounts.FileName), cipher, cfg.BookloreAPI.APIURL)
+	}
+
+	// Initialize e-mail delivery outbox if SMTP is configured
+	if cfg.Delivery.Enabled {
+		sender := delivery.NewSMTPSender(delivery.SMTPConfig{
+			Host:     cfg.Delivery.SMTPHost,
+			Port:     cfg.Delivery.SMTPPort,
+			Username: cfg.Delivery.SMTPUsername,
+			Password: cfg.Delivery.SMTPPassword,
+			From:     cfg.Delivery.From,
+			TLSMode:  cfg.Delivery.SMTPTLSMode,
+		})
+		outboxPath := filepath.Join(cfg.DataFolder, "outbox.json")
+		b.outbox = delivery.NewOutbox(cfg.Logger, outboxPath, sender, b.openDeliveryAttachment, delivery.OutboxConfig{
+			From:               cfg.Delivery.From,
+			MaxAttempts:        cfg.Delivery.MaxAttempts,
+			RetryDelay:         time.Duration(cfg.Delivery.RetryDelay) * time.Second,
+			MaxAttachmentBytes: cfg.Delivery.MaxAttachmentMB * 1024 * 1024,
+			AllowedFormats:     cfg.Delivery.AllowedFormats,
+		})
+		b.outbox.SetNotifier(b.notifyDeliveryStatus)
+	}
+
+	// Serve health, readiness and metrics endpoints for monitoring
+	if cfg.AdminListenAddr != "" {
+		b.admin = b.newAdminServer()
+	}
+
+	return b, nil
 }
 
 func (b *Bot) Start() error {
@@ -67,6 +222,73 @@ func (b *Bot) Start() error {
 		b.config.Logger.Info("Booklore API integration disabled")
 	}
 
+	// Serve health, readiness and metrics endpoints
+	if b.admin != nil {
+		go func() {
+			if err := b.admin.Run(b.ctx); err != nil {
+				b.config.Logger.Error("Admin server stopped",
+					zap.Error(err))
+			}
+		}()
+	}
+
+	// Remove audit log entries past their retention
+	go b.audit.Run(b.ctx)
+
+	// Start e-mail delivery worker
+	if b.outbox != nil {
+		b.config.Logger.Info("E-mail delivery enabled",
+			zap.String("smtp_host", b.config.Delivery.SMTPHost),
+			zap.Int("smtp_port", b.config.Delivery.SMTPPort))
+		go b.outbox.Run(b.ctx)
+	}
+
+	// Keep the library catalog up to date
+	if b.booklore.IsEnabled() {
+		go b.catalog.Run(b.ctx)
+	}
+
+	// Connect to the Booklore event stream
+	if b.events != nil {
+		go b.events.Run(b.ctx)
+	}
+
+	// Watch the bookdrop for files added outside the bot
+	if b.watcher != nil {
+		go b.watcher.Run(b.ctx)
+		go b.runHeldNotices(b.ctx)
+	}
+
+	// Reload routing rules when the file changes
+	if b.router != nil {
+		go b.router.Run(b.ctx)
+	}
+
+	// Apply config file changes to the running bot, and pick up a rotated Booklore token
+	for _, path := range []string{b.config.File, b.config.BookloreAPI.APITokenFile} {
+		if path == "" {
+			continue
+		}
+		go func() {
+			if err := config.Watch(b.ctx, path, b.config.Logger, b.ReloadConfig); err != nil {
+				b.config.Logger.Warn("Failed to watch file, reload with SIGHUP instead",
+					zap.String("path", path),
+					zap.Error(err))
+			}
+		}()
+	}
+
+	// Receive updates through the webhook if configured
+	if b.config.Webhook.Enabled {
+		return b.runWebhook()
+	}
+
+	// getUpdates is refused while a webhook is set, e.g. after switching back from webhook mode
+	if _, err := b.api.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		b.config.Logger.Warn("Failed to delete webhook before polling",
+			zap.Error(err))
+	}
+
 	// Set up update configuration
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
@@ -76,30 +298,78 @@ func (b *Bot) Start() error {
 
 	// Process updates
 	for update := range updates {
-		if update.Message != nil {
-			b.handleMessage(update.Message)
-		}
-		if update.CallbackQuery != nil {
-			callbackData := update.CallbackQuery.Data
-
-			// Handle different callback types
-			if strings.HasPrefix(callbackData, "import_") {
-				b.handleImportCallback(update.CallbackQuery)
-			} else if strings.HasPrefix(callbackData, "select_library_") {
-				b.handleLibrarySelectCallback(update.CallbackQuery)
-			} else if strings.HasPrefix(callbackData, "select_path_") {
-				b.handlePathSelectCallback(update.CallbackQuery)
-			} else if callbackData == "prompt_set_library" || callbackData == "import_cancel_prompt" {
-				b.handleLibraryPromptCallback(update.CallbackQuery)
-			}
-		}
+		b.handleUpdate(update)
 	}
 
 	return nil
 }
 
+// handleUpdate dispatches a single update, however it was received. Each update
+// starts a trace that follows its download and import.
+func (b *Bot) handleUpdate(update tgbotapi.Update) {
+	ctx, span := tracing.Start(context.Background(), "telegram.update",
+		attribute.Int("telegram.update_id", update.UpdateID))
+	defer span.End()
+
+	if update.Message != nil {
+		span.SetAttributes(attribute.String("telegram.update_type", "message"))
+		b.handleMessage(ctx, update.Message)
+	}
+	if update.InlineQuery != nil {
+		span.SetAttributes(attribute.String("telegram.update_type", "inline_query"))
+		b.handleInlineQuery(update.InlineQuery)
+	}
+	if update.CallbackQuery != nil {
+		callbackData := update.CallbackQuery.Data
+		span.SetAttributes(
+			attribute.String("telegram.update_type", "callback_query"),
+			attribute.String("telegram.callback_data", callbackData))
+
+		// Buttons stay pressable by everyone in a group, not just allowed users
+		if !b.auth.IsUserAllowed(update.CallbackQuery.From.ID) {
+			b.api.Request(tgbotapi.NewCallback(update.CallbackQuery.ID, "🚫 You are not authorized to use this bot."))
+			return
+		}
+
+		// Handle different callback types
+		if strings.HasPrefix(callbackData, "import_") {
+			b.handleImportCallback(ctx, update.CallbackQuery)
+		} else if strings.HasPrefix(callbackData, "select_library_") {
+			b.handleLibrarySelectCallback(update.CallbackQuery)
+		} else if strings.HasPrefix(callbackData, "select_path_") {
+			b.handlePathSelectCallback(update.CallbackQuery)
+		} else if strings.HasPrefix(callbackData, "book_get_") {
+			b.handleBookCallback(update.CallbackQuery)
+		} else if strings.HasPrefix(callbackData, "send_") {
+			b.handleSendCallback(update.CallbackQuery)
+		} else if strings.HasPrefix(callbackData, "search_page_") {
+			b.handleSearchCallback(update.CallbackQuery)
+		} else if strings.HasPrefix(callbackData, "history_") {
+			b.handleHistoryCallback(ctx, update.CallbackQuery)
+		} else if strings.HasPrefix(callbackData, "upload_") {
+			b.handleUploadTargetCallback(ctx, update.CallbackQuery)
+		} else if strings.HasPrefix(callbackData, "bookdrop_") {
+			b.handleBookdropCallback(update.CallbackQuery)
+		} else if strings.HasPrefix(callbackData, "review_") {
+			b.handleReviewCallback(update.CallbackQuery)
+		} else if callbackData == "prompt_set_library" || callbackData == "import_cancel_prompt" {
+			b.handleLibraryPromptCallback(update.CallbackQuery)
+		}
+	}
+}
+
 func (b *Bot) Stop() {
 	b.config.Logger.Info("Stopping Telegram bot")
+
+	// Stop Telegram from posting to a server that is about to go away
+	if b.config.Webhook.Enabled {
+		if _, err := b.api.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+			b.config.Logger.Warn("Failed to delete webhook",
+				zap.Error(err))
+		}
+	}
+
+	b.cancel()
 }
 
 func (b *Bot) GetBotInfo() string {
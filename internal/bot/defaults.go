@@ -0,0 +1,157 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/brauni/booklore-tg-bot/internal/booklore"
+	"go.uber.org/zap"
+)
+
+// defaultImportTarget finds where files go without a per-upload target or matching
+// routing rule: the user's library, then the chat default, then the global default.
+// It returns nil if none of them is configured and usable.
+func (b *Bot) defaultImportTarget(chatID int64, userID int64) *booklore.ImportTarget {
+	pref := b.preferences.GetUserPreference(userID)
+
+	// Skip a preference the cached catalog shows was deleted; the user is told to pick a new one
+	if libraries := b.catalog.Cached(); pref.HasLibrary() && libraries != nil {
+		if b.checkPreference(userID, libraries) {
+			pref = b.preferences.GetUserPreference(userID)
+		} else {
+			pref = &booklore.UserPreferences{}
+		}
+	}
+
+	if pref.HasLibrary() {
+		return &booklore.ImportTarget{
+			LibraryID:   pref.GetLibraryID(),
+			PathID:      pref.GetPathID(),
+			LibraryName: pref.GetLibraryName(),
+			PathName:    pref.GetPathName(),
+		}
+	}
+
+	for _, fallback := range b.fallbackTargetSpecs(chatID) {
+		target, err := b.resolveImportTarget(userID, fallback.spec)
+		if err != nil {
+			b.config.Logger.Warn("Default import target can't be used",
+				zap.String("source", fallback.source),
+				zap.String("target", fallback.spec.String()),
+				zap.Int64("chat_id", chatID),
+				zap.Int64("user_id", userID),
+				zap.Error(err))
+			continue
+		}
+
+		b.config.Logger.Info("Using default import target",
+			zap.String("source", fallback.source),
+			zap.Int64("user_id", userID),
+			zap.Int64("library_id", target.LibraryID),
+			zap.Int64("path_id", target.PathID))
+		return target
+	}
+
+	return nil
+}
+
+// fallbackTarget is a configured default target and where it was configured
+type fallbackTarget struct {
+	source string
+	spec   booklore.TargetSpec
+}
+
+// fallbackTargetSpecs lists the configured defaults for a chat, most specific first
+func (b *Bot) fallbackTargetSpecs(chatID int64) []fallbackTarget {
+	var fallbacks []fallbackTarget
+
+	if target, ok := b.settings().BookloreAPI.ChatDefaults[chatID]; ok {
+		// Entries were checked when the configuration was loaded
+		if spec, err := booklore.ParseTargetSpec(target); err == nil {
+			fallbacks = append(fallbacks, fallbackTarget{source: "chat default", spec: spec})
+		}
+	}
+
+	if spec, ok := b.globalDefaultSpec(); ok {
+		fallbacks = append(fallbacks, fallbackTarget{source: "global default", spec: spec})
+	}
+
+	return fallbacks
+}
+
+// globalDefaultSpec returns BOOKLORE_DEFAULT_LIBRARY_ID and BOOKLORE_DEFAULT_PATH_ID as a target spec
+func (b *Bot) globalDefaultSpec() (booklore.TargetSpec, bool) {
+	if b.config.BookloreAPI.DefaultLibraryID == "" {
+		return booklore.TargetSpec{}, false
+	}
+	return booklore.TargetSpec{
+		Library: b.config.BookloreAPI.DefaultLibraryID,
+		Path:    b.config.BookloreAPI.DefaultPathID,
+	}, true
+}
+
+// hasImportTarget reports whether files can be imported without asking for a library
+func (b *Bot) hasImportTarget(chatID int64, userID int64) bool {
+	return b.preferences.GetUserPreference(userID).HasLibrary() || len(b.fallbackTargetSpecs(chatID)) > 0
+}
+
+// getLibraryIDsForUser gets the library and path IDs files are imported into,
+// or empty strings if neither the user nor the configuration names a library
+func (b *Bot) getLibraryIDsForUser(chatID int64, userID int64) (string, string) {
+	target := b.defaultImportTarget(chatID, userID)
+	if target == nil {
+		b.config.Logger.Info("User has no library configured",
+			zap.Int64("user_id", userID),
+			zap.Int64("chat_id", chatID))
+		return "", ""
+	}
+
+	return strconv.FormatInt(target.LibraryID, 10), strconv.FormatInt(target.PathID, 10)
+}
+
+// validateDefaultTargets checks the configured default targets against Booklore.
+// Targets that don't exist are an error; an unreachable Booklore is only logged.
+func (b *Bot) validateDefaultTargets() error {
+	if !b.booklore.IsEnabled() {
+		return nil
+	}
+
+	specs := make(map[string]booklore.TargetSpec)
+	if spec, ok := b.globalDefaultSpec(); ok {
+		specs["BOOKLORE_DEFAULT_LIBRARY_ID"] = spec
+	}
+	for chatID, target := range b.settings().BookloreAPI.ChatDefaults {
+		spec, err := booklore.ParseTargetSpec(target)
+		if err != nil {
+			return fmt.Errorf("BOOKLORE_CHAT_DEFAULTS entry for chat %d: %w", chatID, err)
+		}
+		specs[fmt.Sprintf("BOOKLORE_CHAT_DEFAULTS entry for chat %d", chatID)] = spec
+	}
+	if len(specs) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	libraries, err := b.catalog.Libraries(ctx)
+	if err != nil {
+		b.config.Logger.Warn("Could not validate default libraries, Booklore is not reachable",
+			zap.Error(err))
+		return nil
+	}
+
+	for name, spec := range specs {
+		target, err := booklore.ResolveTarget(libraries, spec)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		b.config.Logger.Info("Validated default import target",
+			zap.String("setting", name),
+			zap.String("target", target.String()))
+	}
+
+	return nil
+}
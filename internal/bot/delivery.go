@@ -0,0 +1,273 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/brauni/booklore-tg-bot/internal/audit"
+	"github.com/brauni/booklore-tg-bot/internal/delivery"
+	"github.com/brauni/booklore-tg-bot/internal/downloader"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// outboxListSize is the number of deliveries shown by /outbox
+const outboxListSize = 10
+
+func (b *Bot) handleDeviceCommand(chatID int64, userID int64, args string) {
+	if b.outbox == nil {
+		msg := tgbotapi.NewMessage(chatID, "❌ E-mail delivery is not enabled. Please configure SMTP.")
+		b.api.Send(msg)
+		return
+	}
+
+	args = strings.TrimSpace(args)
+	switch {
+	case args == "":
+		text := "📧 No device configured.\n\nUse /device <e-mail> to set the address of your e-reader, e.g. your Send-to-Kindle address."
+		if address, ok := b.devices.Get(userID); ok {
+			text = fmt.Sprintf("📧 Books are sent to %s\n\nUse /device <e-mail> to change it or /device off to remove it.", address)
+		}
+		if len(b.config.Delivery.AllowedFormats) > 0 {
+			text += fmt.Sprintf("\n\nAccepted formats: %s", strings.Join(b.config.Delivery.AllowedFormats, ", "))
+		}
+		text += fmt.Sprintf("\n\n⚠️ Remember to allow %s as a sender on your device.", b.config.Delivery.From)
+		b.api.Send(tgbotapi.NewMessage(chatID, text))
+
+	case args == "off":
+		b.devices.Clear(userID)
+		b.recordAudit(userID, audit.ActionDevice, "", map[string]string{"address": "removed"}, nil)
+		b.api.Send(tgbotapi.NewMessage(chatID, "✅ Device address removed."))
+
+	default:
+		err := b.devices.Set(userID, args)
+		b.recordAudit(userID, audit.ActionDevice, "", map[string]string{"address": delivery.MaskAddress(args)}, err)
+		if err != nil {
+			b.sendErrorMessage(chatID, err.Error())
+			return
+		}
+		b.config.Logger.Info("Device address set",
+			zap.Int64("user_id", userID))
+		b.api.Send(tgbotapi.NewMessage(chatID, "✅ Device address saved. Use the 📧 buttons to send books to it."))
+	}
+}
+
+func (b *Bot) handleOutboxCommand(chatID int64, userID int64) {
+	if b.outbox == nil {
+		msg := tgbotapi.NewMessage(chatID, "❌ E-mail delivery is not enabled. Please configure SMTP.")
+		b.api.Send(msg)
+		return
+	}
+
+	entries := b.outbox.List(userID, outboxListSize)
+	if len(entries) == 0 {
+		b.api.Send(tgbotapi.NewMessage(chatID, "📭 No deliveries yet."))
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString("📬 Recent deliveries\n\n")
+	for _, e := range entries {
+		sb.WriteString(fmt.Sprintf("%s %s\n   ➡️ %s • %s\n",
+			deliveryStatusEmoji(e.Status), e.Title, delivery.MaskAddress(e.To),
+			e.CreatedAt.Local().Format("2006-01-02 15:04")))
+		if e.Status == delivery.StatusQueued && e.Attempts > 0 {
+			sb.WriteString(fmt.Sprintf("   🔁 Attempt %d failed, retrying at %s\n",
+				e.Attempts, e.NextAttemptAt.Local().Format("15:04")))
+		}
+		if e.Status == delivery.StatusFailed && e.LastError != "" {
+			sb.WriteString(fmt.Sprintf("   ℹ️ %s\n", truncateString(e.LastError, 120)))
+		}
+		sb.WriteString("\n")
+	}
+
+	b.api.Send(tgbotapi.NewMessage(chatID, sb.String()))
+}
+
+// handleSendCallback queues a library book or an upload for delivery to the user's device
+func (b *Bot) handleSendCallback(callback *tgbotapi.CallbackQuery) {
+	userID := callback.From.ID
+	chatID := callback.Message.Chat.ID
+	data := callback.Data
+
+	if b.outbox == nil {
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "E-mail delivery is not enabled"))
+		return
+	}
+
+	address, ok := b.devices.Get(userID)
+	if !ok {
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "No device configured"))
+		b.api.Send(tgbotapi.NewMessage(chatID, "📧 Set your e-reader address first with /device <e-mail>."))
+		return
+	}
+
+	entry := delivery.Entry{
+		UserID: userID,
+		ChatID: chatID,
+		To:     address,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	switch {
+	case strings.HasPrefix(data, "send_book_"):
+		ctx, err := b.bookloreContext(ctx, userID)
+		if err != nil {
+			b.api.Request(tgbotapi.NewCallback(callback.ID, "Booklore account required"))
+			b.sendErrorMessage(chatID, err.Error())
+			return
+		}
+
+		var bookID int64
+		if _, err := fmt.Sscanf(data, "send_book_%d", &bookID); err != nil {
+			b.api.Request(tgbotapi.NewCallback(callback.ID, "Invalid book ID"))
+			return
+		}
+
+		book, err := b.booklore.GetBook(ctx, bookID)
+		if err != nil {
+			b.api.Request(tgbotapi.NewCallback(callback.ID, "Book not found"))
+			return
+		}
+		if !b.auth.CanAccessLibrary(userID, book.LibraryID) {
+			b.api.Request(tgbotapi.NewCallback(callback.ID, "You don't have access to this library"))
+			return
+		}
+		if !delivery.IsFormatAllowed(book.FileName, b.config.Delivery.AllowedFormats) {
+			b.api.Request(tgbotapi.NewCallback(callback.ID, "Your device doesn't accept this format"))
+			return
+		}
+
+		entry.Kind = delivery.SourceBook
+		entry.Ref = bookID
+		entry.Title = book.Title()
+
+	case strings.HasPrefix(data, "send_upload_"):
+		var recordID int64
+		if _, err := fmt.Sscanf(data, "send_upload_%d", &recordID); err != nil {
+			b.api.Request(tgbotapi.NewCallback(callback.ID, "Invalid upload ID"))
+			return
+		}
+
+		rec, ok := b.historyRecordForUser(callback, recordID)
+		if !ok {
+			return
+		}
+		if !delivery.IsFormatAllowed(rec.OriginalName, b.config.Delivery.AllowedFormats) {
+			b.api.Request(tgbotapi.NewCallback(callback.ID, "Your device doesn't accept this format"))
+			return
+		}
+
+		entry.Kind = delivery.SourceUpload
+		entry.Ref = recordID
+		entry.Title = rec.OriginalName
+
+	default:
+		return
+	}
+
+	b.outbox.Enqueue(entry)
+	b.api.Request(tgbotapi.NewCallback(callback.ID, "Queued for delivery"))
+	b.api.Send(tgbotapi.NewMessage(chatID,
+		fmt.Sprintf("📧 Sending '%s' to %s...\n\n💡 Use /outbox to check the delivery status.", entry.Title, delivery.MaskAddress(address))))
+}
+
+// openDeliveryAttachment opens the file of an outbox entry for sending
+func (b *Bot) openDeliveryAttachment(ctx context.Context, entry delivery.Entry) (*delivery.Attachment, error) {
+	switch entry.Kind {
+	case delivery.SourceBook:
+		ctx, err := b.bookloreContext(ctx, entry.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		book, err := b.booklore.GetBook(ctx, entry.Ref)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get book: %w", err)
+		}
+
+		download, err := b.booklore.DownloadBook(ctx, entry.Ref)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download book: %w", err)
+		}
+
+		name := download.FileName
+		if name == "" {
+			name = book.FileName
+		}
+		return &delivery.Attachment{Name: name, Reader: download.Body, Size: download.Size}, nil
+
+	case delivery.SourceUpload:
+		rec, ok := b.history.Get(entry.Ref)
+		if !ok {
+			return nil, &delivery.PermanentError{Err: fmt.Errorf("upload %d no longer exists", entry.Ref)}
+		}
+
+		// Uploads may already have been moved into a library, so fetch them from Telegram again
+		fileURL, err := b.getFileURL(ctx, rec.TelegramFileID)
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", fileURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download upload: %w", downloader.WithoutURL(err))
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("failed to download upload: status %d", resp.StatusCode)
+		}
+
+		return &delivery.Attachment{Name: rec.OriginalName, Reader: resp.Body, Size: rec.Size}, nil
+
+	default:
+		return nil, &delivery.PermanentError{Err: fmt.Errorf("unknown delivery source '%s'", entry.Kind)}
+	}
+}
+
+// notifyDeliveryStatus tells the user how a delivery ended
+func (b *Bot) notifyDeliveryStatus(entry delivery.Entry) {
+	var text string
+	switch entry.Status {
+	case delivery.StatusSent:
+		text = fmt.Sprintf("📧 '%s' was sent to %s", entry.Title, delivery.MaskAddress(entry.To))
+	case delivery.StatusFailed:
+		text = fmt.Sprintf("❌ Failed to send '%s' after %d attempts: %s", entry.Title, entry.Attempts, entry.LastError)
+	default:
+		return
+	}
+
+	b.api.Send(tgbotapi.NewMessage(entry.ChatID, text))
+}
+
+// uploadDeliveryMarkup returns a "Send to device" button for an upload, or nil if delivery is disabled
+func (b *Bot) uploadDeliveryMarkup(recordID int64) *tgbotapi.InlineKeyboardMarkup {
+	if b.outbox == nil || recordID == 0 {
+		return nil
+	}
+
+	markup := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("📧 Send to device", fmt.Sprintf("send_upload_%d", recordID)),
+	))
+	return &markup
+}
+
+func deliveryStatusEmoji(status delivery.Status) string {
+	switch status {
+	case delivery.StatusSent:
+		return "✅"
+	case delivery.StatusFailed:
+		return "❌"
+	default:
+		return "⏳"
+	}
+}
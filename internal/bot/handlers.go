@@ -3,15 +3,22 @@ package bot
 import (
 	"context"
 	"fmt"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/brauni/booklore-tg-bot/internal/audit"
 	"github.com/brauni/booklore-tg-bot/internal/booklore"
+	"github.com/brauni/booklore-tg-bot/internal/history"
+	"github.com/brauni/booklore-tg-bot/internal/tracing"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
-func (b *Bot) handleMessage(message *tgbotapi.Message) {
+func (b *Bot) handleMessage(ctx context.Context, message *tgbotapi.Message) {
 	userID := message.From.ID
 	b.config.Logger.Debug("Received message",
 		zap.Int64("user_id", userID),
@@ -27,15 +34,15 @@ func (b *Bot) handleMessage(message *tgbotapi.Message) {
 	// Handle different message types
 	switch {
 	case message.Document != nil:
-		b.handleDocument(message)
+		b.handleDocument(ctx, message)
 	case message.Photo != nil:
-		b.handlePhoto(message)
+		b.handlePhoto(ctx, message)
 	case message.Audio != nil:
-		b.handleAudio(message)
+		b.handleAudio(ctx, message)
 	case message.Video != nil:
-		b.handleVideo(message)
+		b.handleVideo(ctx, message)
 	case message.Voice != nil:
-		b.handleVoice(message)
+		b.handleVoice(ctx, message)
 	case message.Text != "":
 		b.handleTextMessage(message)
 	default:
@@ -43,7 +50,7 @@ func (b *Bot) handleMessage(message *tgbotapi.Message) {
 	}
 }
 
-func (b *Bot) handleDocument(message *tgbotapi.Message) {
+func (b *Bot) handleDocument(ctx context.Context, message *tgbotapi.Message) {
 	document := message.Document
 	userID := message.From.ID
 
@@ -56,13 +63,13 @@ func (b *Bot) handleDocument(message *tgbotapi.Message) {
 	// Check file size
 	if !b.downloader.IsFileSizeAllowed(int64(document.FileSize)) {
 		msg := tgbotapi.NewMessage(message.Chat.ID,
-			fmt.Sprintf("❌ File too large! Maximum size is %d MB.", b.config.MaxFileSizeMB))
+			fmt.Sprintf("❌ File too large! Maximum size is %d MB.", b.settings().MaxFileSizeMB))
 		b.api.Send(msg)
 		return
 	}
 
 	// Get file URL
-	fileURL, err := b.getFileURL(document.FileID)
+	fileURL, err := b.getFileURL(ctx, document.FileID)
 	if err != nil {
 		b.config.Logger.Error("Failed to get file URL",
 			zap.String("file_id", document.FileID),
@@ -81,7 +88,7 @@ func (b *Bot) handleDocument(message *tgbotapi.Message) {
 	}
 
 	// Download file
-	_, err = b.downloader.DownloadFile(fileURL, document.FileName)
+	result, err := b.downloader.DownloadFile(ctx, fileURL, document.FileName)
 	if err != nil {
 		b.config.Logger.Error("Failed to download file",
 			zap.String("file_name", document.FileName),
@@ -90,21 +97,14 @@ func (b *Bot) handleDocument(message *tgbotapi.Message) {
 		return
 	}
 
-	// Trigger Booklore import if enabled
-	importStatus := b.triggerBookloreImport(message.Chat.ID, userID, document.FileName)
-
-	// Prepare success message
-	successMsg := fmt.Sprintf("✅ File '%s' downloaded successfully!", document.FileName)
-	if importStatus != "" {
-		successMsg = importStatus
-	}
+	// Record the upload in the history
+	recordID := b.recordUpload(message, document.FileID, document.FileName, result)
 
-	// Send success message
-	msg := tgbotapi.NewMessage(message.Chat.ID, successMsg)
-	b.api.Send(msg)
+	// Trigger Booklore import, honouring a target given in the caption
+	b.importDocument(ctx, message, document.FileName, filepath.Base(result.Path), recordID)
 }
 
-func (b *Bot) handlePhoto(message *tgbotapi.Message) {
+func (b *Bot) handlePhoto(ctx context.Context, message *tgbotapi.Message) {
 	photos := message.Photo
 	if len(photos) == 0 {
 		return
@@ -124,7 +124,7 @@ func (b *Bot) handlePhoto(message *tgbotapi.Message) {
 	filename := fmt.Sprintf("photo_%s_%d.jpg", message.From.UserName, message.MessageID)
 
 	// Get file URL
-	fileURL, err := b.getFileURL(photo.FileID)
+	fileURL, err := b.getFileURL(ctx, photo.FileID)
 	if err != nil {
 		b.config.Logger.Error("Failed to get photo URL",
 			zap.String("file_id", photo.FileID),
@@ -143,7 +143,7 @@ func (b *Bot) handlePhoto(message *tgbotapi.Message) {
 	}
 
 	// Download photo
-	_, err = b.downloader.DownloadFile(fileURL, filename)
+	result, err := b.downloader.DownloadFile(ctx, fileURL, filename)
 	if err != nil {
 		b.config.Logger.Error("Failed to download photo",
 			zap.String("filename", filename),
@@ -152,8 +152,11 @@ func (b *Bot) handlePhoto(message *tgbotapi.Message) {
 		return
 	}
 
+	// Record the upload in the history
+	recordID := b.recordUpload(message, photo.FileID, filename, result)
+
 	// Trigger Booklore import if enabled
-	importStatus := b.triggerBookloreImport(message.Chat.ID, userID, filename)
+	importStatus := b.triggerBookloreImport(ctx, message.Chat.ID, userID, filepath.Base(result.Path), recordID, nil)
 
 	// Prepare success message
 	successMsg := fmt.Sprintf("✅ Photo '%s' downloaded successfully!", filename)
@@ -166,23 +169,23 @@ func (b *Bot) handlePhoto(message *tgbotapi.Message) {
 	b.api.Send(msg)
 }
 
-func (b *Bot) handleAudio(message *tgbotapi.Message) {
+func (b *Bot) handleAudio(ctx context.Context, message *tgbotapi.Message) {
 	audio := message.Audio
-	b.downloadMediaFile(message, audio.FileID, audio.FileName, "audio", int64(audio.FileSize))
+	b.downloadMediaFile(ctx, message, audio.FileID, audio.FileName, "audio", int64(audio.FileSize))
 }
 
-func (b *Bot) handleVideo(message *tgbotapi.Message) {
+func (b *Bot) handleVideo(ctx context.Context, message *tgbotapi.Message) {
 	video := message.Video
-	b.downloadMediaFile(message, video.FileID, video.FileName, "video", int64(video.FileSize))
+	b.downloadMediaFile(ctx, message, video.FileID, video.FileName, "video", int64(video.FileSize))
 }
 
-func (b *Bot) handleVoice(message *tgbotapi.Message) {
+func (b *Bot) handleVoice(ctx context.Context, message *tgbotapi.Message) {
 	voice := message.Voice
 	filename := fmt.Sprintf("voice_%s_%d.ogg", message.From.UserName, message.MessageID)
-	b.downloadMediaFile(message, voice.FileID, filename, "voice", int64(voice.FileSize))
+	b.downloadMediaFile(ctx, message, voice.FileID, filename, "voice", int64(voice.FileSize))
 }
 
-func (b *Bot) downloadMediaFile(message *tgbotapi.Message, fileID, filename, mediaType string, fileSize int64) {
+func (b *Bot) downloadMediaFile(ctx context.Context, message *tgbotapi.Message, fileID, filename, mediaType string, fileSize int64) {
 	userID := message.From.ID
 
 	b.config.Logger.Info("Processing "+mediaType,
@@ -193,13 +196,13 @@ func (b *Bot) downloadMediaFile(message *tgbotapi.Message, fileID, filename, med
 	// Check file size
 	if !b.downloader.IsFileSizeAllowed(fileSize) {
 		msg := tgbotapi.NewMessage(message.Chat.ID,
-			fmt.Sprintf("❌ File too large! Maximum size is %d MB.", b.config.MaxFileSizeMB))
+			fmt.Sprintf("❌ File too large! Maximum size is %d MB.", b.settings().MaxFileSizeMB))
 		b.api.Send(msg)
 		return
 	}
 
 	// Get file URL
-	fileURL, err := b.getFileURL(fileID)
+	fileURL, err := b.getFileURL(ctx, fileID)
 	if err != nil {
 		b.config.Logger.Error("Failed to get file URL",
 			zap.String("file_id", fileID),
@@ -218,7 +221,7 @@ func (b *Bot) downloadMediaFile(message *tgbotapi.Message, fileID, filename, med
 	}
 
 	// Download file
-	_, err = b.downloader.DownloadFile(fileURL, filename)
+	result, err := b.downloader.DownloadFile(ctx, fileURL, filename)
 	if err != nil {
 		b.config.Logger.Error("Failed to download "+mediaType,
 			zap.String("filename", filename),
@@ -227,8 +230,11 @@ func (b *Bot) downloadMediaFile(message *tgbotapi.Message, fileID, filename, med
 		return
 	}
 
+	// Record the upload in the history
+	recordID := b.recordUpload(message, fileID, filename, result)
+
 	// Trigger Booklore import if enabled
-	importStatus := b.triggerBookloreImport(message.Chat.ID, userID, filename)
+	importStatus := b.triggerBookloreImport(ctx, message.Chat.ID, userID, filepath.Base(result.Path), recordID, nil)
 
 	// Prepare success message
 	successMsg := fmt.Sprintf("✅ %s '%s' downloaded successfully!", mediaType, filename)
@@ -245,6 +251,23 @@ func (b *Bot) handleTextMessage(message *tgbotapi.Message) {
 	text := message.Text
 	userID := message.From.ID
 
+	// Handle deep links from inline results, e.g. "/start book_42"
+	if message.Command() == "start" && strings.HasPrefix(message.CommandArguments(), "book_") {
+		var bookID int64
+		if _, err := fmt.Sscanf(message.CommandArguments(), "book_%d", &bookID); err == nil {
+			b.sendLibraryBook(message.Chat.ID, userID, bookID)
+			return
+		}
+	}
+
+	// Plain text may answer a pending prompt, e.g. a title typed during /review
+	if !strings.HasPrefix(text, "/") {
+		if prompt, ok := b.prompts.Take(userID, message.Chat.ID); ok {
+			b.handleReviewInput(message, prompt)
+			return
+		}
+	}
+
 	// Handle commands
 	if text == "/start" || text == "/help" {
 		b.sendHelpMessage(message.Chat.ID)
@@ -256,23 +279,23 @@ func (b *Bot) handleTextMessage(message *tgbotapi.Message) {
 		return
 	}
 
-	if text == "/bookdrop" {
-		b.handleBookdropCommand(message.Chat.ID)
+	if message.Command() == "bookdrop" {
+		b.handleBookdropCommand(message.Chat.ID, userID, message.CommandArguments())
 		return
 	}
 
 	if text == "/rescan" {
-		b.handleRescanCommand(message.Chat.ID)
+		b.handleRescanCommand(message.Chat.ID, userID)
 		return
 	}
 
-	if text == "/import" {
-		b.handleImportCommand(message.Chat.ID, userID)
+	if message.Command() == "import" {
+		b.handleImportCommand(message.Chat.ID, userID, message.CommandArguments())
 		return
 	}
 
 	if text == "/debug_bookdrop" {
-		b.handleDebugBookdropCommand(message.Chat.ID)
+		b.handleDebugBookdropCommand(message.Chat.ID, userID)
 		return
 	}
 
@@ -286,17 +309,73 @@ func (b *Bot) handleTextMessage(message *tgbotapi.Message) {
 		return
 	}
 
+	if message.Command() == "search" {
+		b.handleSearchCommand(message.Chat.ID, userID, message.CommandArguments())
+		return
+	}
+
+	if message.Command() == "book" {
+		b.handleBookCommand(message.Chat.ID, userID, message.CommandArguments())
+		return
+	}
+
+	if message.Command() == "device" {
+		b.handleDeviceCommand(message.Chat.ID, userID, message.CommandArguments())
+		return
+	}
+
+	if text == "/outbox" {
+		b.handleOutboxCommand(message.Chat.ID, userID)
+		return
+	}
+
+	if message.Command() == "review" {
+		b.handleReviewCommand(message.Chat.ID, userID, message.CommandArguments())
+		return
+	}
+
+	if message.Command() == "history" {
+		b.handleHistoryCommand(message.Chat.ID, userID)
+		return
+	}
+
+	if message.Command() == "subscribe" {
+		b.handleSubscribeCommand(message.Chat.ID, userID, message.CommandArguments())
+		return
+	}
+
+	if message.Command() == "audit" {
+		b.handleAuditCommand(message.Chat.ID, userID, message.CommandArguments())
+		return
+	}
+
+	if message.Command() == "route" {
+		b.handleRouteCommand(message.Chat.ID, userID, message.CommandArguments())
+		return
+	}
+
+	if message.Command() == "link" {
+		b.handleLinkCommand(message)
+		return
+	}
+
+	if text == "/unlink" {
+		b.handleUnlinkCommand(message.Chat.ID, userID)
+		return
+	}
+
 	// Default text response
 	msg := tgbotapi.NewMessage(message.Chat.ID,
 		"👋 Send me a file and I'll download it for you!\n\nUse /help for more information.")
 	b.api.Send(msg)
 }
 
-func (b *Bot) getFileURL(fileID string) (string, error) {
-	// Add some logging to debug the file ID and bot configuration
+func (b *Bot) getFileURL(ctx context.Context, fileID string) (string, error) {
+	_, span := tracing.Start(ctx, "telegram.getFile")
+	defer span.End()
+
 	b.config.Logger.Debug("Attempting to get file URL",
-		zap.String("file_id", fileID),
-		zap.String("bot_token_prefix", b.config.BotToken[:min(len(b.config.BotToken), 10)]+"..."))
+		zap.String("file_id", fileID))
 
 	file, err := b.api.GetFile(tgbotapi.FileConfig{FileID: fileID})
 	if err != nil {
@@ -323,16 +402,17 @@ func (b *Bot) getFileURL(fileID string) (string, error) {
 		botToken := b.config.BotToken
 		alternativeURL := fmt.Sprintf("https://api.telegram.org/file/bot%s/%s", botToken, file.FilePath)
 
+		// The URL contains the bot token, so only the file path is logged
 		b.config.Logger.Info("Trying alternative file URL format",
 			zap.String("file_id", fileID),
-			zap.String("alternative_url", alternativeURL))
+			zap.String("file_path", file.FilePath))
 
 		return alternativeURL, nil
 	}
 
 	b.config.Logger.Debug("Successfully generated file URL",
 		zap.String("file_id", fileID),
-		zap.String("file_url", fileURL))
+		zap.String("file_path", file.FilePath))
 
 	return fileURL, nil
 }
@@ -385,24 +465,55 @@ I can download files you send me and save them to my storage.`
 
 *Commands:*
 /start or /help - Show this help message
-/status - Show bot status and settings`
+/status - Show bot status and settings
+/history - Browse your uploads`
 
 	if b.booklore.IsEnabled() {
 		helpText += `
-/bookdrop - List all files in bookdrop
+/bookdrop [filter] - Browse files in bookdrop
 /rescan - Scan bookdrop for new files
-/import - Select files for import to library
+/import [filter] - Select files for import to library
+/review - Review metadata before importing
 /libraries - List available libraries
-/set_library - Choose your preferred library`
+/set_library - Choose your preferred library
+/search <query> - Search the library
+/book <id> - Get a book from the library`
 		// Debug command - not shown in help but available
 		// /debug_bookdrop - Test different API endpoints
 	}
 
+	if b.accounts != nil {
+		helpText += `
+/link - Link your own Booklore account (private chat)
+/unlink - Remove your linked Booklore account`
+	}
+
+	if b.router != nil {
+		helpText += `
+/route <filename> - Preview where an upload would be imported`
+	}
+
+	if b.watcher != nil {
+		helpText += `
+/subscribe - Get notified about new bookdrop files`
+	}
+
+	if b.outbox != nil {
+		helpText += `
+/device <e-mail> - Set your e-reader address
+/outbox - Show e-mail delivery status`
+	}
+
+	if b.auth.HasAdmins() {
+		helpText += `
+/audit - Show the audit log (admins)`
+	}
+
 	helpText += `
 
-*Allowed file types:* ` + fmt.Sprintf("%v", b.config.AllowedFileTypes) + `
+*Allowed file types:* ` + fmt.Sprintf("%v", b.settings().AllowedFileTypes) + `
 
-*Max file size:* ` + fmt.Sprintf("%d MB", b.config.MaxFileSizeMB) + `
+*Max file size:* ` + fmt.Sprintf("%d MB", b.settings().MaxFileSizeMB) + `
 
 Simply send me any file and I'll download it for you!`
 
@@ -428,16 +539,18 @@ func (b *Bot) sendStatusMessage(chatID int64, userID int64) {
 		b.api.Self.UserName,
 		b.config.DownloadFolder,
 		b.auth.GetAllowedUsersCount(),
-		len(b.config.AllowedFileTypes),
-		b.config.MaxFileSizeMB)
+		len(b.settings().AllowedFileTypes),
+		b.settings().MaxFileSizeMB)
 
 	// Add Booklore status if configured
 	if b.booklore.IsEnabled() {
 		// Get user's library preference
 		pref := b.preferences.GetUserPreference(userID)
-		libraryInfo := "Default library"
+		libraryInfo := "Not configured"
 		if pref.HasLibrary() {
 			libraryInfo = fmt.Sprintf("%s (📁 %s)", pref.GetLibraryName(), pref.GetPathName())
+		} else if fallbacks := b.fallbackTargetSpecs(chatID); len(fallbacks) > 0 {
+			libraryInfo = fmt.Sprintf("%s (%s)", fallbacks[0].spec, fallbacks[0].source)
 		}
 
 		statusText += fmt.Sprintf(`
@@ -449,6 +562,16 @@ func (b *Bot) sendStatusMessage(chatID int64, userID int64) {
 			b.config.BookloreAPI.APIURL,
 			b.config.BookloreAPI.AutoImport,
 			libraryInfo)
+
+		if b.accounts != nil {
+			accountInfo := "Bot account"
+			if _, linked := b.accounts.Get(userID); linked {
+				accountInfo = "Your linked account"
+			} else if !b.settings().BookloreAPI.SharedFallback {
+				accountInfo = "Not linked, use /link"
+			}
+			statusText += "\n👤 Account: " + accountInfo
+		}
 	} else {
 		statusText += `
 
@@ -460,94 +583,17 @@ func (b *Bot) sendStatusMessage(chatID int64, userID int64) {
 	b.api.Send(msg)
 }
 
-func (b *Bot) handleBookdropCommand(chatID int64) {
+func (b *Bot) handleBookdropCommand(chatID int64, userID int64, args string) {
 	if !b.booklore.IsEnabled() {
 		msg := tgbotapi.NewMessage(chatID, "❌ Booklore integration is not enabled. Please configure the API token.")
 		b.api.Send(msg)
 		return
 	}
 
-	// Send typing indicator to show we're working
-	action := tgbotapi.NewChatAction(chatID, "typing")
-	b.api.Send(action)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	// Get all files from bookdrop (no status filter)
-	b.config.Logger.Info("Fetching bookdrop files",
-		zap.String("api_url", b.config.BookloreAPI.APIURL))
-
-	files, err := b.booklore.GetBookdropFilesNoStatus(ctx, 0, 50) // Get up to 50 files
-	if err != nil {
-		b.config.Logger.Error("Failed to get bookdrop files",
-			zap.Error(err),
-			zap.String("api_url", b.config.BookloreAPI.APIURL))
-		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Failed to retrieve bookdrop files: %s", err.Error()))
-		b.api.Send(msg)
-		return
-	}
-
-	b.config.Logger.Info("Bookdrop files retrieved",
-		zap.Int("total_files", files.TotalElements),
-		zap.Int("files_in_content", len(files.Content)))
-
-	// Log detailed information about each file
-	for i, file := range files.Content {
-		b.config.Logger.Info("File details",
-			zap.Int("index", i),
-			zap.Int64("id", file.ID),
-			zap.String("filename", file.FileName),
-			zap.String("status", file.Status),
-			zap.String("date_added", file.DateAdded))
-	}
-
-	if files.TotalElements == 0 {
-		msg := tgbotapi.NewMessage(chatID, "📂 Bookdrop is empty. No files found.")
-		b.api.Send(msg)
-		return
-	}
-
-	// Format and send the bookdrop contents
-	message := fmt.Sprintf("📂 *Bookdrop Contents*\n\nFound %d files:\n\n", files.TotalElements)
-
-	for i, file := range files.Content {
-		status := "⏳ " + file.Status
-		if file.Status == "NEW" {
-			status = "🆕 " + file.Status
-		} else if file.Status == "IMPORTED" {
-			status = "✅ " + file.Status
-		} else if file.Status == "FAILED" {
-			status = "❌ " + file.Status
-		}
-
-		message += fmt.Sprintf("%d. %s\n   📄 %s\n   📏 %d KB\n   📅 %s\n\n",
-			i+1, status, file.FileName, file.FileSize/1024, file.DateAdded)
-
-		// Split long messages to avoid Telegram limits
-		if len(message) > 3500 {
-			msg := tgbotapi.NewMessage(chatID, message)
-			msg.ParseMode = "Markdown"
-			b.api.Send(msg)
-			message = ""
-		}
-	}
-
-	if message != "" {
-		msg := tgbotapi.NewMessage(chatID, message)
-		msg.ParseMode = "Markdown"
-		b.api.Send(msg)
-	}
-
-	// Add suggestion for import
-	if files.TotalElements > 0 {
-		hint := tgbotapi.NewMessage(chatID,
-			"💡 Use /rescan to refresh the bookdrop or /import to select files for import.")
-		b.api.Send(hint)
-	}
+	b.openBookdropBrowser(chatID, userID, false, args)
 }
 
-func (b *Bot) handleRescanCommand(chatID int64) {
+func (b *Bot) handleRescanCommand(chatID int64, userID int64) {
 	if !b.booklore.IsEnabled() {
 		msg := tgbotapi.NewMessage(chatID, "❌ Booklore integration is not enabled. Please configure the API token.")
 		b.api.Send(msg)
@@ -564,7 +610,9 @@ func (b *Bot) handleRescanCommand(chatID int64) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if err := b.booklore.RescanBookdrop(ctx); err != nil {
+	err := b.booklore.RescanBookdrop(ctx)
+	b.recordAudit(userID, audit.ActionRescan, "bookdrop", nil, err)
+	if err != nil {
 		b.config.Logger.Error("Failed to rescan bookdrop",
 			zap.Error(err))
 		errorMsg := tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Failed to scan bookdrop: %s", err.Error()))
@@ -576,16 +624,15 @@ func (b *Bot) handleRescanCommand(chatID int64) {
 	b.api.Send(successMsg)
 }
 
-func (b *Bot) handleImportCommand(chatID int64, userID int64) {
+func (b *Bot) handleImportCommand(chatID int64, userID int64, args string) {
 	if !b.booklore.IsEnabled() {
 		msg := tgbotapi.NewMessage(chatID, "❌ Booklore integration is not enabled. Please configure the API token.")
 		b.api.Send(msg)
 		return
 	}
 
-	// Check if user has library configured
-	pref := b.preferences.GetUserPreference(userID)
-	if !pref.HasLibrary() {
+	// Check if user has library configured, or a default applies
+	if !b.hasImportTarget(chatID, userID) {
 		// User has no library configured, force them to set one
 		message := `📚 *Library Configuration Required*
 
@@ -621,107 +668,10 @@ Use /set_library to configure your preferred library and path.
 		return
 	}
 
-	// Send typing indicator
-	action := tgbotapi.NewChatAction(chatID, "typing")
-	b.api.Send(action)
-
-	msg := tgbotapi.NewMessage(chatID, "🔄 Preparing import options...")
-	b.api.Send(msg)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	// Get all files for import (not just NEW files)
-	files, err := b.booklore.GetBookdropFilesNoStatus(ctx, 0, 20) // Get up to 20 files
-	if err != nil {
-		b.config.Logger.Error("Failed to get bookdrop files for import",
-			zap.Error(err))
-		errorMsg := tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Failed to retrieve files for import: %s", err.Error()))
-		b.api.Send(errorMsg)
-		return
-	}
-
-	if files.TotalElements == 0 {
-		msg := tgbotapi.NewMessage(chatID, "📂 No new files found in bookdrop for import.\n\n💡 Use /rescan to check for new files, or /bookdrop to see all files.")
-		b.api.Send(msg)
-		return
-	}
-
-	// Create inline keyboard for file selection
-	var keyboard [][]tgbotapi.InlineKeyboardButton
-
-	for i, file := range files.Content {
-		if i >= 10 { // Limit to 10 files to keep message manageable
-			break
-		}
-
-		b.config.Logger.Info("Creating file button",
-			zap.Int64("file_id", file.ID),
-			zap.String("file_name", file.FileName),
-			zap.String("file_status", file.Status))
-
-		statusEmoji := ""
-		switch file.Status {
-		case "NEW":
-			statusEmoji = "🆕"
-		case "PENDING_REVIEW":
-			statusEmoji = "⏳"
-		case "PROCESSED":
-			statusEmoji = "🔍"
-		case "IMPORTED":
-			statusEmoji = "✅"
-		case "FAILED":
-			statusEmoji = "❌"
-		default:
-			statusEmoji = "📄"
-		}
-
-		buttonText := fmt.Sprintf("%s %s (%.1f MB)",
-			statusEmoji,
-			truncateString(file.FileName, 40),
-			float64(file.FileSize)/1024/1024)
-
-		callbackData := fmt.Sprintf("import_%d", file.ID)
-
-		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
-			{Text: buttonText, CallbackData: &callbackData},
-		})
-	}
-
-	// Add "Import All" and "Cancel" buttons
-	if files.TotalElements >= 1 {
-		var importAllBtnText string
-		if files.TotalElements == 1 {
-			importAllBtnText = "📥 Import Book"
-		} else {
-			importAllBtnText = "📥 Import All"
-		}
-
-		importAllBtn := tgbotapi.InlineKeyboardButton{
-			Text:         importAllBtnText,
-			CallbackData: &[]string{"import_all"}[0],
-		}
-		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{importAllBtn})
-	}
-
-	cancelBtn := tgbotapi.InlineKeyboardButton{
-		Text:         "❌ Cancel",
-		CallbackData: &[]string{"import_cancel"}[0],
-	}
-	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{cancelBtn})
-
-	replyMarkup := tgbotapi.NewInlineKeyboardMarkup(keyboard...)
-
-	message := fmt.Sprintf("📥 *Select files to import*\n\nFound %d new files:\n\n💡 Choose files below or use 📥 Import All for all new files.",
-		files.TotalElements)
-
-	telegramMsg := tgbotapi.NewMessage(chatID, message)
-	telegramMsg.ParseMode = "Markdown"
-	telegramMsg.ReplyMarkup = replyMarkup
-	b.api.Send(telegramMsg)
+	b.openBookdropBrowser(chatID, userID, true, args)
 }
 
-func (b *Bot) handleDebugBookdropCommand(chatID int64) {
+func (b *Bot) handleDebugBookdropCommand(chatID int64, userID int64) {
 	if !b.booklore.IsEnabled() {
 		msg := tgbotapi.NewMessage(chatID, "❌ Booklore integration is not enabled.")
 		b.api.Send(msg)
@@ -731,6 +681,8 @@ func (b *Bot) handleDebugBookdropCommand(chatID int64) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	b.recordAudit(userID, audit.ActionDebug, "bookdrop", nil, nil)
+
 	debugMsg := "🔍 *Bookdrop Debug Information*\n\n"
 
 	// Test different API endpoints
@@ -799,6 +751,9 @@ func (b *Bot) handleLibrariesCommand(chatID int64, userID int64) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	// Listing libraries always asks Booklore, which also refreshes the cached catalog
+	b.catalog.Invalidate()
+
 	// Get user's current preference
 	pref := b.preferences.GetUserPreference(userID)
 	currentLibMsg := ""
@@ -807,7 +762,7 @@ func (b *Bot) handleLibrariesCommand(chatID int64, userID int64) {
 	}
 
 	// Fetch libraries
-	libraries, err := b.booklore.GetLibraries(ctx)
+	libraries, err := b.catalog.Libraries(ctx)
 	if err != nil {
 		b.config.Logger.Error("Failed to get libraries",
 			zap.Error(err))
@@ -862,7 +817,7 @@ func (b *Bot) handleSetLibraryCommand(chatID int64, userID int64) {
 	defer cancel()
 
 	// Fetch libraries
-	libraries, err := b.booklore.GetLibraries(ctx)
+	libraries, err := b.catalog.Libraries(ctx)
 	if err != nil {
 		b.config.Logger.Error("Failed to get libraries for selection",
 			zap.Error(err))
@@ -1020,6 +975,10 @@ func (b *Bot) handlePathSelectCallback(callback *tgbotapi.CallbackQuery) {
 			zap.String("path_name", pathName))
 
 		b.preferences.SetUserPreference(userID, libraryID, pathID, libraryDetails.Name, pathName)
+		b.recordAudit(userID, audit.ActionPreference, fmt.Sprintf("library:%d/path:%d", libraryID, pathID), map[string]string{
+			"library": libraryDetails.Name,
+			"path":    pathName,
+		}, nil)
 
 		successMsg := fmt.Sprintf("✅ Library preference set!\n\n📚 **Library**: %s\n📁 **Path**: %s\n\nAll imports will now go to this library and path.",
 			libraryDetails.Name, pathName)
@@ -1030,62 +989,181 @@ func (b *Bot) handlePathSelectCallback(callback *tgbotapi.CallbackQuery) {
 }
 
 // triggerBookloreImport triggers the Booklore import process after a file download
-func (b *Bot) triggerBookloreImport(chatID int64, userID int64, filename string) string {
+func (b *Bot) triggerBookloreImport(ctx context.Context, chatID int64, userID int64, filename string, recordID int64, target *booklore.ImportTarget) string {
 	if !b.booklore.IsEnabled() || !b.config.BookloreAPI.AutoImport {
+		b.setUploadImportResult(recordID, history.ImportSkipped, "Auto-import disabled")
 		return ""
 	}
 
+	return b.importUpload(ctx, chatID, userID, filename, recordID, target)
+}
+
+// importUpload rescans the bookdrop and imports a downloaded file into the given
+// target, or into the user's library if target is nil
+func (b *Bot) importUpload(ctx context.Context, chatID int64, userID int64, filename string, recordID int64, target *booklore.ImportTarget) string {
+	ctx, span := tracing.Start(ctx, "bot.importUpload",
+		attribute.String("file.name", filename),
+		attribute.Int64("upload.record_id", recordID))
+	defer func() {
+		if rec, ok := b.history.Get(recordID); ok {
+			span.SetAttributes(attribute.String("upload.import_status", string(rec.ImportStatus)))
+		}
+		span.End()
+	}()
+
 	b.config.Logger.Info("Triggering Booklore import",
 		zap.String("filename", filename),
-		zap.Int64("user_id", userID))
+		zap.Int64("user_id", userID),
+		zap.Int64("record_id", recordID),
+		zap.Bool("target_override", target != nil),
+		tracing.TraceID(ctx))
 
 	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
 	defer cancel()
 
+	// The import itself is made as the user's linked Booklore account
+	importCtx, err := b.bookloreContext(ctx, userID)
+	if err != nil {
+		b.setUploadImportResult(recordID, history.ImportSkipped, err.Error())
+		return fmt.Sprintf("📥 File downloaded, but not imported to Booklore: %s", err.Error())
+	}
+
+	// Listen for Booklore events before rescanning so the bookdrop update isn't missed
+	var events <-chan booklore.Event
+	if b.events != nil && b.events.Connected() {
+		var unsubscribe func()
+		events, unsubscribe = b.events.Subscribe()
+		defer unsubscribe()
+	}
+
 	// First rescan the bookdrop folder
 	if err := b.booklore.RescanBookdrop(ctx); err != nil {
 		b.config.Logger.Error("Failed to rescan bookdrop folder",
 			zap.String("filename", filename),
-			zap.Error(err))
+			zap.Error(err),
+			tracing.TraceID(ctx))
+		b.setUploadImportResult(recordID, history.ImportFailed, err.Error())
 		return fmt.Sprintf("📥 File downloaded, but failed to trigger Booklore scan: %s", err.Error())
 	}
 
-	// Get library IDs for user
-	libraryID, pathID := b.getLibraryIDsForUser(userID)
+	// Reuse the Booklore file ID if a previous attempt already found it
+	var bookdropFileID int64
+	if rec, ok := b.history.Get(recordID); ok {
+		bookdropFileID = rec.BookloreFileID
+	}
 
-	// If user has no library configured, don't attempt auto-import
-	if libraryID == "" || pathID == "" {
-		b.config.Logger.Info("Skipping auto-import - user has no library configured",
-			zap.Int64("user_id", userID),
-			zap.String("filename", filename))
-		return ""
+	// Booklore announces when the rescan picked up new files, which also means
+	// their metadata is ready. No need to wait if the file is listed already.
+	if events != nil && bookdropFileID == 0 {
+		bookdropFileID = b.findBookdropFileID(ctx, filename)
+		if bookdropFileID == 0 && !waitForBookdropEvent(ctx, events, bookdropEventTimeout) {
+			b.config.Logger.Debug("No bookdrop event after rescan, continuing",
+				zap.String("filename", filename))
+		}
+		if bookdropFileID != 0 && recordID != 0 {
+			b.history.Update(recordID, func(rec *history.Record) {
+				rec.BookloreFileID = bookdropFileID
+			})
+		}
 	}
 
+	// Without a target chosen for this upload the routing rules pick one, but
+	// only once Booklore lists the file, as rules may match on its metadata
+	routed := target != nil || b.router == nil
+	var libraryID, pathID string
+
 	// Wait a moment for Booklore to process the file, then retry import
 	maxRetries := 3
 	retryDelay := 3 * time.Second
 
 	for attempt := 0; attempt < maxRetries; attempt++ {
+		span.AddEvent("import attempt", trace.WithAttributes(attribute.Int("attempt", attempt+1)))
+
 		if attempt > 0 {
 			b.config.Logger.Info("Waiting before retry",
 				zap.String("filename", filename),
 				zap.Int("attempt", attempt+1),
 				zap.Duration("delay", retryDelay))
 
-			select {
-			case <-ctx.Done():
+			// A bookdrop event ends the wait early
+			waitForBookdropEvent(ctx, events, retryDelay)
+			if ctx.Err() != nil {
+				b.setUploadImportResult(recordID, history.ImportFailed, "Import timed out")
 				return "📥 File downloaded, but Booklore import timed out"
-			case <-time.After(retryDelay):
 			}
 		}
 
-		// Finalize all imports
-		result, err := b.booklore.FinalizeAllImports(ctx, libraryID, pathID)
+		// Look up the bookdrop entry for this file so only it gets imported
+		if bookdropFileID == 0 {
+			bookdropFileID = b.findBookdropFileID(ctx, filename)
+			if bookdropFileID != 0 && recordID != 0 {
+				b.history.Update(recordID, func(rec *history.Record) {
+					rec.BookloreFileID = bookdropFileID
+				})
+			}
+		}
+
+		if !routed {
+			// Give Booklore until the last attempt to list the file before routing without metadata
+			if bookdropFileID == 0 && attempt < maxRetries-1 {
+				b.config.Logger.Debug("Upload not in the bookdrop yet, routing it later",
+					zap.String("filename", filename),
+					zap.Int("attempt", attempt+1))
+				continue
+			}
+			target = b.routeUpload(ctx, chatID, userID, filename, recordID, bookdropFileID)
+			routed = true
+		}
+
+		if libraryID == "" {
+			// Use the target chosen for this upload, falling back to the user's library
+			if target != nil {
+				libraryID = strconv.FormatInt(target.LibraryID, 10)
+				pathID = strconv.FormatInt(target.PathID, 10)
+				if recordID != 0 {
+					b.history.Update(recordID, func(rec *history.Record) {
+						rec.TargetLibraryID = target.LibraryID
+						rec.TargetPathID = target.PathID
+					})
+				}
+			} else {
+				libraryID, pathID = b.getLibraryIDsForUser(chatID, userID)
+			}
+
+			// If user has no library configured, don't attempt auto-import
+			if libraryID == "" || pathID == "" {
+				b.config.Logger.Info("Skipping auto-import - user has no library configured",
+					zap.Int64("user_id", userID),
+					zap.String("filename", filename))
+				b.setUploadImportResult(recordID, history.ImportSkipped, "No library configured")
+				return ""
+			}
+		}
+
+		var result *booklore.BookdropFinalizeResult
+		var err error
+		if bookdropFileID != 0 {
+			result, err = b.booklore.FinalizeImport(importCtx, []int64{bookdropFileID}, libraryID, pathID)
+		} else {
+			// Fall back to finalizing everything in the bookdrop
+			result, err = b.booklore.FinalizeAllImports(importCtx, libraryID, pathID)
+		}
+		if err != nil || result.ImportedCount > 0 {
+			var fileIDs []int64
+			if bookdropFileID != 0 {
+				fileIDs = []int64{bookdropFileID}
+			}
+			b.recordImportAudit(userID, fileIDs, libraryID, pathID, map[string]string{"filename": filename}, result, err)
+		}
 		if err != nil {
 			b.config.Logger.Error("Failed to finalize Booklore import",
 				zap.String("filename", filename),
-				zap.Error(err))
+				zap.Error(err),
+				tracing.TraceID(ctx))
+			// The target library or path may have been removed
+			b.catalog.Invalidate()
+			b.setUploadImportResult(recordID, history.ImportFailed, err.Error())
 			return fmt.Sprintf("📥 File downloaded, but failed to complete Booklore import: %s", err.Error())
 		}
 
@@ -1094,6 +1172,7 @@ func (b *Bot) triggerBookloreImport(chatID int64, userID int64, filename string)
 				zap.String("filename", filename),
 				zap.Int("imported_count", result.ImportedCount),
 				zap.Int("attempt", attempt+1))
+			b.setUploadImportResult(recordID, history.ImportImported, fmt.Sprintf("%d books imported", result.ImportedCount))
 			return fmt.Sprintf("📚 File downloaded and imported to Booklore successfully! (%d books imported)", result.ImportedCount)
 		}
 
@@ -1108,20 +1187,69 @@ func (b *Bot) triggerBookloreImport(chatID int64, userID int64, filename string)
 	b.config.Logger.Info("Booklore import completed after retries",
 		zap.String("filename", filename),
 		zap.Int("total_attempts", maxRetries))
+	b.recordImportAudit(userID, nil, libraryID, pathID, map[string]string{"filename": filename}, nil, fmt.Errorf("no books imported after %d attempts", maxRetries))
+	b.setUploadImportResult(recordID, history.ImportFailed, "No books imported after multiple attempts")
 	return "📥 File downloaded to bookdrop, but no new books were imported after multiple attempts"
 }
 
-// Helper function for case-insensitive string matching
-func containsIgnoreCase(s, substr string) bool {
-	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+// bookdropEventTimeout bounds how long an import waits for Booklore to announce a
+// rescan; the retries below poll for the file anyway
+const bookdropEventTimeout = 5 * time.Second
+
+// waitForBookdropEvent waits for a bookdrop event until the timeout passes. Without
+// an event stream it simply waits out the timeout. It reports whether an event arrived.
+func waitForBookdropEvent(ctx context.Context, events <-chan booklore.Event, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-timer.C:
+			return false
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			if _, isBookdrop := event.(booklore.BookdropEvent); isBookdrop {
+				return true
+			}
+		}
+	}
 }
 
-// Helper function to get minimum of two integers
-func min(a, b int) int {
-	if a < b {
-		return a
+// findBookdropFileID looks up the bookdrop file ID for a file name, returning 0 if it is not listed
+func (b *Bot) findBookdropFileID(ctx context.Context, filename string) int64 {
+	if file := b.findBookdropFile(ctx, filename); file != nil {
+		return file.ID
 	}
-	return b
+	return 0
+}
+
+// findBookdropFile looks up the bookdrop entry for a file name, returning nil if it is not listed
+func (b *Bot) findBookdropFile(ctx context.Context, filename string) *booklore.BookdropFile {
+	files, err := b.booklore.GetBookdropFilesNoStatus(ctx, 0, 1000)
+	if err != nil {
+		b.config.Logger.Warn("Failed to look up bookdrop file",
+			zap.String("filename", filename),
+			zap.Error(err))
+		return nil
+	}
+
+	for _, file := range files.Content {
+		if file.FileName == filename {
+			return &file
+		}
+	}
+
+	return nil
+}
+
+// Helper function for case-insensitive string matching
+func containsIgnoreCase(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
 }
 
 // Helper function to truncate strings
@@ -1136,7 +1264,7 @@ func truncateString(s string, maxLength int) string {
 }
 
 // handleImportCallback handles callback queries from inline keyboards
-func (b *Bot) handleImportCallback(callback *tgbotapi.CallbackQuery) {
+func (b *Bot) handleImportCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
 	if !b.booklore.IsEnabled() {
 		callbackResponse := tgbotapi.NewCallback(callback.ID, "Booklore integration is not enabled")
 		b.api.Request(callbackResponse)
@@ -1156,10 +1284,30 @@ func (b *Bot) handleImportCallback(callback *tgbotapi.CallbackQuery) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
 	defer cancel()
 
+	// Imports are made as the user's linked Booklore account
+	importCtx, err := b.bookloreContext(ctx, userID)
+	if err != nil {
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "Booklore account required"))
+		b.api.Send(tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, "❌ "+err.Error()))
+		return
+	}
+
 	if data == "import_all" {
+		// Only the files the notification listed, other new files may be uploads
+		// still waiting for their owner to choose a library
+		notice, ok := b.sessions.Get(chatID, callback.Message.MessageID).(*bookdropNotice)
+		if !ok {
+			b.api.Request(tgbotapi.NewCallback(callback.ID, "This notification has expired, use /bookdrop instead"))
+			return
+		}
+		listed := make(map[int64]bool, len(notice.fileIDs))
+		for _, fileID := range notice.fileIDs {
+			listed[fileID] = true
+		}
+
 		callbackResponse := tgbotapi.NewCallback(callback.ID, "Importing all new files...")
 		b.api.Request(callbackResponse)
 
@@ -1167,8 +1315,8 @@ func (b *Bot) handleImportCallback(callback *tgbotapi.CallbackQuery) {
 		editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, "📥 Importing all new files... This may take a moment.")
 		b.api.Send(editMsg)
 
-		// Get all new files
-		files, err := b.booklore.GetBookdropFiles(ctx, "NEW", 0, 100)
+		// Get the listed files that are still new
+		files, err := b.booklore.GetBookdropFiles(ctx, "NEW", 0, bookdropFetchSize)
 		if err != nil {
 			b.api.Request(tgbotapi.NewCallback(callback.ID, "Failed to get files"))
 			editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, fmt.Sprintf("❌ Failed to get files: %s", err.Error()))
@@ -1176,31 +1324,36 @@ func (b *Bot) handleImportCallback(callback *tgbotapi.CallbackQuery) {
 			return
 		}
 
-		if len(files.Content) == 0 {
-			editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, "📂 No new files found to import.")
-			b.api.Send(editMsg)
-			return
-		}
-
 		// Extract file IDs
-		fileIDs := make([]int64, len(files.Content))
-		for i, file := range files.Content {
-			fileIDs[i] = file.ID
+		var fileIDs []int64
+		for _, file := range files.Content {
+			if !listed[file.ID] {
+				continue
+			}
+			fileIDs = append(fileIDs, file.ID)
 			b.config.Logger.Info("Added file to import all",
 				zap.Int64("file_id", file.ID),
 				zap.String("file_name", file.FileName),
 				zap.String("file_status", file.Status))
 		}
 
+		if len(fileIDs) == 0 {
+			editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, "📂 The listed files are no longer new, nothing to import.")
+			b.api.Send(editMsg)
+			return
+		}
+		b.sessions.Delete(chatID, callback.Message.MessageID)
+
 		b.config.Logger.Info("Importing all files",
 			zap.Int("file_count", len(fileIDs)),
 			zap.Any("file_ids", fileIDs))
 
 		// Get library IDs for user
-		libraryID, pathID := b.getLibraryIDsForUser(userID)
+		libraryID, pathID := b.getLibraryIDsForUser(chatID, userID)
 
 		// Import all files
-		result, err := b.booklore.FinalizeImport(ctx, fileIDs, libraryID, pathID)
+		result, err := b.booklore.FinalizeImport(importCtx, fileIDs, libraryID, pathID)
+		b.recordImportAudit(userID, fileIDs, libraryID, pathID, nil, result, err)
 		if err != nil {
 			editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, fmt.Sprintf("❌ Import failed: %s", err.Error()))
 			b.api.Send(editMsg)
@@ -1239,10 +1392,11 @@ func (b *Bot) handleImportCallback(callback *tgbotapi.CallbackQuery) {
 		b.api.Send(editMsg)
 
 		// Get library IDs for user
-		libraryID, pathID := b.getLibraryIDsForUser(userID)
+		libraryID, pathID := b.getLibraryIDsForUser(chatID, userID)
 
 		// Import the specific file
-		result, err := b.booklore.FinalizeImport(ctx, []int64{fileID}, libraryID, pathID)
+		result, err := b.booklore.FinalizeImport(importCtx, []int64{fileID}, libraryID, pathID)
+		b.recordImportAudit(userID, []int64{fileID}, libraryID, pathID, nil, result, err)
 		if err != nil {
 			b.config.Logger.Error("Failed to import individual file",
 				zap.Int64("file_id", fileID),
@@ -1308,42 +1462,17 @@ func (b *Bot) getLibraryDetails(libraryID int64) (*booklore.Library, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	libraries, err := b.booklore.GetLibraries(ctx)
+	library, err := b.catalog.Library(ctx, libraryID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get libraries: %w", err)
-	}
-
-	for _, lib := range libraries {
-		if lib.ID == libraryID {
-			return &lib, nil
-		}
+		// The library may have been created after the catalog was cached
+		b.catalog.Invalidate()
+		library, err = b.catalog.Library(ctx, libraryID)
 	}
-
-	return nil, fmt.Errorf("library with ID %d not found", libraryID)
-}
-
-// getLibraryIDsForUser gets library and path IDs for a user (user MUST have configured library)
-func (b *Bot) getLibraryIDsForUser(userID int64) (string, string) {
-	pref := b.preferences.GetUserPreference(userID)
-
-	b.config.Logger.Info("Getting library IDs for user",
-		zap.Int64("user_id", userID),
-		zap.Bool("has_library", pref.HasLibrary()),
-		zap.Int64("pref_library_id", pref.GetLibraryID()),
-		zap.Int64("pref_path_id", pref.GetPathID()))
-
-	if pref.HasLibrary() {
-		libraryID := fmt.Sprintf("%d", pref.GetLibraryID())
-		pathID := fmt.Sprintf("%d", pref.GetPathID())
-		b.config.Logger.Info("Using user preferences",
-			zap.String("library_id", libraryID),
-			zap.String("path_id", pathID))
-		return libraryID, pathID
+	if err != nil {
+		return nil, fmt.Errorf("library with ID %d not found: %w", libraryID, err)
 	}
 
-	// No fallback - user must configure library
-	b.config.Logger.Info("User has no library configured")
-	return "", ""
+	return library, nil
 }
 
 // handlePathSelection presents path selection inline keyboard for a library
@@ -0,0 +1,337 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/brauni/booklore-tg-bot/internal/audit"
+	"github.com/brauni/booklore-tg-bot/internal/downloader"
+	"github.com/brauni/booklore-tg-bot/internal/history"
+	"github.com/brauni/booklore-tg-bot/internal/metrics"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// historyPageSize is the number of uploads shown per /history page
+const historyPageSize = 5
+
+// recordUpload stores a downloaded file in the upload history and returns the record ID
+func (b *Bot) recordUpload(message *tgbotapi.Message, fileID, originalName string, result *downloader.DownloadResult) int64 {
+	recordID := b.history.Add(history.Record{
+		UserID:         message.From.ID,
+		Username:       message.From.UserName,
+		ChatID:         message.Chat.ID,
+		OriginalName:   originalName,
+		SavedPath:      result.Path,
+		SHA256:         result.SHA256,
+		Size:           result.Size,
+		TelegramFileID: fileID,
+		MimeType:       uploadMimeType(message, originalName),
+	})
+
+	b.config.Logger.Debug("Recorded upload in history",
+		zap.Int64("record_id", recordID),
+		zap.Int64("user_id", message.From.ID),
+		zap.String("saved_path", result.Path))
+
+	return recordID
+}
+
+// uploadMimeType returns the MIME type Telegram reported for an upload, guessing from the name otherwise
+func uploadMimeType(message *tgbotapi.Message, originalName string) string {
+	switch {
+	case message.Document != nil && message.Document.MimeType != "":
+		return message.Document.MimeType
+	case message.Video != nil && message.Video.MimeType != "":
+		return message.Video.MimeType
+	case message.Audio != nil && message.Audio.MimeType != "":
+		return message.Audio.MimeType
+	case message.Photo != nil:
+		return "image/jpeg"
+	}
+	return mime.TypeByExtension(filepath.Ext(originalName))
+}
+
+// setUploadImportResult updates the import outcome of an upload, ignoring unknown records
+func (b *Bot) setUploadImportResult(recordID int64, status history.ImportStatus, message string) {
+	// Retries and deletions aren't import outcomes
+	if status != history.ImportPending && status != history.ImportDeleted {
+		metrics.ObserveImport(string(status))
+	}
+
+	if recordID == 0 {
+		return
+	}
+
+	if err := b.history.SetImportResult(recordID, status, message); err != nil {
+		b.config.Logger.Warn("Failed to update upload history",
+			zap.Int64("record_id", recordID),
+			zap.Error(err))
+	}
+}
+
+func (b *Bot) handleHistoryCommand(chatID int64, userID int64) {
+	text, markup := b.buildHistoryPage(userID, 0)
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	if markup != nil {
+		msg.ReplyMarkup = *markup
+	}
+	b.api.Send(msg)
+}
+
+// buildHistoryPage renders one page of the upload history visible to the user
+func (b *Bot) buildHistoryPage(userID int64, page int) (string, *tgbotapi.InlineKeyboardMarkup) {
+	isAdmin := b.auth.IsAdmin(userID)
+	filterUserID := userID
+	if isAdmin {
+		filterUserID = 0
+	}
+
+	records, total := b.history.List(filterUserID, page*historyPageSize, historyPageSize)
+	if total == 0 {
+		return "📜 No uploads recorded yet.\n\nSend me a file to get started!", nil
+	}
+
+	totalPages := (total + historyPageSize - 1) / historyPageSize
+	if page >= totalPages {
+		page = totalPages - 1
+		records, _ = b.history.List(filterUserID, page*historyPageSize, historyPageSize)
+	}
+
+	title := "📜 Your Uploads"
+	if isAdmin {
+		title = "📜 All Uploads"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s (page %d/%d, %d total)\n\n", title, page+1, totalPages, total))
+
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	for _, rec := range records {
+		sb.WriteString(fmt.Sprintf("#%d %s %s\n", rec.ID, importStatusEmoji(rec.ImportStatus), rec.OriginalName))
+		sb.WriteString(fmt.Sprintf("   📏 %.1f MB • 📅 %s\n",
+			float64(rec.Size)/1024/1024, rec.CreatedAt.Local().Format("2006-01-02 15:04")))
+		if isAdmin {
+			sb.WriteString(fmt.Sprintf("   👤 %s\n", formatUploader(rec)))
+		}
+		if rec.ImportMessage != "" {
+			sb.WriteString(fmt.Sprintf("   ℹ️ %s\n", truncateString(rec.ImportMessage, 120)))
+		}
+		sb.WriteString("\n")
+
+		var row []tgbotapi.InlineKeyboardButton
+		if rec.ImportStatus == history.ImportFailed || rec.ImportStatus == history.ImportSkipped {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("🔁 Retry #%d", rec.ID),
+				fmt.Sprintf("history_retry_%d", rec.ID)))
+		}
+		if rec.ImportStatus != history.ImportImported && rec.ImportStatus != history.ImportDeleted {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("🗑 Delete #%d", rec.ID),
+				fmt.Sprintf("history_delete_%d_%d", rec.ID, page)))
+		}
+		if len(row) > 0 {
+			keyboard = append(keyboard, row)
+		}
+	}
+
+	var navRow []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		navRow = append(navRow, tgbotapi.NewInlineKeyboardButtonData("⬅️ Prev", fmt.Sprintf("history_page_%d", page-1)))
+	}
+	if page < totalPages-1 {
+		navRow = append(navRow, tgbotapi.NewInlineKeyboardButtonData("Next ➡️", fmt.Sprintf("history_page_%d", page+1)))
+	}
+	if len(navRow) > 0 {
+		keyboard = append(keyboard, navRow)
+	}
+
+	if len(keyboard) == 0 {
+		return sb.String(), nil
+	}
+
+	markup := tgbotapi.NewInlineKeyboardMarkup(keyboard...)
+	return sb.String(), &markup
+}
+
+// handleHistoryCallback handles paging, retry and delete buttons of /history
+func (b *Bot) handleHistoryCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	userID := callback.From.ID
+	chatID := callback.Message.Chat.ID
+	messageID := callback.Message.MessageID
+	data := callback.Data
+
+	switch {
+	case strings.HasPrefix(data, "history_page_"):
+		var page int
+		if _, err := fmt.Sscanf(data, "history_page_%d", &page); err != nil || page < 0 {
+			b.api.Request(tgbotapi.NewCallback(callback.ID, "Invalid page"))
+			return
+		}
+
+		b.api.Request(tgbotapi.NewCallback(callback.ID, ""))
+		b.editHistoryPage(chatID, messageID, userID, page)
+
+	case strings.HasPrefix(data, "history_retry_"):
+		var recordID int64
+		if _, err := fmt.Sscanf(data, "history_retry_%d", &recordID); err != nil {
+			b.api.Request(tgbotapi.NewCallback(callback.ID, "Invalid upload ID"))
+			return
+		}
+
+		rec, ok := b.historyRecordForUser(callback, recordID)
+		if !ok {
+			return
+		}
+
+		if !b.booklore.IsEnabled() {
+			b.api.Request(tgbotapi.NewCallback(callback.ID, "Booklore integration is not enabled"))
+			return
+		}
+
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "Retrying import..."))
+		b.api.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("🔁 Retrying import of '%s'...", rec.OriginalName)))
+
+		b.setUploadImportResult(rec.ID, history.ImportPending, "")
+		status := b.importUpload(ctx, chatID, rec.UserID, filepath.Base(rec.SavedPath), rec.ID, b.recordImportTarget(rec))
+		if status == "" {
+			status = "ℹ️ Import skipped - no library configured. Use /set_library first."
+		}
+		b.api.Send(tgbotapi.NewMessage(chatID, status))
+
+	case strings.HasPrefix(data, "history_delete_"):
+		var recordID int64
+		var page int
+		if _, err := fmt.Sscanf(data, "history_delete_%d_%d", &recordID, &page); err != nil {
+			b.api.Request(tgbotapi.NewCallback(callback.ID, "Invalid upload ID"))
+			return
+		}
+
+		rec, ok := b.historyRecordForUser(callback, recordID)
+		if !ok {
+			return
+		}
+
+		err := b.deleteUploadFromBookdrop(rec)
+		b.recordAudit(userID, audit.ActionDiscard, fmt.Sprintf("upload:%d", rec.ID), map[string]string{"filename": filepath.Base(rec.SavedPath)}, err)
+		if err != nil {
+			b.config.Logger.Error("Failed to delete upload from bookdrop",
+				zap.Int64("record_id", rec.ID),
+				zap.String("path", rec.SavedPath),
+				zap.Error(err))
+			b.api.Request(tgbotapi.NewCallback(callback.ID, "Failed to delete file"))
+			return
+		}
+
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "File deleted from bookdrop"))
+		b.editHistoryPage(chatID, messageID, userID, page)
+	}
+}
+
+// historyRecordForUser loads a record and checks the user may act on it,
+// answering the callback when they may not
+func (b *Bot) historyRecordForUser(callback *tgbotapi.CallbackQuery, recordID int64) (history.Record, bool) {
+	rec, ok := b.history.Get(recordID)
+	if !ok {
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "Upload not found"))
+		return history.Record{}, false
+	}
+
+	if rec.UserID != callback.From.ID && !b.auth.IsAdmin(callback.From.ID) {
+		b.config.Logger.Warn("User tried to manage another user's upload",
+			zap.Int64("user_id", callback.From.ID),
+			zap.Int64("record_id", recordID))
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "You can only manage your own uploads"))
+		return history.Record{}, false
+	}
+
+	return rec, true
+}
+
+// deleteUploadFromBookdrop removes an uploaded file from the download folder
+func (b *Bot) deleteUploadFromBookdrop(rec history.Record) error {
+	if _, err := b.removeFromDownloadFolder(rec.SavedPath); err != nil {
+		return err
+	}
+
+	b.config.Logger.Info("Deleted upload from bookdrop",
+		zap.Int64("record_id", rec.ID),
+		zap.String("path", rec.SavedPath))
+
+	b.setUploadImportResult(rec.ID, history.ImportDeleted, "Deleted from bookdrop")
+
+	// Let Booklore notice the file is gone
+	if b.booklore.IsEnabled() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := b.booklore.RescanBookdrop(ctx); err != nil {
+			b.config.Logger.Warn("Failed to rescan bookdrop after delete",
+				zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+// removeFromDownloadFolder deletes a file if it lies inside the download folder.
+// It reports whether a file was actually removed.
+func (b *Bot) removeFromDownloadFolder(filePath string) (bool, error) {
+	// Only ever delete files inside the download folder
+	folder, err := filepath.Abs(b.downloader.GetDownloadFolder())
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve download folder: %w", err)
+	}
+	path, err := filepath.Abs(filePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve file path: %w", err)
+	}
+	if !strings.HasPrefix(path, folder+string(filepath.Separator)) {
+		return false, fmt.Errorf("file %s is outside the download folder", filePath)
+	}
+
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to remove file: %w", err)
+	}
+	return true, nil
+}
+
+func (b *Bot) editHistoryPage(chatID int64, messageID int, userID int64, page int) {
+	text, markup := b.buildHistoryPage(userID, page)
+
+	editMsg := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	if markup != nil {
+		editMsg.ReplyMarkup = markup
+	}
+	b.api.Send(editMsg)
+}
+
+func importStatusEmoji(status history.ImportStatus) string {
+	switch status {
+	case history.ImportImported:
+		return "✅"
+	case history.ImportFailed:
+		return "❌"
+	case history.ImportSkipped:
+		return "⏸️"
+	case history.ImportDeleted:
+		return "🗑"
+	default:
+		return "⏳"
+	}
+}
+
+func formatUploader(rec history.Record) string {
+	if rec.Username != "" {
+		return fmt.Sprintf("@%s (%d)", rec.Username, rec.UserID)
+	}
+	return fmt.Sprintf("%d", rec.UserID)
+}
@@ -0,0 +1,144 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brauni/booklore-tg-bot/internal/booklore"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// inlinePageSize is the number of results per inline answer (Telegram allows up to 50)
+	inlinePageSize = 20
+	// inlineCacheTime is how long Telegram may cache an inline answer, in seconds
+	inlineCacheTime = 30
+)
+
+// handleInlineQuery answers "@bot <query>" with matching library books
+func (b *Bot) handleInlineQuery(query *tgbotapi.InlineQuery) {
+	answer := tgbotapi.InlineConfig{
+		InlineQueryID: query.ID,
+		IsPersonal:    true,
+		CacheTime:     inlineCacheTime,
+		Results:       []interface{}{},
+	}
+
+	if !b.auth.IsUserAllowed(query.From.ID) {
+		answer.CacheTime = 0
+		answer.SwitchPMText = "🚫 Not authorized"
+		answer.SwitchPMParameter = "unauthorized"
+		b.answerInlineQuery(answer)
+		return
+	}
+
+	text := strings.TrimSpace(query.Query)
+	if !b.booklore.IsEnabled() || text == "" {
+		b.answerInlineQuery(answer)
+		return
+	}
+
+	page := 0
+	if query.Offset != "" {
+		if offset, err := strconv.Atoi(query.Offset); err == nil && offset > 0 {
+			page = offset
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Search as the user's own account, both errors ask them to link one
+	ctx, err := b.bookloreContext(ctx, query.From.ID)
+	if err != nil {
+		answer.CacheTime = 0
+		answer.SwitchPMText = "🔗 Link your Booklore account"
+		answer.SwitchPMParameter = "link"
+		b.answerInlineQuery(answer)
+		return
+	}
+
+	result, err := b.booklore.SearchBooks(ctx, booklore.BookSearch{
+		Query:      text,
+		LibraryIDs: b.auth.AllowedLibraries(query.From.ID),
+		Page:       page,
+		Size:       inlinePageSize,
+	})
+	if err != nil {
+		b.config.Logger.Error("Failed to search books for inline query",
+			zap.Int64("user_id", query.From.ID),
+			zap.Error(err))
+		answer.CacheTime = 0
+		b.answerInlineQuery(answer)
+		return
+	}
+
+	libraryNames := b.libraryNames(ctx)
+	for _, book := range result.Content {
+		answer.Results = append(answer.Results, b.inlineBookResult(&book, libraryNames))
+	}
+
+	if !result.Last {
+		answer.NextOffset = strconv.Itoa(page + 1)
+	}
+
+	b.config.Logger.Debug("Answering inline query",
+		zap.Int64("user_id", query.From.ID),
+		zap.String("query", text),
+		zap.Int("page", page),
+		zap.Int("results", len(answer.Results)))
+
+	b.answerInlineQuery(answer)
+}
+
+// inlineBookResult builds an inline result for a book, preferring an already uploaded document
+func (b *Bot) inlineBookResult(book *booklore.Book, libraryNames map[int64]string) interface{} {
+	resultID := fmt.Sprintf("book_%d", book.ID)
+	description := fmt.Sprintf("%s • %s", book.Format(), libraryName(libraryNames, book.LibraryID))
+	if authors := book.Authors(); authors != "" {
+		description = authors + " • " + description
+	}
+
+	if entry, ok := b.fileCache.Get(book.ID); ok {
+		doc := tgbotapi.NewInlineQueryResultCachedDocument(resultID, entry.FileID, book.Title())
+		doc.Description = description
+		doc.Caption = bookCaption(book)
+		return doc
+	}
+
+	// Not uploaded yet: share a summary with a deep link that makes the bot send the file
+	article := tgbotapi.NewInlineQueryResultArticle(resultID, book.Title(),
+		fmt.Sprintf("%s\n📄 %s", bookCaption(book), book.Format()))
+	article.Description = description
+	article.ThumbURL = b.thumbnailURL(book.ID)
+
+	deepLink := fmt.Sprintf("https://t.me/%s?start=book_%d", b.api.Self.UserName, book.ID)
+	markup := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonURL("📥 Get book", deepLink)),
+	)
+	article.ReplyMarkup = &markup
+
+	return article
+}
+
+// thumbnailURL returns the cover URL Telegram should fetch for inline results.
+// Telegram can't authenticate against Booklore, so without a public template
+// the results have no thumbnail.
+func (b *Bot) thumbnailURL(bookID int64) string {
+	if b.config.InlineThumbnailURL == "" {
+		return ""
+	}
+	return strings.ReplaceAll(b.config.InlineThumbnailURL, "{id}", strconv.FormatInt(bookID, 10))
+}
+
+func (b *Bot) answerInlineQuery(answer tgbotapi.InlineConfig) {
+	if _, err := b.api.Request(answer); err != nil {
+		b.config.Logger.Error("Failed to answer inline query",
+			zap.String("inline_query_id", answer.InlineQueryID),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,92 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/brauni/booklore-tg-bot/internal/audit"
+	"github.com/brauni/booklore-tg-bot/internal/booklore"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// staleNotices remembers which stale preferences users were told about,
+// so they are only notified once per preference
+type staleNotices struct {
+	notified map[int64]string
+	mutex    sync.Mutex
+}
+
+func newStaleNotices() *staleNotices {
+	return &staleNotices{notified: make(map[int64]string)}
+}
+
+// markNotified records a notice and reports whether it is new
+func (n *staleNotices) markNotified(userID int64, key string) bool {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if n.notified[userID] == key {
+		return false
+	}
+	n.notified[userID] = key
+	return true
+}
+
+// checkStalePreferences looks for preferences that point at libraries or paths
+// Booklore no longer has. It runs whenever the library catalog changes.
+func (b *Bot) checkStalePreferences(libraries []booklore.Library) {
+	for _, userID := range b.preferences.UserIDs() {
+		b.checkPreference(userID, libraries)
+	}
+}
+
+// checkPreference reports whether a user's preference still exists. Renamed libraries
+// and paths are updated; users whose preference is gone are notified.
+func (b *Bot) checkPreference(userID int64, libraries []booklore.Library) bool {
+	pref := b.preferences.GetUserPreference(userID)
+	if !pref.HasLibrary() {
+		return true
+	}
+
+	target, err := booklore.FindTarget(libraries, pref.GetLibraryID(), pref.GetPathID())
+	if err != nil && pref.GetPathID() == pref.GetLibraryID() {
+		// "Library Root" stores the library ID as the path, so only the library has to exist
+		target, err = booklore.FindTarget(libraries, pref.GetLibraryID(), 0)
+		if err == nil {
+			target.PathID = pref.GetPathID()
+			target.PathName = pref.GetPathName()
+		}
+	}
+	if err == nil {
+		if target.LibraryName != pref.GetLibraryName() || target.PathName != pref.GetPathName() {
+			b.preferences.SetUserPreference(userID, target.LibraryID, target.PathID, target.LibraryName, target.PathName)
+			b.recordAudit(audit.SystemActor, audit.ActionPreference, fmt.Sprintf("library:%d/path:%d", target.LibraryID, target.PathID), map[string]string{
+				"user":    strconv.FormatInt(userID, 10),
+				"library": target.LibraryName,
+				"path":    target.PathName,
+				"reason":  "renamed",
+			}, nil)
+		}
+		return true
+	}
+
+	b.config.Logger.Warn("User preference points at a missing library or path",
+		zap.Int64("user_id", userID),
+		zap.Int64("library_id", pref.GetLibraryID()),
+		zap.Int64("path_id", pref.GetPathID()),
+		zap.Error(err))
+
+	key := fmt.Sprintf("%d/%d", pref.GetLibraryID(), pref.GetPathID())
+	if b.staleNotices.markNotified(userID, key) {
+		text := fmt.Sprintf("⚠️ Your library '%s' (📁 %s) no longer exists in Booklore.\n\nUse /set_library to choose a new one.",
+			pref.GetLibraryName(), pref.GetPathName())
+		if _, err := b.api.Send(tgbotapi.NewMessage(userID, text)); err != nil {
+			b.config.Logger.Warn("Failed to notify user about stale library preference",
+				zap.Int64("user_id", userID),
+				zap.Error(err))
+		}
+	}
+	return false
+}
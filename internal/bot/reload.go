@@ -0,0 +1,88 @@
+package bot
+
+import (
+	"strings"
+
+	"github.com/brauni/booklore-tg-bot/internal/audit"
+	"github.com/brauni/booklore-tg-bot/internal/booklore"
+	"github.com/brauni/booklore-tg-bot/internal/config"
+	"github.com/brauni/booklore-tg-bot/internal/logging"
+	"go.uber.org/zap"
+)
+
+// settings returns the current configuration. Unlike b.config it reflects
+// reloads, so it is used for the settings that can change while the bot runs.
+func (b *Bot) settings() *config.Config {
+	return b.live.Load()
+}
+
+// ReloadConfig reads the configuration again and applies the settings that can
+// change while the bot runs: users, limits, file types, import targets and the
+// Booklore API token.
+// An invalid configuration is logged and the current one stays active.
+func (b *Bot) ReloadConfig() {
+	// SIGHUP and the file watchers may trigger reloads at the same time
+	b.reloadMutex.Lock()
+	defer b.reloadMutex.Unlock()
+
+	next, err := config.Read(b.config.File)
+	if err != nil {
+		b.config.Logger.Error("Failed to reload configuration, keeping the current one",
+			zap.Error(err))
+		b.recordAudit(audit.SystemActor, audit.ActionConfigReload, b.config.File, nil, err)
+		return
+	}
+
+	current := b.settings()
+	reloaded := current.ReloadedSettings(next)
+	if restart := current.RestartRequired(next); len(restart) > 0 {
+		b.config.Logger.Warn("Some changed settings only take effect after a restart",
+			zap.Strings("settings", restart))
+	}
+	if len(reloaded) == 0 {
+		b.config.Logger.Info("Configuration reloaded, nothing to apply")
+		return
+	}
+
+	// Redact the new secrets before anything uses them; the old ones stay
+	// redacted too, requests in flight may still log them
+	logging.SetSecrets(b.config.Logger, append(current.Secrets(), next.Secrets()...))
+
+	updated := current.WithReloaded(next)
+	b.setBookloreToken(current.BookloreAPI.APIToken, updated)
+	b.live.Store(updated)
+	b.auth.Update(updated.AllowedUserIDs, updated.AdminUserIDs, updated.LibraryAccess)
+	b.downloader.SetLimits(updated.AllowedFileTypes, updated.MaxFileSizeMB)
+
+	b.config.Logger.Info("Configuration reloaded",
+		zap.Strings("settings", reloaded))
+	b.recordAudit(audit.SystemActor, audit.ActionConfigReload, b.config.File, map[string]string{
+		"settings": strings.Join(reloaded, ","),
+	}, nil)
+}
+
+// setBookloreToken hands a rotated API token to the Booklore client and event stream,
+// which share the token provider
+func (b *Bot) setBookloreToken(previous string, updated *config.Config) {
+	token := updated.BookloreAPI.APIToken
+	if token == previous {
+		return
+	}
+
+	// Everything that depends on Booklore was set up at startup, so it can't be enabled or disabled now
+	if !b.config.BookloreAPI.Enabled || token == "" {
+		b.config.Logger.Warn("Enabling or disabling the Booklore integration requires a restart")
+		updated.BookloreAPI.APIToken = previous
+		return
+	}
+
+	static, ok := b.booklore.Auth().(*booklore.StaticToken)
+	if !ok {
+		b.config.Logger.Warn("Switching between an API token and a Booklore login requires a restart")
+		updated.BookloreAPI.APIToken = previous
+		return
+	}
+
+	static.SetToken(token)
+	b.config.Logger.Info("Booklore API token rotated")
+}
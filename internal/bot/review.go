@@ -0,0 +1,576 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brauni/booklore-tg-bot/internal/booklore"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// Review steps, in the order they are shown
+const (
+	reviewStepTitle = iota
+	reviewStepAuthors
+	reviewStepSeries
+	reviewStepCover
+	reviewStepConfirm
+)
+
+// reviewListSize is the number of bookdrop files offered by /review
+const reviewListSize = 10
+
+// reviewSession holds the metadata being edited for a bookdrop file
+type reviewSession struct {
+	userID      int64
+	fileID      int64
+	fileName    string
+	original    booklore.BookMetadata
+	fetched     booklore.BookMetadata
+	metadata    booklore.BookMetadata
+	step        int
+	coversShown bool
+}
+
+// reviewPicker remembers who opened a /review file list
+type reviewPicker struct {
+	userID int64
+}
+
+// reviewField returns the metadata field edited in a step
+func reviewField(step int) string {
+	switch step {
+	case reviewStepTitle:
+		return "title"
+	case reviewStepAuthors:
+		return "authors"
+	case reviewStepSeries:
+		return "series"
+	case reviewStepCover:
+		return "cover"
+	default:
+		return ""
+	}
+}
+
+func (b *Bot) handleReviewCommand(chatID int64, userID int64, args string) {
+	if !b.booklore.IsEnabled() {
+		msg := tgbotapi.NewMessage(chatID, "❌ Booklore integration is not enabled. Please configure the API token.")
+		b.api.Send(msg)
+		return
+	}
+
+	args = strings.TrimSpace(args)
+	if args != "" {
+		fileID, err := strconv.ParseInt(args, 10, 64)
+		if err != nil {
+			b.api.Send(tgbotapi.NewMessage(chatID, "📝 Usage: /review [bookdrop file ID]"))
+			return
+		}
+
+		sent, err := b.api.Send(tgbotapi.NewMessage(chatID, "🔄 Loading metadata..."))
+		if err != nil {
+			return
+		}
+		b.startReview(chatID, userID, sent.MessageID, fileID)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	files, err := b.booklore.GetBookdropFilesNoStatus(ctx, 0, reviewListSize)
+	if err != nil {
+		b.config.Logger.Error("Failed to get bookdrop files for review",
+			zap.Error(err))
+		b.sendErrorMessage(chatID, fmt.Sprintf("Failed to retrieve bookdrop files: %s", err.Error()))
+		return
+	}
+
+	if len(files.Content) == 0 {
+		msg := tgbotapi.NewMessage(chatID, "📂 No files waiting in the bookdrop.\n\n💡 Use /rescan to check for new files.")
+		b.api.Send(msg)
+		return
+	}
+
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	for _, file := range files.Content {
+		keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📝 "+truncateString(file.FileName, 40), fmt.Sprintf("review_file_%d", file.ID)),
+		))
+	}
+	keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("❌ Cancel", "review_cancel"),
+	))
+
+	msg := tgbotapi.NewMessage(chatID, "📝 Select a bookdrop file to review its metadata before importing:")
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
+	sent, err := b.api.Send(msg)
+	if err != nil {
+		return
+	}
+	b.sessions.Set(chatID, sent.MessageID, &reviewPicker{userID: userID})
+}
+
+// reviewOwner returns the user who opened the file list or review in a message
+func (b *Bot) reviewOwner(chatID int64, messageID int) (int64, bool) {
+	switch sess := b.sessions.Get(chatID, messageID).(type) {
+	case *reviewPicker:
+		return sess.userID, true
+	case *reviewSession:
+		return sess.userID, true
+	}
+	return 0, false
+}
+
+// startReview loads a bookdrop file and turns the given message into its review
+func (b *Bot) startReview(chatID int64, userID int64, messageID int, fileID int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	file, err := b.booklore.GetBookdropFile(ctx, fileID)
+	if err != nil {
+		b.config.Logger.Error("Failed to get bookdrop file for review",
+			zap.Int64("file_id", fileID),
+			zap.Error(err))
+		b.api.Send(tgbotapi.NewEditMessageText(chatID, messageID, fmt.Sprintf("❌ Failed to load bookdrop file: %s", err.Error())))
+		return
+	}
+
+	sess := &reviewSession{
+		userID:   userID,
+		fileID:   file.ID,
+		fileName: file.FileName,
+		metadata: file.ReviewMetadata(),
+	}
+	if file.OriginalMetadata != nil {
+		sess.original = *file.OriginalMetadata
+	}
+	if file.FetchedMetadata != nil {
+		sess.fetched = *file.FetchedMetadata
+	}
+
+	b.config.Logger.Info("Started bookdrop review",
+		zap.Int64("user_id", userID),
+		zap.Int64("file_id", file.ID),
+		zap.Bool("has_fetched_metadata", file.FetchedMetadata != nil))
+
+	b.sessions.Set(chatID, messageID, sess)
+	b.showReviewStep(chatID, messageID, sess)
+}
+
+// handleReviewCallback handles the buttons of the review conversation
+func (b *Bot) handleReviewCallback(callback *tgbotapi.CallbackQuery) {
+	userID := callback.From.ID
+	chatID := callback.Message.Chat.ID
+	messageID := callback.Message.MessageID
+	data := callback.Data
+
+	// The file list and the review itself both belong to the user who ran /review
+	if owner, ok := b.reviewOwner(chatID, messageID); ok && owner != userID {
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "This review belongs to another user"))
+		return
+	}
+
+	if data == "review_cancel" {
+		b.sessions.Delete(chatID, messageID)
+		b.prompts.Clear(userID)
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "Review cancelled"))
+		b.api.Send(tgbotapi.NewEditMessageText(chatID, messageID, "❌ Review cancelled"))
+		return
+	}
+
+	if strings.HasPrefix(data, "review_file_") {
+		var fileID int64
+		if _, err := fmt.Sscanf(data, "review_file_%d", &fileID); err != nil {
+			b.api.Request(tgbotapi.NewCallback(callback.ID, "Invalid file ID"))
+			return
+		}
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "Loading metadata..."))
+		b.startReview(chatID, userID, messageID, fileID)
+		return
+	}
+
+	sess, ok := b.sessions.Get(chatID, messageID).(*reviewSession)
+	if !ok {
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "Review expired, please start again with /review"))
+		return
+	}
+	if sess.userID != userID {
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "This review belongs to another user"))
+		return
+	}
+
+	switch {
+	case data == "review_next" && sess.step < reviewStepConfirm:
+		sess.step++
+	case data == "review_back" && sess.step > reviewStepTitle:
+		sess.step--
+	case data == "review_use_original":
+		applyReviewSource(&sess.metadata, &sess.original, sess.step)
+	case data == "review_use_fetched":
+		applyReviewSource(&sess.metadata, &sess.fetched, sess.step)
+	case data == "review_edit":
+		field := reviewField(sess.step)
+		if field == "" || field == "cover" {
+			b.api.Request(tgbotapi.NewCallback(callback.ID, ""))
+			return
+		}
+		b.prompts.Set(userID, chatID, messageID, field)
+		b.api.Request(tgbotapi.NewCallback(callback.ID, ""))
+		b.api.Send(tgbotapi.NewMessage(chatID, reviewPromptText(field)))
+		return
+	case data == "review_confirm":
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "Importing..."))
+		b.finalizeReview(chatID, messageID, sess)
+		return
+	}
+
+	b.api.Request(tgbotapi.NewCallback(callback.ID, ""))
+	b.showReviewStep(chatID, messageID, sess)
+}
+
+// handleReviewInput applies a typed value to the review it was requested for
+func (b *Bot) handleReviewInput(message *tgbotapi.Message, prompt textPrompt) {
+	chatID := message.Chat.ID
+
+	sess, ok := b.sessions.Get(chatID, prompt.messageID).(*reviewSession)
+	if !ok || sess.userID != message.From.ID {
+		b.api.Send(tgbotapi.NewMessage(chatID, "⌛ This review has expired, please start again with /review"))
+		return
+	}
+
+	if err := setReviewField(&sess.metadata, prompt.field, message.Text); err != nil {
+		b.prompts.Set(message.From.ID, chatID, prompt.messageID, prompt.field)
+		b.sendErrorMessage(chatID, err.Error())
+		return
+	}
+
+	b.showReviewStep(chatID, prompt.messageID, sess)
+	b.api.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("✅ %s updated.", reviewFieldLabel(prompt.field))))
+}
+
+// showReviewStep renders the current step of a review into its message
+func (b *Bot) showReviewStep(chatID int64, messageID int, sess *reviewSession) {
+	if sess.step == reviewStepCover && !sess.coversShown {
+		sess.coversShown = true
+		b.sendReviewCovers(chatID, sess)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📝 Review: %s\n", sess.fileName))
+	sb.WriteString(fmt.Sprintf("Step %d/%d\n\n", sess.step+1, reviewStepConfirm+1))
+
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+
+	if sess.step == reviewStepConfirm {
+		sb.WriteString("Please check the metadata before importing:\n\n")
+		for step := reviewStepTitle; step < reviewStepConfirm; step++ {
+			field := reviewField(step)
+			sb.WriteString(fmt.Sprintf("%s %s: %s\n", reviewFieldEmoji(field), reviewFieldLabel(field), reviewValue(&sess.metadata, field)))
+		}
+
+		keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Import", "review_confirm"),
+		))
+	} else {
+		field := reviewField(sess.step)
+		sb.WriteString(fmt.Sprintf("%s %s\n\n", reviewFieldEmoji(field), reviewFieldLabel(field)))
+		sb.WriteString(fmt.Sprintf("➡️ Current: %s\n", reviewValue(&sess.metadata, field)))
+		sb.WriteString(fmt.Sprintf("📄 From file: %s\n", reviewValue(&sess.original, field)))
+		sb.WriteString(fmt.Sprintf("🌐 Fetched: %s\n", reviewValue(&sess.fetched, field)))
+
+		var sourceRow []tgbotapi.InlineKeyboardButton
+		// The cover embedded in the file can always be chosen
+		if reviewHasValue(&sess.original, field) || field == "cover" {
+			sourceRow = append(sourceRow, tgbotapi.NewInlineKeyboardButtonData("📄 Use from file", "review_use_original"))
+		}
+		if reviewHasValue(&sess.fetched, field) {
+			sourceRow = append(sourceRow, tgbotapi.NewInlineKeyboardButtonData("🌐 Use fetched", "review_use_fetched"))
+		}
+		if len(sourceRow) > 0 {
+			keyboard = append(keyboard, sourceRow)
+		}
+		if field != "cover" {
+			keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("✏️ Edit", "review_edit"),
+			))
+		}
+	}
+
+	var navRow []tgbotapi.InlineKeyboardButton
+	if sess.step > reviewStepTitle {
+		navRow = append(navRow, tgbotapi.NewInlineKeyboardButtonData("⬅️ Back", "review_back"))
+	}
+	if sess.step < reviewStepConfirm {
+		navRow = append(navRow, tgbotapi.NewInlineKeyboardButtonData("Next ➡️", "review_next"))
+	}
+	keyboard = append(keyboard, navRow)
+	keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("❌ Cancel", "review_cancel"),
+	))
+
+	editMsg := tgbotapi.NewEditMessageText(chatID, messageID, sb.String())
+	markup := tgbotapi.NewInlineKeyboardMarkup(keyboard...)
+	editMsg.ReplyMarkup = &markup
+	b.api.Send(editMsg)
+}
+
+// sendReviewCovers shows the cover embedded in the file and the fetched cover side by side
+func (b *Bot) sendReviewCovers(chatID int64, sess *reviewSession) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cover, err := b.booklore.DownloadBookdropCover(ctx, sess.fileID)
+	if err != nil {
+		b.config.Logger.Debug("No cover in bookdrop file",
+			zap.Int64("file_id", sess.fileID),
+			zap.Error(err))
+	} else {
+		photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileReader{Name: "cover.jpg", Reader: cover})
+		photo.Caption = "📄 Cover from file"
+		if _, err := b.api.Send(photo); err != nil {
+			b.config.Logger.Warn("Failed to send bookdrop cover",
+				zap.Int64("file_id", sess.fileID),
+				zap.Error(err))
+		}
+		cover.Close()
+	}
+
+	if sess.fetched.ThumbnailURL != "" {
+		photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileURL(sess.fetched.ThumbnailURL))
+		photo.Caption = "🌐 Fetched cover"
+		if _, err := b.api.Send(photo); err != nil {
+			b.config.Logger.Warn("Failed to send fetched cover",
+				zap.Int64("file_id", sess.fileID),
+				zap.Error(err))
+		}
+	}
+}
+
+// finalizeReview imports the reviewed file with the edited metadata
+func (b *Bot) finalizeReview(chatID int64, messageID int, sess *reviewSession) {
+	libraryIDStr, pathIDStr := b.getLibraryIDsForUser(chatID, sess.userID)
+	if libraryIDStr == "" {
+		b.api.Send(tgbotapi.NewEditMessageText(chatID, messageID,
+			"📚 Library configuration required\n\nUse /set_library to choose where books are imported, then run /review again."))
+		b.sessions.Delete(chatID, messageID)
+		return
+	}
+	libraryID, _ := strconv.ParseInt(libraryIDStr, 10, 64)
+	pathID, _ := strconv.ParseInt(pathIDStr, 10, 64)
+
+	b.api.Send(tgbotapi.NewEditMessageText(chatID, messageID, "📥 Importing with reviewed metadata..."))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	metadata := sess.metadata
+	var result *booklore.BookdropFinalizeResult
+	ctx, err := b.bookloreContext(ctx, sess.userID)
+	if err == nil {
+		result, err = b.booklore.FinalizeImportWithMetadata(ctx, []booklore.BookdropFinalizeFile{
+			{
+				FileID:    sess.fileID,
+				LibraryID: libraryID,
+				PathID:    pathID,
+				Metadata:  &metadata,
+			},
+		}, libraryID, pathID)
+	}
+	b.recordImportAudit(sess.userID, []int64{sess.fileID}, libraryIDStr, pathIDStr, map[string]string{"reviewed": "true"}, result, err)
+	if err != nil {
+		b.config.Logger.Error("Failed to import reviewed file",
+			zap.Int64("file_id", sess.fileID),
+			zap.Error(err))
+		// Keep the session so the user can go back and retry
+		editMsg := tgbotapi.NewEditMessageText(chatID, messageID, fmt.Sprintf("❌ Import failed: %s", err.Error()))
+		markup := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("🔄 Retry", "review_confirm"),
+				tgbotapi.NewInlineKeyboardButtonData("❌ Cancel", "review_cancel"),
+			),
+		)
+		editMsg.ReplyMarkup = &markup
+		b.api.Send(editMsg)
+		return
+	}
+
+	b.sessions.Delete(chatID, messageID)
+
+	b.config.Logger.Info("Reviewed file imported",
+		zap.Int64("user_id", sess.userID),
+		zap.Int64("file_id", sess.fileID),
+		zap.Int("imported_count", result.ImportedCount),
+		zap.Int("failed_count", result.FailedCount))
+
+	var text string
+	switch {
+	case result.ImportedCount > 0:
+		text = fmt.Sprintf("✅ '%s' imported successfully! 📚", metadata.Title)
+	case result.FailedCount > 0:
+		text = fmt.Sprintf("❌ Import of '%s' failed", metadata.Title)
+		if result.Message != "" {
+			text += ": " + result.Message
+		}
+	default:
+		text = "ℹ️ No files were imported (file may already be imported)"
+	}
+	b.api.Send(tgbotapi.NewEditMessageText(chatID, messageID, text))
+}
+
+// applyReviewSource copies the field of a step from one of the metadata sources
+func applyReviewSource(dst, src *booklore.BookMetadata, step int) {
+	switch step {
+	case reviewStepTitle:
+		dst.Title = src.Title
+	case reviewStepAuthors:
+		dst.Authors = src.Authors
+	case reviewStepSeries:
+		dst.SeriesName = src.SeriesName
+		dst.SeriesNumber = src.SeriesNumber
+	case reviewStepCover:
+		// An empty thumbnail URL keeps the cover embedded in the file
+		dst.ThumbnailURL = src.ThumbnailURL
+	}
+}
+
+// setReviewField parses typed input into a metadata field
+func setReviewField(md *booklore.BookMetadata, field, input string) error {
+	input = strings.TrimSpace(input)
+
+	switch field {
+	case "title":
+		if input == "" {
+			return fmt.Errorf("the title can't be empty")
+		}
+		md.Title = input
+
+	case "authors":
+		var authors []string
+		for _, author := range strings.Split(input, ",") {
+			if author = strings.TrimSpace(author); author != "" {
+				authors = append(authors, author)
+			}
+		}
+		if len(authors) == 0 {
+			return fmt.Errorf("please enter at least one author")
+		}
+		md.Authors = authors
+
+	case "series":
+		if input == "-" {
+			md.SeriesName = ""
+			md.SeriesNumber = 0
+			return nil
+		}
+
+		name, number, found := strings.Cut(input, "#")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return fmt.Errorf("the series name can't be empty")
+		}
+
+		var seriesNumber float64
+		if found {
+			n, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+			if err != nil {
+				return fmt.Errorf("invalid series number '%s'", strings.TrimSpace(number))
+			}
+			seriesNumber = n
+		}
+		md.SeriesName = name
+		md.SeriesNumber = seriesNumber
+
+	default:
+		return fmt.Errorf("field '%s' can't be edited", field)
+	}
+
+	return nil
+}
+
+func reviewPromptText(field string) string {
+	switch field {
+	case "authors":
+		return "✍️ Send the authors, separated by commas:"
+	case "series":
+		return "📚 Send the series as \"Name #Number\", or \"-\" to remove it:"
+	default:
+		return "📖 Send the new title:"
+	}
+}
+
+// reviewValue formats a metadata field for display
+func reviewValue(md *booklore.BookMetadata, field string) string {
+	if !reviewHasValue(md, field) {
+		if field == "cover" {
+			return "cover from file"
+		}
+		return "—"
+	}
+
+	switch field {
+	case "title":
+		return md.Title
+	case "authors":
+		return strings.Join(md.Authors, ", ")
+	case "series":
+		if md.SeriesNumber > 0 {
+			return fmt.Sprintf("%s #%s", md.SeriesName, strconv.FormatFloat(md.SeriesNumber, 'f', -1, 64))
+		}
+		return md.SeriesName
+	case "cover":
+		return "fetched cover"
+	default:
+		return ""
+	}
+}
+
+func reviewHasValue(md *booklore.BookMetadata, field string) bool {
+	switch field {
+	case "title":
+		return md.Title != ""
+	case "authors":
+		return len(md.Authors) > 0
+	case "series":
+		return md.SeriesName != ""
+	case "cover":
+		return md.ThumbnailURL != ""
+	default:
+		return false
+	}
+}
+
+func reviewFieldLabel(field string) string {
+	switch field {
+	case "title":
+		return "Title"
+	case "authors":
+		return "Authors"
+	case "series":
+		return "Series"
+	case "cover":
+		return "Cover"
+	default:
+		return field
+	}
+}
+
+func reviewFieldEmoji(field string) string {
+	switch field {
+	case "title":
+		return "📖"
+	case "authors":
+		return "✍️"
+	case "series":
+		return "📚"
+	case "cover":
+		return "🖼️"
+	default:
+		return "📄"
+	}
+}
@@ -0,0 +1,159 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"path/filepath"
+	"strings"
+
+	"github.com/brauni/booklore-tg-bot/internal/booklore"
+	"github.com/brauni/booklore-tg-bot/internal/routing"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// routeUpload picks an import target for an upload from the routing rules, with
+// the metadata of its bookdrop entry if the file is listed.
+// It returns nil when no rule matches or the matched target can't be used.
+func (b *Bot) routeUpload(ctx context.Context, chatID int64, userID int64, filename string, recordID int64, bookdropFileID int64) *booklore.ImportTarget {
+	in := routing.Input{
+		FileName: filename,
+		MIMEType: mime.TypeByExtension(filepath.Ext(filename)),
+		UserID:   userID,
+		ChatID:   chatID,
+	}
+
+	rec, hasRecord := b.history.Get(recordID)
+	if hasRecord && rec.MimeType != "" {
+		in.MIMEType = rec.MimeType
+	}
+
+	// Language and series come from the metadata Booklore extracted from the file
+	if bookdropFileID != 0 {
+		file, err := b.booklore.GetBookdropFile(ctx, bookdropFileID)
+		if err != nil {
+			b.config.Logger.Debug("Failed to get bookdrop file metadata for routing",
+				zap.Int64("file_id", bookdropFileID),
+				zap.Error(err))
+		} else {
+			metadata := file.ReviewMetadata()
+			in.Language = metadata.Language
+			in.Series = metadata.SeriesName
+		}
+	}
+
+	decision, ok := b.router.Route(in)
+	if !ok {
+		return nil
+	}
+
+	target, err := b.resolveImportTarget(userID, decision.Target)
+	if err != nil {
+		b.config.Logger.Warn("Routing rule target can't be used, falling back to the user's library",
+			zap.String("rule", decision.Rule),
+			zap.String("target", decision.Target.String()),
+			zap.String("filename", filename),
+			zap.Int64("user_id", userID),
+			zap.Error(err))
+		return nil
+	}
+
+	b.config.Logger.Info("Routed upload",
+		zap.String("rule", decision.Rule),
+		zap.String("filename", filename),
+		zap.Int64("library_id", target.LibraryID),
+		zap.Int64("path_id", target.PathID))
+	return target
+}
+
+// handleRouteCommand shows where an upload would be imported without importing anything
+func (b *Bot) handleRouteCommand(chatID int64, userID int64, args string) {
+	if b.router == nil {
+		b.api.Send(tgbotapi.NewMessage(chatID, "ℹ️ No routing rules are configured.\n\nSet BOOKLORE_ROUTING_RULES to a rules file to route uploads automatically."))
+		return
+	}
+
+	in, ok := parseRouteArgs(args)
+	if !ok {
+		b.api.Send(tgbotapi.NewMessage(chatID, b.routeRulesSummary()))
+		return
+	}
+	in.UserID = userID
+	in.ChatID = chatID
+	in.MIMEType = mime.TypeByExtension(filepath.Ext(in.FileName))
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "🧭 Route for '%s'\n\n", in.FileName)
+	if in.MIMEType != "" {
+		fmt.Fprintf(&text, "Type: %s\n", in.MIMEType)
+	}
+	if in.Language != "" {
+		fmt.Fprintf(&text, "Language: %s\n", in.Language)
+	}
+	if in.Series != "" {
+		fmt.Fprintf(&text, "Series: %s\n", in.Series)
+	}
+
+	decision, matched := b.router.Route(in)
+	if matched {
+		fmt.Fprintf(&text, "\n✅ Matches rule '%s'\n", decision.Rule)
+		if b.booklore.IsEnabled() {
+			target, err := b.resolveImportTarget(userID, decision.Target)
+			if err != nil {
+				fmt.Fprintf(&text, "⚠️ Target %s can't be used: %s\nThe upload would go to your default library instead.", decision.Target, err)
+			} else {
+				fmt.Fprintf(&text, "📚 Would be imported into %s", target)
+			}
+		} else {
+			fmt.Fprintf(&text, "📚 Target: %s", decision.Target)
+		}
+	} else {
+		text.WriteString("\n❌ No rule matches, the upload would go to your default library.")
+	}
+
+	b.api.Send(tgbotapi.NewMessage(chatID, text.String()))
+}
+
+// parseRouteArgs parses "<filename> [lang:<code>] [series:<name>]"
+func parseRouteArgs(args string) (routing.Input, bool) {
+	var in routing.Input
+	var name, series []string
+	inSeries := false
+
+	for _, word := range strings.Fields(args) {
+		lower := strings.ToLower(word)
+		switch {
+		case strings.HasPrefix(lower, "lang:"):
+			in.Language = word[len("lang:"):]
+			inSeries = false
+		case strings.HasPrefix(lower, "series:"):
+			series = append(series, word[len("series:"):])
+			inSeries = true
+		case inSeries:
+			series = append(series, word)
+		default:
+			name = append(name, word)
+		}
+	}
+
+	in.FileName = strings.Join(name, " ")
+	in.Series = strings.TrimSpace(strings.Join(series, " "))
+	return in, in.FileName != ""
+}
+
+// routeRulesSummary lists the active routing rules
+func (b *Bot) routeRulesSummary() string {
+	rules := b.router.Rules()
+
+	var text strings.Builder
+	text.WriteString("🧭 Routing rules\n\n")
+	if len(rules) == 0 {
+		fmt.Fprintf(&text, "No rules are active. Add some to %s.\n", b.router.Path())
+	}
+	for i, rule := range rules {
+		fmt.Fprintf(&text, "%d. %s → %s\n", i+1, rule.Name, rule.Target)
+	}
+	text.WriteString("\nUsage: /route <filename> [lang:<code>] [series:<name>]\nExample: /route Asterix 01.cbz")
+	return text.String()
+}
@@ -0,0 +1,198 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/brauni/booklore-tg-bot/internal/booklore"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// searchPageSize is the number of books shown per /search page
+const searchPageSize = 5
+
+// searchSession holds the query behind a search results message
+type searchSession struct {
+	query string
+}
+
+func (b *Bot) handleSearchCommand(chatID int64, userID int64, query string) {
+	if !b.booklore.IsEnabled() {
+		msg := tgbotapi.NewMessage(chatID, "❌ Booklore integration is not enabled.")
+		b.api.Send(msg)
+		return
+	}
+
+	query = strings.TrimSpace(query)
+	if query == "" {
+		msg := tgbotapi.NewMessage(chatID, "🔎 Usage: /search <title, author, series or ISBN>")
+		b.api.Send(msg)
+		return
+	}
+
+	// Send typing indicator
+	action := tgbotapi.NewChatAction(chatID, "typing")
+	b.api.Send(action)
+
+	text, markup, err := b.buildSearchPage(userID, query, 0)
+	if err != nil {
+		b.config.Logger.Error("Failed to search library",
+			zap.String("query", query),
+			zap.Error(err))
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Failed to search library: %s", err.Error()))
+		b.api.Send(msg)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	if markup != nil {
+		msg.ReplyMarkup = *markup
+	}
+	sent, err := b.api.Send(msg)
+	if err != nil {
+		b.config.Logger.Error("Failed to send search results",
+			zap.Error(err))
+		return
+	}
+
+	b.sessions.Set(chatID, sent.MessageID, &searchSession{query: query})
+}
+
+// handleSearchCallback handles the paging buttons of /search results
+func (b *Bot) handleSearchCallback(callback *tgbotapi.CallbackQuery) {
+	userID := callback.From.ID
+	chatID := callback.Message.Chat.ID
+	messageID := callback.Message.MessageID
+
+	var page int
+	if _, err := fmt.Sscanf(callback.Data, "search_page_%d", &page); err != nil || page < 0 {
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "Invalid page"))
+		return
+	}
+
+	sess, ok := b.sessions.Get(chatID, messageID).(*searchSession)
+	if !ok {
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "Search expired, please search again"))
+		return
+	}
+
+	b.api.Request(tgbotapi.NewCallback(callback.ID, ""))
+
+	text, markup, err := b.buildSearchPage(userID, sess.query, page)
+	if err != nil {
+		b.config.Logger.Error("Failed to search library",
+			zap.String("query", sess.query),
+			zap.Error(err))
+		text = fmt.Sprintf("❌ Failed to search library: %s", err.Error())
+		markup = nil
+	}
+
+	editMsg := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	if markup != nil {
+		editMsg.ReplyMarkup = markup
+	}
+	b.api.Send(editMsg)
+}
+
+// buildSearchPage renders one page of search results for a query
+func (b *Bot) buildSearchPage(userID int64, query string, page int) (string, *tgbotapi.InlineKeyboardMarkup, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	ctx, err := b.bookloreContext(ctx, userID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	result, err := b.booklore.SearchBooks(ctx, booklore.BookSearch{
+		Query:      query,
+		LibraryIDs: b.auth.AllowedLibraries(userID),
+		Page:       page,
+		Size:       searchPageSize,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	if result.TotalElements == 0 {
+		return fmt.Sprintf("🔎 No books found for \"%s\".", query), nil, nil
+	}
+
+	libraryNames := b.libraryNames(ctx)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🔎 Results for \"%s\" (page %d/%d, %d books)\n\n",
+		query, result.Number+1, result.TotalPages, result.TotalElements))
+
+	for i, book := range result.Content {
+		sb.WriteString(fmt.Sprintf("%d. %s\n", page*searchPageSize+i+1, book.Title()))
+		if authors := book.Authors(); authors != "" {
+			sb.WriteString(fmt.Sprintf("   ✍️ %s\n", authors))
+		}
+		sb.WriteString(fmt.Sprintf("   🏛️ %s • 📄 %s • 🆔 %d\n\n",
+			libraryName(libraryNames, book.LibraryID), book.Format(), book.ID))
+	}
+
+	// One download button per result
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	var downloadRow []tgbotapi.InlineKeyboardButton
+	for i, book := range result.Content {
+		downloadRow = append(downloadRow, tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("⬇️ %d", page*searchPageSize+i+1),
+			fmt.Sprintf("book_get_%d", book.ID)))
+	}
+	keyboard = append(keyboard, downloadRow)
+
+	// One "send to device" button per result if e-mail delivery is enabled
+	if b.outbox != nil {
+		var sendRow []tgbotapi.InlineKeyboardButton
+		for i, book := range result.Content {
+			sendRow = append(sendRow, tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("📧 %d", page*searchPageSize+i+1),
+				fmt.Sprintf("send_book_%d", book.ID)))
+		}
+		keyboard = append(keyboard, sendRow)
+	}
+
+	var navRow []tgbotapi.InlineKeyboardButton
+	if !result.First {
+		navRow = append(navRow, tgbotapi.NewInlineKeyboardButtonData("⬅️ Prev", fmt.Sprintf("search_page_%d", page-1)))
+	}
+	if !result.Last {
+		navRow = append(navRow, tgbotapi.NewInlineKeyboardButtonData("Next ➡️", fmt.Sprintf("search_page_%d", page+1)))
+	}
+
+	if len(navRow) > 0 {
+		keyboard = append(keyboard, navRow)
+	}
+
+	markup := tgbotapi.NewInlineKeyboardMarkup(keyboard...)
+	return sb.String(), &markup, nil
+}
+
+// libraryNames maps library IDs to names, returning an empty map if libraries can't be fetched
+func (b *Bot) libraryNames(ctx context.Context) map[int64]string {
+	names := make(map[int64]string)
+
+	libraries, err := b.catalog.Libraries(ctx)
+	if err != nil {
+		b.config.Logger.Warn("Failed to get libraries for name lookup",
+			zap.Error(err))
+		return names
+	}
+
+	for _, lib := range libraries {
+		names[lib.ID] = lib.Name
+	}
+	return names
+}
+
+func libraryName(names map[int64]string, libraryID int64) string {
+	if name, ok := names[libraryID]; ok {
+		return name
+	}
+	return fmt.Sprintf("Library #%d", libraryID)
+}
@@ -0,0 +1,132 @@
+package bot
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// sessionTTL is how long interactive message state is kept after its last use
+const sessionTTL = 30 * time.Minute
+
+// sessionStore keeps server-side state for interactive messages, keyed by chat and message ID
+type sessionStore struct {
+	sessions map[string]*session
+	mutex    sync.Mutex
+}
+
+type session struct {
+	value    interface{}
+	lastUsed time.Time
+}
+
+func newSessionStore() *sessionStore {
+	return &sessionStore{
+		sessions: make(map[string]*session),
+	}
+}
+
+func sessionKey(chatID int64, messageID int) string {
+	return fmt.Sprintf("%d:%d", chatID, messageID)
+}
+
+// Set stores state for a message
+func (s *sessionStore) Set(chatID int64, messageID int, value interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.cleanup()
+	s.sessions[sessionKey(chatID, messageID)] = &session{
+		value:    value,
+		lastUsed: time.Now(),
+	}
+}
+
+// Get returns the state stored for a message, or nil if it expired
+func (s *sessionStore) Get(chatID int64, messageID int) interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	sess, ok := s.sessions[sessionKey(chatID, messageID)]
+	if !ok || time.Since(sess.lastUsed) > sessionTTL {
+		return nil
+	}
+
+	sess.lastUsed = time.Now()
+	return sess.value
+}
+
+// Delete removes the state stored for a message
+func (s *sessionStore) Delete(chatID int64, messageID int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.sessions, sessionKey(chatID, messageID))
+}
+
+// cleanup drops expired sessions; callers must hold the lock
+func (s *sessionStore) cleanup() {
+	for key, sess := range s.sessions {
+		if time.Since(sess.lastUsed) > sessionTTL {
+			delete(s.sessions, key)
+		}
+	}
+}
+
+// textPrompt is a pending request for the user to type a value for an interactive message
+type textPrompt struct {
+	chatID    int64
+	messageID int
+	field     string
+	createdAt time.Time
+}
+
+// promptStore tracks which users are expected to answer a text prompt, one per user
+type promptStore struct {
+	prompts map[int64]textPrompt
+	mutex   sync.Mutex
+}
+
+func newPromptStore() *promptStore {
+	return &promptStore{
+		prompts: make(map[int64]textPrompt),
+	}
+}
+
+// Set asks the user for a value, replacing any earlier prompt
+func (p *promptStore) Set(userID int64, chatID int64, messageID int, field string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.prompts[userID] = textPrompt{
+		chatID:    chatID,
+		messageID: messageID,
+		field:     field,
+		createdAt: time.Now(),
+	}
+}
+
+// Take returns and removes the user's pending prompt in a chat
+func (p *promptStore) Take(userID int64, chatID int64) (textPrompt, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	prompt, ok := p.prompts[userID]
+	if !ok || prompt.chatID != chatID {
+		return textPrompt{}, false
+	}
+	delete(p.prompts, userID)
+
+	if time.Since(prompt.createdAt) > sessionTTL {
+		return textPrompt{}, false
+	}
+	return prompt, true
+}
+
+// Clear drops the user's pending prompt
+func (p *promptStore) Clear(userID int64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	delete(p.prompts, userID)
+}
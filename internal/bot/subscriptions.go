@@ -0,0 +1,243 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brauni/booklore-tg-bot/internal/audit"
+	"github.com/brauni/booklore-tg-bot/internal/subscriptions"
+	"github.com/brauni/booklore-tg-bot/internal/watcher"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// Limits of a bookdrop notification
+const (
+	noticeMaxLines   = 10
+	noticeMaxButtons = 5
+)
+
+// heldNotices keeps bookdrop changes for users in their quiet hours
+type heldNotices struct {
+	changes map[int64][]watcher.Change
+	mutex   sync.Mutex
+}
+
+func newHeldNotices() *heldNotices {
+	return &heldNotices{changes: make(map[int64][]watcher.Change)}
+}
+
+// handleSubscribeCommand toggles bookdrop notifications and sets quiet hours
+func (b *Bot) handleSubscribeCommand(chatID int64, userID int64, args string) {
+	if b.watcher == nil {
+		b.api.Send(tgbotapi.NewMessage(chatID, "ℹ️ Bookdrop notifications are not enabled.\n\nSet BOOKDROP_WATCH=true to enable them."))
+		return
+	}
+
+	if !b.canManageBookdrop(userID) {
+		b.api.Send(tgbotapi.NewMessage(chatID, "⛔ Only admins can subscribe to bookdrop notifications."))
+		return
+	}
+
+	fields := strings.Fields(args)
+	switch {
+	case len(fields) == 0:
+		enabled := b.subscribers.Toggle(userID)
+		b.recordAudit(userID, audit.ActionSubscription, "", map[string]string{"enabled": strconv.FormatBool(enabled)}, nil)
+		if enabled {
+			b.api.Send(tgbotapi.NewMessage(chatID, "🔔 You'll be notified when files reach the bookdrop outside the bot.\n\n"+b.subscriptionSummary(userID)))
+		} else {
+			b.api.Send(tgbotapi.NewMessage(chatID, "🔕 Bookdrop notifications turned off."))
+		}
+
+	case strings.EqualFold(fields[0], "quiet") && len(fields) == 2 && strings.EqualFold(fields[1], "off"):
+		b.subscribers.SetQuietHours(userID, "")
+		b.recordAudit(userID, audit.ActionSubscription, "", map[string]string{"quiet": "off"}, nil)
+		b.api.Send(tgbotapi.NewMessage(chatID, "✅ Quiet hours removed.\n\n"+b.subscriptionSummary(userID)))
+
+	case strings.EqualFold(fields[0], "quiet") && len(fields) == 2:
+		quiet, err := subscriptions.ParseQuietHours(fields[1])
+		if err != nil {
+			b.api.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ %s\n\nExample: /subscribe quiet 22:00-07:00", err)))
+			return
+		}
+		b.subscribers.SetQuietHours(userID, quiet.String())
+		b.recordAudit(userID, audit.ActionSubscription, "", map[string]string{"quiet": quiet.String()}, nil)
+		b.api.Send(tgbotapi.NewMessage(chatID, "✅ Quiet hours set.\n\n"+b.subscriptionSummary(userID)))
+
+	default:
+		b.api.Send(tgbotapi.NewMessage(chatID, "Usage:\n/subscribe - Turn bookdrop notifications on or off\n/subscribe quiet 22:00-07:00 - Hold notifications during these hours\n/subscribe quiet off - Remove quiet hours"))
+	}
+}
+
+// subscriptionSummary describes a user's notification settings
+func (b *Bot) subscriptionSummary(userID int64) string {
+	sub := b.subscribers.Get(userID)
+
+	status := "🔕 Notifications: off"
+	if sub.Enabled {
+		status = "🔔 Notifications: on"
+	}
+
+	quiet := "🌙 Quiet hours: none"
+	if sub.QuietHours != "" {
+		quiet = fmt.Sprintf("🌙 Quiet hours: %s (held notifications are sent afterwards)", sub.QuietHours)
+	}
+
+	return status + "\n" + quiet
+}
+
+// notifyBookdropChanges passes bookdrop changes on to subscribers. Files uploaded
+// through the bot are left out, their uploaders already hear about them.
+func (b *Bot) notifyBookdropChanges(changes []watcher.Change) {
+	var external []watcher.Change
+	for _, change := range changes {
+		if _, uploaded := b.history.FindBySavedName(change.File.FileName); uploaded && change.Kind == watcher.ChangeNew {
+			continue
+		}
+		external = append(external, change)
+	}
+	if len(external) == 0 {
+		return
+	}
+
+	b.heldNotices.mutex.Lock()
+	for _, userID := range b.subscribers.Subscribers() {
+		if b.canManageBookdrop(userID) {
+			b.heldNotices.changes[userID] = append(b.heldNotices.changes[userID], external...)
+		}
+	}
+	b.heldNotices.mutex.Unlock()
+
+	b.flushBookdropNotices()
+}
+
+// flushBookdropNotices sends held changes to every user outside their quiet hours
+func (b *Bot) flushBookdropNotices() {
+	now := time.Now()
+
+	b.heldNotices.mutex.Lock()
+	ready := make(map[int64][]watcher.Change)
+	for userID, changes := range b.heldNotices.changes {
+		if b.subscribers.IsQuiet(userID, now) {
+			continue
+		}
+		ready[userID] = changes
+		delete(b.heldNotices.changes, userID)
+	}
+	b.heldNotices.mutex.Unlock()
+
+	for userID, changes := range ready {
+		// Users may have unsubscribed while their notifications were held
+		if !b.subscribers.Get(userID).Enabled {
+			continue
+		}
+
+		text, markup, newFileIDs := buildBookdropNotice(changes)
+		msg := tgbotapi.NewMessage(userID, text)
+		if markup != nil {
+			msg.ReplyMarkup = *markup
+		}
+		sent, err := b.api.Send(msg)
+		if err != nil {
+			b.config.Logger.Warn("Failed to send bookdrop notification",
+				zap.Int64("user_id", userID),
+				zap.Error(err))
+			continue
+		}
+
+		// "Import All New" imports only the files this notice lists
+		if len(newFileIDs) > 1 {
+			b.sessions.Set(userID, sent.MessageID, &bookdropNotice{fileIDs: newFileIDs})
+		}
+	}
+}
+
+// runHeldNotices sends held notifications once quiet hours end
+func (b *Bot) runHeldNotices(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.flushBookdropNotices()
+		}
+	}
+}
+
+// bookdropNotice remembers the new files a bookdrop notification listed
+type bookdropNotice struct {
+	fileIDs []int64
+}
+
+// buildBookdropNotice lists bookdrop changes with buttons to import the files
+// and returns the IDs of the new ones
+func buildBookdropNotice(changes []watcher.Change) (string, *tgbotapi.InlineKeyboardMarkup, []int64) {
+	// A file may have changed more than once while notifications were held; keep its latest state
+	latest := make(map[int64]int)
+	var unique []watcher.Change
+	for _, change := range changes {
+		if i, ok := latest[change.File.ID]; ok {
+			unique[i] = change
+			continue
+		}
+		latest[change.File.ID] = len(unique)
+		unique = append(unique, change)
+	}
+
+	var text strings.Builder
+	text.WriteString("📥 Bookdrop update\n\n")
+
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	var newFileIDs []int64
+	for i, change := range unique {
+		if change.Kind == watcher.ChangeNew {
+			newFileIDs = append(newFileIDs, change.File.ID)
+		}
+
+		if i < noticeMaxLines {
+			fmt.Fprintf(&text, "%s %s: %s\n", bookdropStatusEmoji(string(change.Kind)), changeLabel(change.Kind), truncateString(change.File.FileName, 60))
+		}
+
+		if len(keyboard) < noticeMaxButtons && change.Kind != watcher.ChangeFailed {
+			keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("📥 Import now: "+truncateString(change.File.FileName, 30), fmt.Sprintf("import_%d", change.File.ID)),
+			))
+		}
+	}
+	if len(unique) > noticeMaxLines {
+		fmt.Fprintf(&text, "…and %d more\n", len(unique)-noticeMaxLines)
+	}
+	text.WriteString("\n💡 Use /bookdrop to browse all files or /review to check metadata first.")
+
+	if len(newFileIDs) > 1 {
+		keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📥 Import All New", "import_all"),
+		))
+	}
+	if len(keyboard) == 0 {
+		return text.String(), nil, newFileIDs
+	}
+
+	markup := tgbotapi.NewInlineKeyboardMarkup(keyboard...)
+	return text.String(), &markup, newFileIDs
+}
+
+// changeLabel names a change kind for display
+func changeLabel(kind watcher.ChangeKind) string {
+	switch kind {
+	case watcher.ChangeFailed:
+		return "Failed"
+	case watcher.ChangePendingReview:
+		return "Pending review"
+	default:
+		return "New"
+	}
+}
@@ -0,0 +1,338 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/brauni/booklore-tg-bot/internal/booklore"
+	"github.com/brauni/booklore-tg-bot/internal/history"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// importDocument imports a downloaded document into the target named in its caption,
+// asks the user for a target, or falls back to the user's library
+func (b *Bot) importDocument(ctx context.Context, message *tgbotapi.Message, originalName, filename string, recordID int64) {
+	chatID := message.Chat.ID
+	userID := message.From.ID
+
+	var status string
+	switch {
+	case !b.booklore.IsEnabled() || !b.config.BookloreAPI.AutoImport:
+		status = b.triggerBookloreImport(ctx, chatID, userID, filename, recordID, nil)
+
+	default:
+		spec, found, err := b.captionTargetSpec(message.Caption)
+		var target *booklore.ImportTarget
+		if err == nil && found {
+			target, err = b.resolveImportTarget(userID, spec)
+		}
+
+		switch {
+		case err != nil:
+			b.config.Logger.Warn("Invalid import target in caption",
+				zap.Int64("user_id", userID),
+				zap.String("caption", message.Caption),
+				zap.Error(err))
+			b.setUploadImportResult(recordID, history.ImportSkipped, err.Error())
+			status = fmt.Sprintf("📥 File '%s' downloaded, but not imported: %s\n\n💡 Use /history to retry the import.", originalName, err.Error())
+		case target != nil:
+			status = b.triggerBookloreImport(ctx, chatID, userID, filename, recordID, target)
+		case b.config.BookloreAPI.PromptLibrary && recordID != 0:
+			if b.promptUploadTarget(chatID, userID, originalName, recordID) {
+				return
+			}
+			status = b.triggerBookloreImport(ctx, chatID, userID, filename, recordID, nil)
+		default:
+			status = b.triggerBookloreImport(ctx, chatID, userID, filename, recordID, nil)
+		}
+	}
+
+	// Prepare success message
+	if status == "" {
+		status = fmt.Sprintf("✅ File '%s' downloaded successfully!", originalName)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, status)
+	if markup := b.uploadDeliveryMarkup(recordID); markup != nil {
+		msg.ReplyMarkup = *markup
+	}
+	b.api.Send(msg)
+}
+
+// targetSpecStart returns where "lib:" starts a word in line, so that text such
+// as "mylib:" is not taken for a target, or -1 if it does not
+func targetSpecStart(line string) int {
+	previous := ' '
+	for i, r := range line {
+		if unicode.IsSpace(previous) && len(line)-i >= len("lib:") && strings.EqualFold(line[i:i+len("lib:")], "lib:") {
+			return i
+		}
+		previous = r
+	}
+	return -1
+}
+
+// captionSpecText returns the target spec at the start of text. The spec ends
+// at the first whitespace, so names with spaces have to be quoted, e.g.
+// lib:"My Books"/path:"Sci Fi"; the quotes are dropped.
+func captionSpecText(text string) string {
+	var spec strings.Builder
+	quoted := false
+	for _, r := range text {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case unicode.IsSpace(r) && !quoted:
+			return spec.String()
+		default:
+			spec.WriteRune(r)
+		}
+	}
+	return spec.String()
+}
+
+// captionTargetSpec finds an import target in a caption, either written out as
+// "lib:<library>/path:<path>" or as a configured hashtag such as "#comics"
+func (b *Bot) captionTargetSpec(caption string) (booklore.TargetSpec, bool, error) {
+	for _, line := range strings.Split(caption, "\n") {
+		if i := targetSpecStart(line); i >= 0 {
+			spec, err := booklore.ParseTargetSpec(captionSpecText(line[i:]))
+			return spec, true, err
+		}
+	}
+
+	for _, word := range strings.Fields(caption) {
+		if !strings.HasPrefix(word, "#") {
+			continue
+		}
+		tag := strings.ToLower(strings.TrimRight(word[1:], ".,;:!?"))
+		if target, ok := b.settings().BookloreAPI.CaptionTags[tag]; ok {
+			spec, err := booklore.ParseTargetSpec(target)
+			return spec, true, err
+		}
+	}
+
+	// Other hashtags are ordinary caption text
+	return booklore.TargetSpec{}, false, nil
+}
+
+// resolveImportTarget looks up the library and path a spec names and checks the user may use it
+func (b *Bot) resolveImportTarget(userID int64, spec booklore.TargetSpec) (*booklore.ImportTarget, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	libraries, err := b.catalog.Libraries(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get libraries: %w", err)
+	}
+
+	target, err := booklore.ResolveTarget(libraries, spec)
+	if err != nil {
+		return nil, err
+	}
+	if !b.auth.CanAccessLibrary(userID, target.LibraryID) {
+		return nil, fmt.Errorf("you don't have access to library '%s'", target.LibraryName)
+	}
+	return target, nil
+}
+
+// promptUploadTarget asks where to import an upload. It returns false if no picker could be shown.
+func (b *Bot) promptUploadTarget(chatID int64, userID int64, originalName string, recordID int64) bool {
+	markup, err := b.buildLibraryPicker(chatID, userID, recordID)
+	if err != nil {
+		b.config.Logger.Warn("Failed to build library picker, using default library",
+			zap.Int64("record_id", recordID),
+			zap.Error(err))
+		return false
+	}
+
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("✅ File '%s' downloaded successfully!\n\n📚 Where should it be imported?", originalName))
+	msg.ReplyMarkup = *markup
+	b.api.Send(msg)
+	return true
+}
+
+// buildLibraryPicker lists the libraries a user can import an upload into
+func (b *Bot) buildLibraryPicker(chatID int64, userID int64, recordID int64) (*tgbotapi.InlineKeyboardMarkup, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	libraries, err := b.catalog.Libraries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	for _, lib := range libraries {
+		if !b.auth.CanAccessLibrary(userID, lib.ID) {
+			continue
+		}
+		keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📚 "+lib.Name, fmt.Sprintf("upload_lib_%d_%d", recordID, lib.ID)),
+		))
+	}
+	if len(keyboard) == 0 {
+		return nil, fmt.Errorf("no libraries available")
+	}
+
+	var optionRow []tgbotapi.InlineKeyboardButton
+	if b.preferences.GetUserPreference(userID).HasLibrary() {
+		optionRow = append(optionRow, tgbotapi.NewInlineKeyboardButtonData("⭐ My library", fmt.Sprintf("upload_default_%d", recordID)))
+	} else if b.hasImportTarget(chatID, userID) {
+		optionRow = append(optionRow, tgbotapi.NewInlineKeyboardButtonData("⭐ Default library", fmt.Sprintf("upload_default_%d", recordID)))
+	}
+	optionRow = append(optionRow, tgbotapi.NewInlineKeyboardButtonData("⏭️ Don't import", fmt.Sprintf("upload_skip_%d", recordID)))
+	keyboard = append(keyboard, optionRow)
+
+	if delivery := b.uploadDeliveryMarkup(recordID); delivery != nil {
+		keyboard = append(keyboard, delivery.InlineKeyboard...)
+	}
+
+	markup := tgbotapi.NewInlineKeyboardMarkup(keyboard...)
+	return &markup, nil
+}
+
+// handleUploadTargetCallback handles the library and path picker shown after an upload
+func (b *Bot) handleUploadTargetCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	chatID := callback.Message.Chat.ID
+	messageID := callback.Message.MessageID
+	data := callback.Data
+
+	var recordID, libraryID, pathID int64
+	var err error
+	switch {
+	case strings.HasPrefix(data, "upload_lib_"):
+		_, err = fmt.Sscanf(data, "upload_lib_%d_%d", &recordID, &libraryID)
+	case strings.HasPrefix(data, "upload_path_"):
+		_, err = fmt.Sscanf(data, "upload_path_%d_%d_%d", &recordID, &libraryID, &pathID)
+	case strings.HasPrefix(data, "upload_default_"):
+		_, err = fmt.Sscanf(data, "upload_default_%d", &recordID)
+	case strings.HasPrefix(data, "upload_skip_"):
+		_, err = fmt.Sscanf(data, "upload_skip_%d", &recordID)
+	case strings.HasPrefix(data, "upload_back_"):
+		_, err = fmt.Sscanf(data, "upload_back_%d", &recordID)
+	default:
+		return
+	}
+	if err != nil {
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "Invalid selection"))
+		return
+	}
+
+	rec, ok := b.historyRecordForUser(callback, recordID)
+	if !ok {
+		return
+	}
+	if rec.ImportStatus != history.ImportPending {
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "This upload was already handled"))
+		return
+	}
+	if libraryID != 0 && !b.auth.CanAccessLibrary(callback.From.ID, libraryID) {
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "You don't have access to this library"))
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(data, "upload_skip_"):
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "Not imported"))
+		b.setUploadImportResult(rec.ID, history.ImportSkipped, "Import skipped by user")
+		b.editUploadMessage(chatID, messageID, rec.ID,
+			fmt.Sprintf("⏭️ '%s' was not imported.\n\n💡 Use /history to import it later.", rec.OriginalName))
+
+	case strings.HasPrefix(data, "upload_default_"):
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "Importing..."))
+		b.importUploadFromPicker(ctx, chatID, messageID, rec, nil)
+
+	case strings.HasPrefix(data, "upload_back_"):
+		markup, err := b.buildLibraryPicker(chatID, callback.From.ID, rec.ID)
+		if err != nil {
+			b.api.Request(tgbotapi.NewCallback(callback.ID, "Failed to load libraries"))
+			return
+		}
+		b.api.Request(tgbotapi.NewCallback(callback.ID, ""))
+		b.api.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, *markup))
+
+	default:
+		listCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+
+		libraries, err := b.catalog.Libraries(listCtx)
+		if err != nil {
+			b.api.Request(tgbotapi.NewCallback(callback.ID, "Failed to load libraries"))
+			return
+		}
+
+		// Ask for the path when the library has several and none was picked yet
+		if pathID == 0 {
+			for _, lib := range libraries {
+				if lib.ID != libraryID || len(lib.Paths) <= 1 {
+					continue
+				}
+
+				var keyboard [][]tgbotapi.InlineKeyboardButton
+				for _, p := range lib.Paths {
+					keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(
+						tgbotapi.NewInlineKeyboardButtonData("📁 "+p.Name, fmt.Sprintf("upload_path_%d_%d_%d", rec.ID, lib.ID, p.ID)),
+					))
+				}
+				keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(
+					tgbotapi.NewInlineKeyboardButtonData("⬅️ Back", fmt.Sprintf("upload_back_%d", rec.ID)),
+				))
+
+				b.api.Request(tgbotapi.NewCallback(callback.ID, ""))
+				b.api.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, tgbotapi.NewInlineKeyboardMarkup(keyboard...)))
+				return
+			}
+		}
+
+		target, err := booklore.FindTarget(libraries, libraryID, pathID)
+		if err != nil {
+			b.api.Request(tgbotapi.NewCallback(callback.ID, err.Error()))
+			return
+		}
+
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "Importing..."))
+		b.importUploadFromPicker(ctx, chatID, messageID, rec, target)
+	}
+}
+
+// importUploadFromPicker imports an upload into the picked target and reports the result in the picker message
+func (b *Bot) importUploadFromPicker(ctx context.Context, chatID int64, messageID int, rec history.Record, target *booklore.ImportTarget) {
+	destination := "your library"
+	if target != nil {
+		destination = target.String()
+	}
+	b.api.Send(tgbotapi.NewEditMessageText(chatID, messageID,
+		fmt.Sprintf("📥 Importing '%s' into %s...", rec.OriginalName, destination)))
+
+	status := b.importUpload(ctx, chatID, rec.UserID, filepath.Base(rec.SavedPath), rec.ID, target)
+	if status == "" {
+		status = "ℹ️ Import skipped - no library configured. Use /set_library first."
+	}
+	b.editUploadMessage(chatID, messageID, rec.ID, status)
+}
+
+// editUploadMessage replaces an upload message, keeping its "Send to device" button
+func (b *Bot) editUploadMessage(chatID int64, messageID int, recordID int64, text string) {
+	editMsg := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	if markup := b.uploadDeliveryMarkup(recordID); markup != nil {
+		editMsg.ReplyMarkup = markup
+	}
+	b.api.Send(editMsg)
+}
+
+// recordImportTarget returns the target an upload was sent to, or nil if it used the default
+func (b *Bot) recordImportTarget(rec history.Record) *booklore.ImportTarget {
+	if rec.TargetLibraryID == 0 {
+		return nil
+	}
+	return &booklore.ImportTarget{
+		LibraryID: rec.TargetLibraryID,
+		PathID:    rec.TargetPathID,
+	}
+}
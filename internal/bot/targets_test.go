@@ -0,0 +1,36 @@
+package bot
+
+import "testing"
+
+func TestTargetSpecStart(t *testing.T) {
+	tests := map[string]int{
+		"lib:Books/path:/books":      0,
+		"For you lib:Books":          8,
+		"Read\tLIB:Comics":           5,
+		"mylib:Books":                -1,
+		"see calib:x then lib:Books": 17,
+		"no target here":             -1,
+		"Übersetzung lib:Bücher":     13,
+		"":                           -1,
+	}
+	for line, want := range tests {
+		if got := targetSpecStart(line); got != want {
+			t.Errorf("targetSpecStart(%q) = %d, want %d", line, got, want)
+		}
+	}
+}
+
+func TestCaptionSpecText(t *testing.T) {
+	tests := map[string]string{
+		"lib:Comics":                         "lib:Comics",
+		"lib:Comics thanks!":                 "lib:Comics",
+		"lib:Comics/path:Manga\tfor you":     "lib:Comics/path:Manga",
+		`lib:"My Books" thanks`:              "lib:My Books",
+		`lib:"My Books"/path:"Sci Fi" enjoy`: "lib:My Books/path:Sci Fi",
+	}
+	for text, want := range tests {
+		if got := captionSpecText(text); got != want {
+			t.Errorf("captionSpecText(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
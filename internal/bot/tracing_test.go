@@ -0,0 +1,211 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/brauni/booklore-tg-bot/internal/config"
+	"github.com/brauni/booklore-tg-bot/internal/tracing"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap/zaptest"
+)
+
+const testBotToken = "123456:test-token"
+
+// redirectTransport sends requests for the Telegram API, which the bot API
+// library addresses by a fixed URL, to a test server
+type redirectTransport struct {
+	host string
+	next http.RoundTripper
+}
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host == "api.telegram.org" {
+		req = req.Clone(req.Context())
+		req.URL.Scheme = "http"
+		req.URL.Host = t.host
+	}
+	return t.next.RoundTrip(req)
+}
+
+// stubTelegram answers the Bot API methods an upload uses and serves the uploaded file
+func stubTelegram(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var result any
+		switch {
+		case r.URL.Path == "/file/bot"+testBotToken+"/documents/book.epub":
+			io.WriteString(w, "epub content")
+			return
+		case strings.HasSuffix(r.URL.Path, "/getMe"):
+			result = map[string]any{"id": 1, "is_bot": true, "username": "test_bot"}
+		case strings.HasSuffix(r.URL.Path, "/getFile"):
+			result = map[string]any{"file_id": "file-1", "file_path": "documents/book.epub"}
+		default:
+			result = map[string]any{"message_id": 1, "date": 0, "chat": map[string]any{"id": 42, "type": "private"}}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": result})
+	}))
+}
+
+// stubBooklore imports a bookdrop file on the second finalize approach and
+// records the trace context of every request
+type stubBooklore struct {
+	mutex        sync.Mutex
+	traceparents []string
+	finalizes    int
+}
+
+func (s *stubBooklore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	s.traceparents = append(s.traceparents, r.Header.Get("traceparent"))
+	s.mutex.Unlock()
+
+	switch {
+	case r.URL.Path == "/api/v1/bookdrop/rescan":
+	case r.URL.Path == "/api/v1/bookdrop/files":
+		json.NewEncoder(w).Encode(map[string]any{
+			"content":       []map[string]any{{"id": 5, "fileName": "book.epub", "status": "NEW"}},
+			"totalElements": 1,
+			"totalPages":    1,
+			"first":         true,
+			"last":          true,
+		})
+	case r.URL.Path == "/api/v1/bookdrop/imports/finalize":
+		s.mutex.Lock()
+		s.finalizes++
+		s.mutex.Unlock()
+
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), `"fileIds"`) {
+			// Nothing imported, so the client tries the next approach
+			io.WriteString(w, `{"importedCount":0,"failedCount":0}`)
+			return
+		}
+		io.WriteString(w, `{"importedCount":1,"failedCount":0,"success":true}`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestUploadTrace(t *testing.T) {
+	telegram := stubTelegram(t)
+	defer telegram.Close()
+	bookloreStub := &stubBooklore{}
+	booklore := httptest.NewServer(bookloreStub)
+	defer booklore.Close()
+
+	telegramURL, _ := url.Parse(telegram.URL)
+	defaultTransport := http.DefaultTransport
+	http.DefaultTransport = redirectTransport{host: telegramURL.Host, next: defaultTransport}
+	defer func() { http.DefaultTransport = defaultTransport }()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.Install(exporter, "booklore-tg-bot-test")
+	defer provider.Shutdown(context.Background())
+
+	cfg := config.Default()
+	cfg.Logger = zaptest.NewLogger(t)
+	cfg.BotToken = testBotToken
+	cfg.AllowedUserIDs = []int64{42}
+	cfg.AllowedFileTypes = []string{".epub"}
+	cfg.DownloadFolder = t.TempDir()
+	cfg.DataFolder = t.TempDir()
+	cfg.AdminListenAddr = ""
+	cfg.BookloreAPI.APIURL = booklore.URL
+	cfg.BookloreAPI.APIToken = "booklore-token"
+	cfg.BookloreAPI.Enabled = true
+
+	b, err := NewBot(cfg)
+	if err != nil {
+		t.Fatalf("NewBot: %v", err)
+	}
+	defer b.cancel()
+	b.preferences.SetUserPreference(42, 1, 2, "Books", "/books")
+
+	b.handleUpdate(tgbotapi.Update{
+		UpdateID: 1,
+		Message: &tgbotapi.Message{
+			MessageID: 10,
+			From:      &tgbotapi.User{ID: 42, UserName: "reader"},
+			Chat:      &tgbotapi.Chat{ID: 42, Type: "private"},
+			Document:  &tgbotapi.Document{FileID: "file-1", FileName: "book.epub", FileSize: 12},
+		},
+	})
+
+	if err := provider.ForceFlush(context.Background()); err != nil {
+		t.Fatalf("ForceFlush: %v", err)
+	}
+	spans := exporter.GetSpans()
+
+	byID := make(map[string]tracetest.SpanStub, len(spans))
+	var root *tracetest.SpanStub
+	for i, span := range spans {
+		byID[span.SpanContext.SpanID().String()] = span
+		if span.Name == "telegram.update" {
+			root = &spans[i]
+		}
+	}
+	if root == nil {
+		t.Fatalf("no telegram.update span among %d spans", len(spans))
+	}
+	if root.Parent.IsValid() {
+		t.Errorf("telegram.update has parent %s, want a root span", root.Parent.SpanID())
+	}
+
+	// descends walks up the parents of a span to the update's span
+	descends := func(span tracetest.SpanStub) bool {
+		for span.Parent.IsValid() {
+			if span.Parent.SpanID() == root.SpanContext.SpanID() {
+				return true
+			}
+			parent, ok := byID[span.Parent.SpanID().String()]
+			if !ok {
+				return false
+			}
+			span = parent
+		}
+		return false
+	}
+
+	count := make(map[string]int)
+	for _, span := range spans {
+		count[span.Name]++
+		if span.Name != "telegram.update" && !descends(span) {
+			t.Errorf("span %s is not part of the update's trace", span.Name)
+		}
+	}
+	for _, name := range []string{"telegram.getFile", "downloader.DownloadFile", "booklore.RescanBookdrop", "bot.importUpload", "booklore.FinalizeImport"} {
+		if count[name] != 1 {
+			t.Errorf("%d %s spans, want 1", count[name], name)
+		}
+	}
+
+	bookloreStub.mutex.Lock()
+	defer bookloreStub.mutex.Unlock()
+	if bookloreStub.finalizes != 2 {
+		t.Fatalf("Booklore received %d finalize requests, want 2", bookloreStub.finalizes)
+	}
+	if got := count["booklore.FinalizeImport.attempt"]; got != bookloreStub.finalizes {
+		t.Errorf("%d booklore.FinalizeImport.attempt spans, want one per attempt (%d)", got, bookloreStub.finalizes)
+	}
+
+	// Booklore requests continue the update's trace
+	traceID := root.SpanContext.TraceID().String()
+	for i, traceparent := range bookloreStub.traceparents {
+		parts := strings.Split(traceparent, "-")
+		if len(parts) != 4 || parts[1] != traceID {
+			t.Errorf("Booklore request %d has traceparent %q, want trace %s", i+1, traceparent, traceID)
+		}
+	}
+	if len(bookloreStub.traceparents) == 0 {
+		t.Error("Booklore received no requests")
+	}
+}
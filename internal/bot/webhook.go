@@ -0,0 +1,155 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/brauni/booklore-tg-bot/internal/metrics"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// webhookSecretHeader carries the secret token Telegram was given in setWebhook
+const webhookSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// webhookQueueSize buffers updates while the previous one is still being handled
+const webhookQueueSize = 100
+
+// runWebhook serves the webhook, registers it with Telegram and handles updates
+// until the bot is stopped
+func (b *Bot) runWebhook() error {
+	cfg := b.config.Webhook
+
+	webhookURL, err := url.Parse(cfg.URL)
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	path := webhookURL.Path
+	if path == "" {
+		path = "/"
+	}
+
+	// Without a configured secret, a new one is generated on every start
+	secret := cfg.SecretToken
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+		secret = hex.EncodeToString(buf)
+	}
+
+	// Updates are handled one at a time, like with long polling
+	updates := make(chan tgbotapi.Update, webhookQueueSize)
+	metrics.RegisterQueue("webhook_updates", func() int { return len(updates) })
+
+	mux := http.NewServeMux()
+	mux.Handle(path, b.webhookHandler(secret, updates))
+	server := &http.Server{
+		Addr:              cfg.ListenAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		var err error
+		if cfg.TLSCertFile != "" {
+			err = server.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+		close(serverErr)
+	}()
+
+	if err := b.setWebhook(cfg.URL, secret); err != nil {
+		server.Close()
+		return err
+	}
+
+	b.config.Logger.Info("Receiving updates through webhook",
+		zap.String("url", cfg.URL),
+		zap.String("listen_addr", cfg.ListenAddr),
+		zap.Bool("tls", cfg.TLSCertFile != ""))
+
+	for {
+		select {
+		case update := <-updates:
+			b.handleUpdate(update)
+		case err, ok := <-serverErr:
+			if ok {
+				return fmt.Errorf("webhook server failed: %w", err)
+			}
+			return nil
+		case <-b.ctx.Done():
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := server.Shutdown(ctx); err != nil {
+				b.config.Logger.Warn("Failed to shut down webhook server",
+					zap.Error(err))
+			}
+			return nil
+		}
+	}
+}
+
+// webhookHandler accepts updates from Telegram and queues them for handling
+func (b *Bot) webhookHandler(secret string, updates chan<- tgbotapi.Update) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		token := r.Header.Get(webhookSecretHeader)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			b.config.Logger.Warn("Rejected webhook request with invalid secret token",
+				zap.String("remote_addr", r.RemoteAddr))
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		var update tgbotapi.Update
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&update); err != nil {
+			http.Error(w, "invalid update", http.StatusBadRequest)
+			return
+		}
+
+		select {
+		case updates <- update:
+			w.WriteHeader(http.StatusOK)
+		case <-r.Context().Done():
+			// Telegram retries updates that weren't acknowledged
+		case <-b.ctx.Done():
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		}
+	})
+}
+
+// setWebhook registers the webhook with Telegram. The request is built by hand
+// because the Telegram library predates secret tokens.
+func (b *Bot) setWebhook(webhookURL, secret string) error {
+	params := tgbotapi.Params{
+		"url":          webhookURL,
+		"secret_token": secret,
+	}
+	if err := params.AddInterface("allowed_updates", []string{"message", "callback_query", "inline_query"}); err != nil {
+		return fmt.Errorf("failed to build setWebhook request: %w", err)
+	}
+
+	if _, err := b.api.MakeRequest("setWebhook", params); err != nil {
+		return fmt.Errorf("failed to set webhook: %w", err)
+	}
+	return nil
+}
@@ -1,116 +1,412 @@
 package config
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
 
+	"github.com/brauni/booklore-tg-bot/internal/logging"
+	"github.com/brauni/booklore-tg-bot/internal/storage"
 	"github.com/joho/godotenv"
 	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
 )
 
+// Config is the bot's configuration. Every setting can be given in the YAML
+// config file under its yaml key and overridden by its env variable. Settings
+// marked reload are applied to a running bot when the configuration is reloaded.
 type Config struct {
-	BotToken         string
-	AllowedUserIDs   []int64
-	DownloadFolder   string
-	AllowedFileTypes []string
-	MaxFileSizeMB    int64
-	Logger           *zap.Logger
-	BookloreAPI      *BookloreConfig
+	BotToken           string            `yaml:"bot_token" env:"TELEGRAM_BOT_TOKEN" secret:"true"`
+	BotTokenFile       string            `yaml:"bot_token_file" env:"TELEGRAM_BOT_TOKEN_FILE"`
+	AllowedUserIDs     []int64           `yaml:"allowed_user_ids" env:"ALLOWED_USER_IDS" reload:"true"`
+	AdminUserIDs       []int64           `yaml:"admin_user_ids" env:"ADMIN_USER_IDS" reload:"true"`
+	DownloadFolder     string            `yaml:"download_folder" env:"DOWNLOAD_FOLDER"`
+	DataFolder         string            `yaml:"data_folder" env:"DATA_FOLDER"`
+	AllowedFileTypes   []string          `yaml:"allowed_file_types" env:"ALLOWED_FILE_TYPES" reload:"true"`
+	MaxFileSizeMB      int64             `yaml:"max_file_size_mb" env:"MAX_FILE_SIZE_MB" reload:"true"`
+	MaxUploadSizeMB    int64             `yaml:"max_upload_size_mb" env:"TELEGRAM_MAX_UPLOAD_MB" reload:"true"`
+	LibraryAccess      map[int64][]int64 `yaml:"library_access" env:"LIBRARY_ACCESS" reload:"true"`
+	InlineThumbnailURL string            `yaml:"inline_thumbnail_url" env:"INLINE_THUMBNAIL_URL"`
+	AdminListenAddr    string            `yaml:"admin_listen_addr" env:"ADMIN_LISTEN_ADDR"`
+	AuditRetentionDays int               `yaml:"audit_retention_days" env:"AUDIT_RETENTION_DAYS"`
+	EncryptionKey      string            `yaml:"encryption_key" env:"ENCRYPTION_KEY" secret:"true"` // base64, 32 bytes
+	EncryptionKeyFile  string            `yaml:"encryption_key_file" env:"ENCRYPTION_KEY_FILE"`
+	EncryptionOldKeys  []string          `yaml:"encryption_previous_keys" env:"ENCRYPTION_PREVIOUS_KEYS" secret:"true"` // decrypt state from before a key rotation
+	Logging            *LoggingConfig    `yaml:"logging"`
+	BookloreAPI        *BookloreConfig   `yaml:"booklore"`
+	Delivery           *DeliveryConfig   `yaml:"delivery"`
+	Webhook            *WebhookConfig    `yaml:"webhook"`
+	Tracing            *TracingConfig    `yaml:"-"`
+	Logger             *zap.Logger       `yaml:"-"`
+	// File is the config file the settings were read from, empty when only env variables are used
+	File string `yaml:"-"`
 }
 
 type BookloreConfig struct {
-	APIURL         string
-	APIToken       string
-	AutoImport     bool
-	Enabled        bool
-	RetryAttempts  int
-	RetryDelay     int // in seconds
-	DefaultLibraryID string
-	DefaultPathID    string
-}
-
-func Load() (*Config, error) {
-	// Load .env file if it exists (for local development)
+	APIURL           string `yaml:"api_url" env:"BOOKLORE_API_URL"`
+	APIToken         string `yaml:"api_token" env:"BOOKLORE_API_TOKEN" secret:"true" reload:"true"`
+	APITokenFile     string `yaml:"api_token_file" env:"BOOKLORE_API_TOKEN_FILE"` // re-read when it changes to rotate the token
+	Username         string `yaml:"username" env:"BOOKLORE_USERNAME"`             // logs in instead of using an API token
+	Password         string `yaml:"password" env:"BOOKLORE_PASSWORD" secret:"true"`
+	PasswordFile     string `yaml:"password_file" env:"BOOKLORE_PASSWORD_FILE"`
+	AutoImport       bool   `yaml:"auto_import" env:"BOOKLORE_AUTO_IMPORT"`
+	Enabled          bool   `yaml:"-"`
+	RetryAttempts    int    `yaml:"retry_attempts" env:"BOOKLORE_RETRY_ATTEMPTS"`
+	RetryDelay       int    `yaml:"retry_delay" env:"BOOKLORE_RETRY_DELAY"` // in seconds
+	DefaultLibraryID string `yaml:"default_library_id" env:"BOOKLORE_DEFAULT_LIBRARY_ID"`
+	DefaultPathID    string `yaml:"default_path_id" env:"BOOKLORE_DEFAULT_PATH_ID"`
+	// CaptionTags maps caption hashtags (without "#") to targets like "lib:Comics/path:Manga"
+	CaptionTags map[string]string `yaml:"caption_tags" env:"BOOKLORE_CAPTION_TAGS" reload:"true"`
+	// PromptLibrary asks where to import uploads without a caption target
+	PromptLibrary bool `yaml:"prompt_library" env:"BOOKLORE_PROMPT_LIBRARY"`
+	// RoutingRulesFile is a YAML file of rules picking targets for uploads
+	RoutingRulesFile string `yaml:"routing_rules" env:"BOOKLORE_ROUTING_RULES"`
+	// ChatDefaults maps chat IDs to targets used for users without a library preference
+	ChatDefaults map[int64]string `yaml:"chat_defaults" env:"BOOKLORE_CHAT_DEFAULTS" reload:"true"`
+	// LibraryCacheTTL is how long libraries are cached, in seconds
+	LibraryCacheTTL int `yaml:"library_cache_ttl" env:"BOOKLORE_LIBRARY_CACHE_TTL"`
+	// Events subscribes to Booklore's WebSocket events instead of relying on polling alone
+	Events bool `yaml:"events" env:"BOOKLORE_EVENTS"`
+	// EventsURL overrides the WebSocket endpoint derived from APIURL
+	EventsURL string `yaml:"events_url" env:"BOOKLORE_EVENTS_URL"`
+	// WatchBookdrop notifies subscribers about files reaching the bookdrop outside the bot
+	WatchBookdrop bool `yaml:"watch_bookdrop" env:"BOOKDROP_WATCH"`
+	// WatchInterval is how often the watcher polls the bookdrop, in seconds
+	WatchInterval int `yaml:"watch_interval" env:"BOOKDROP_POLL_INTERVAL"`
+	// UserAccounts lets users link their own Booklore account with /link
+	UserAccounts bool `yaml:"user_accounts" env:"BOOKLORE_USER_ACCOUNTS"`
+	// SharedFallback lets users without a linked account act through the bot's account
+	SharedFallback bool `yaml:"shared_fallback" env:"BOOKLORE_SHARED_FALLBACK" reload:"true"`
+}
+
+// DeliveryConfig configures sending books to e-readers by e-mail
+type DeliveryConfig struct {
+	Enabled          bool     `yaml:"-"`
+	SMTPHost         string   `yaml:"smtp_host" env:"SMTP_HOST"`
+	SMTPPort         int      `yaml:"smtp_port" env:"SMTP_PORT"`
+	SMTPUsername     string   `yaml:"smtp_username" env:"SMTP_USERNAME"`
+	SMTPPassword     string   `yaml:"smtp_password" env:"SMTP_PASSWORD" secret:"true"`
+	SMTPPasswordFile string   `yaml:"smtp_password_file" env:"SMTP_PASSWORD_FILE"`
+	SMTPTLSMode      string   `yaml:"smtp_tls" env:"SMTP_TLS"`
+	From             string   `yaml:"from" env:"SMTP_FROM"`
+	MaxAttempts      int      `yaml:"max_attempts" env:"DELIVERY_MAX_ATTEMPTS"`
+	RetryDelay       int      `yaml:"retry_delay" env:"DELIVERY_RETRY_DELAY"` // in seconds
+	MaxAttachmentMB  int64    `yaml:"max_attachment_mb" env:"DELIVERY_MAX_ATTACHMENT_MB"`
+	AllowedFormats   []string `yaml:"allowed_formats" env:"DELIVERY_ALLOWED_FORMATS"`
+}
+
+// WebhookConfig configures receiving updates through a webhook instead of long polling
+type WebhookConfig struct {
+	// Mode is "polling" or "webhook"
+	Mode    string `yaml:"mode" env:"TELEGRAM_MODE"`
+	Enabled bool   `yaml:"-"`
+	// URL is the public HTTPS address Telegram posts updates to; its path is served locally
+	URL string `yaml:"url" env:"WEBHOOK_URL"`
+	// ListenAddr is the local address of the webhook server, e.g. ":8443"
+	ListenAddr string `yaml:"listen_addr" env:"WEBHOOK_LISTEN_ADDR"`
+	// SecretToken is checked against the X-Telegram-Bot-Api-Secret-Token header
+	SecretToken     string `yaml:"secret" env:"WEBHOOK_SECRET" secret:"true"`
+	SecretTokenFile string `yaml:"secret_file" env:"WEBHOOK_SECRET_FILE"`
+	// TLSCertFile and TLSKeyFile serve HTTPS directly instead of behind a reverse proxy
+	TLSCertFile string `yaml:"tls_cert" env:"WEBHOOK_TLS_CERT"`
+	TLSKeyFile  string `yaml:"tls_key" env:"WEBHOOK_TLS_KEY"`
+}
+
+// LoggingConfig configures the bot's logs
+type LoggingConfig struct {
+	Level  string `yaml:"level" env:"LOG_LEVEL"`
+	Format string `yaml:"format" env:"LOG_FORMAT"`
+	// RedactPatterns are regular expressions whose matches are removed from logs
+	RedactPatterns []string `yaml:"redact_patterns" env:"LOG_REDACT_PATTERNS" sep:";"`
+	HashIdentities bool     `yaml:"hash_identities" env:"LOG_HASH_IDENTITIES"`
+	// HashKey keys identity hashes, derived from the bot token when empty
+	HashKey string `yaml:"hash_key" env:"LOG_HASH_KEY" secret:"true"`
+}
+
+// TracingConfig configures exporting traces over OTLP. The exporter itself reads the
+// standard OTEL_EXPORTER_OTLP_* variables, e.g. for headers or TLS.
+type TracingConfig struct {
+	Enabled     bool
+	ServiceName string
+}
+
+// Default returns the configuration used for settings that are neither in the
+// config file nor in the environment
+func Default() *Config {
+	return &Config{
+		DownloadFolder:     "downloads",
+		DataFolder:         "/app/data",
+		AllowedFileTypes:   []string{".pdf", ".doc", ".docx", ".txt", ".jpg", ".jpeg", ".png", ".zip", ".rar"},
+		MaxFileSizeMB:      20,
+		MaxUploadSizeMB:    50, // the Bot API limit
+		AdminListenAddr:    ":9090",
+		AuditRetentionDays: 90,
+		Logging: &LoggingConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		BookloreAPI: &BookloreConfig{
+			APIURL:          "https://booklore.brauni.dev",
+			AutoImport:      true,
+			RetryAttempts:   3,
+			RetryDelay:      3,
+			LibraryCacheTTL: 300,
+			WatchInterval:   60,
+			SharedFallback:  true,
+		},
+		Delivery: &DeliveryConfig{
+			SMTPPort:        587, // the submission port
+			SMTPTLSMode:     "starttls",
+			MaxAttempts:     5,
+			RetryDelay:      60,
+			MaxAttachmentMB: 25, // a common mail server limit
+		},
+		Webhook: &WebhookConfig{
+			Mode:       "polling",
+			ListenAddr: ":8443",
+		},
+	}
+}
+
+// LoadDotEnv loads a .env file into the environment, for local development
+func LoadDotEnv() {
 	if err := godotenv.Load(); err != nil {
 		// It's okay if .env file doesn't exist in production
-		fmt.Printf("Warning: Could not load .env file: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Warning: Could not load .env file: %v\n", err)
 	}
+}
 
-	// Initialize logger
-	logger, err := zap.NewProduction()
+// Load reads the configuration, prepares the download folder and creates the logger.
+// path is the config file, CONFIG_FILE is used when it is empty.
+func Load(path string) (*Config, error) {
+	LoadDotEnv()
+
+	cfg, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create download folder if it doesn't exist
+	if err := os.MkdirAll(cfg.DownloadFolder, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create download folder: %w", err)
+	}
+
+	// Initialize logger, keeping every token and password out of the logs
+	cfg.Logger, err = logging.New(logging.Config{
+		Level:          cfg.Logging.Level,
+		Format:         cfg.Logging.Format,
+		Secrets:        cfg.Secrets(),
+		RedactPatterns: cfg.Logging.RedactPatterns,
+		HashIdentities: cfg.Logging.HashIdentities,
+		HashKey:        logHashKey(cfg.Logging.HashKey, cfg.BotToken),
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize logger: %w", err)
 	}
 
-	// Get required environment variables
-	botToken := os.Getenv("TELEGRAM_BOT_TOKEN")
-	if botToken == "" {
-		return nil, fmt.Errorf("TELEGRAM_BOT_TOKEN environment variable is required")
+	return cfg, nil
+}
+
+// Read reads the config file, if any, applies env overrides and validates the
+// result, reporting every problem at once. It has no side effects, so it is
+// also used to check and reload the configuration.
+func Read(path string) (*Config, error) {
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
 	}
 
-	// Validate bot token format (should be like "123456789:ABCdefGHIjklMNOpqrsTUVwxyz")
-	if len(botToken) < 20 || !strings.Contains(botToken, ":") {
-		return nil, fmt.Errorf("invalid bot token format - token should be in format 'BOT_ID:BOT_TOKEN'")
+	cfg := Default()
+	cfg.File = path
+
+	var problems []string
+	if path != "" {
+		fileProblems, err := decodeFile(path, cfg)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, fileProblems...)
 	}
 
-	// Parse allowed user IDs
-	allowedUsersStr := os.Getenv("ALLOWED_USER_IDS")
-	if allowedUsersStr == "" {
-		return nil, fmt.Errorf("ALLOWED_USER_IDS environment variable is required")
+	problems = append(problems, applyEnv(cfg, os.Getenv)...)
+	problems = append(problems, cfg.readSecretFiles()...)
+	cfg.normalize()
+	problems = append(problems, cfg.validate()...)
+
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
 	}
-	allowedUserIDs, err := parseUserIDs(allowedUsersStr)
+
+	cfg.Tracing = loadTracingConfig()
+	return cfg, nil
+}
+
+// decodeFile reads a YAML config file into cfg. Unknown keys and values of the
+// wrong type are returned as problems, unreadable files as an error.
+func decodeFile(path string, cfg *Config) ([]string, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse ALLOWED_USER_IDS: %w", err)
+		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	// Get download folder (default to "downloads")
-	downloadFolder := os.Getenv("DOWNLOAD_FOLDER")
-	if downloadFolder == "" {
-		downloadFolder = "downloads"
-	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err = decoder.Decode(cfg)
 
-	// Parse allowed file types (default to common document types)
-	allowedFileTypesStr := os.Getenv("ALLOWED_FILE_TYPES")
-	var allowedFileTypes []string
-	if allowedFileTypesStr != "" {
-		allowedFileTypes = strings.Split(allowedFileTypesStr, ",")
-		for i, ft := range allowedFileTypes {
-			allowedFileTypes[i] = strings.TrimSpace(strings.ToLower(ft))
+	var typeErr *yaml.TypeError
+	switch {
+	case err == nil, errors.Is(err, io.EOF):
+		return nil, nil
+	case errors.As(err, &typeErr):
+		problems := make([]string, len(typeErr.Errors))
+		for i, problem := range typeErr.Errors {
+			problems[i] = fmt.Sprintf("%s: %s", path, problem)
 		}
-	} else {
-		// Default allowed file types
-		allowedFileTypes = []string{".pdf", ".doc", ".docx", ".txt", ".jpg", ".jpeg", ".png", ".zip", ".rar"}
+		return problems, nil
+	default:
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
 	}
+}
+
+// readSecretFiles reads secrets given as files, e.g. Docker or Kubernetes
+// secrets, so they don't show up in the container's environment
+func (c *Config) readSecretFiles() []string {
+	secrets := []struct {
+		env   string
+		path  string
+		value *string
+	}{
+		{"TELEGRAM_BOT_TOKEN", c.BotTokenFile, &c.BotToken},
+		{"ENCRYPTION_KEY", c.EncryptionKeyFile, &c.EncryptionKey},
+		{"BOOKLORE_API_TOKEN", c.BookloreAPI.APITokenFile, &c.BookloreAPI.APIToken},
+		{"BOOKLORE_PASSWORD", c.BookloreAPI.PasswordFile, &c.BookloreAPI.Password},
+		{"SMTP_PASSWORD", c.Delivery.SMTPPasswordFile, &c.Delivery.SMTPPassword},
+		{"WEBHOOK_SECRET", c.Webhook.SecretTokenFile, &c.Webhook.SecretToken},
+	}
+
+	var problems []string
+	for _, secret := range secrets {
+		if secret.path == "" {
+			continue
+		}
+		if *secret.value != "" {
+			problems = append(problems, fmt.Sprintf("%s: set either the secret or %s_FILE, not both", settingName(secret.env), secret.env))
+			continue
+		}
 
-	// Parse max file size (default to 20MB)
-	maxFileSizeStr := os.Getenv("MAX_FILE_SIZE_MB")
-	maxFileSizeMB := int64(20) // default
-	if maxFileSizeStr != "" {
-		maxFileSizeMB, err = strconv.ParseInt(maxFileSizeStr, 10, 64)
+		value, err := ReadSecretFile(secret.path)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse MAX_FILE_SIZE_MB: %w", err)
+			problems = append(problems, fmt.Sprintf("%s: %s", settingName(secret.env+"_FILE"), err))
+			continue
 		}
+		*secret.value = value
 	}
+	return problems
+}
 
-	// Create download folder if it doesn't exist
-	if err := os.MkdirAll(downloadFolder, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create download folder: %w", err)
+// ReadSecretFile reads a secret from a file, ignoring surrounding whitespace
+// such as a trailing newline
+func ReadSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
 	}
 
-	// Load Booklore API configuration
-	bookloreConfig := loadBookloreConfig()
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return "", fmt.Errorf("secret file %s is empty", path)
+	}
+	return secret, nil
+}
 
-	return &Config{
-		BotToken:         botToken,
-		AllowedUserIDs:   allowedUserIDs,
-		DownloadFolder:   downloadFolder,
-		AllowedFileTypes: allowedFileTypes,
-		MaxFileSizeMB:    maxFileSizeMB,
-		Logger:           logger,
-		BookloreAPI:      bookloreConfig,
-	}, nil
+// normalize fills in derived settings and brings values into their canonical form
+func (c *Config) normalize() {
+	for i, ft := range c.AllowedFileTypes {
+		c.AllowedFileTypes[i] = strings.TrimSpace(strings.ToLower(ft))
+	}
+	if strings.EqualFold(c.AdminListenAddr, "off") || strings.EqualFold(c.AdminListenAddr, "false") {
+		c.AdminListenAddr = ""
+	}
+	if c.LibraryAccess == nil {
+		c.LibraryAccess = make(map[int64][]int64)
+	}
+
+	// Only enable Booklore integration if an API token or login is provided
+	b := c.BookloreAPI
+	b.APIURL = strings.TrimSuffix(b.APIURL, "/")
+	b.Enabled = b.APIToken != "" || b.Username != ""
+	b.AutoImport = b.AutoImport && b.Enabled
+	b.Events = b.Events && b.Enabled
+	b.WatchBookdrop = b.WatchBookdrop && b.Enabled
+	b.UserAccounts = b.UserAccounts && b.Enabled
+	tags := make(map[string]string, len(b.CaptionTags))
+	for tag, target := range b.CaptionTags {
+		tags[strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))] = strings.TrimSpace(target)
+	}
+	b.CaptionTags = tags
+	if b.ChatDefaults == nil {
+		b.ChatDefaults = make(map[int64]string)
+	}
+
+	// Only enable e-mail delivery if a server and sender address are configured
+	d := c.Delivery
+	d.Enabled = d.SMTPHost != "" && d.From != ""
+	d.SMTPTLSMode = strings.ToLower(d.SMTPTLSMode)
+	var formats []string
+	for _, format := range d.AllowedFormats {
+		format = strings.TrimSpace(strings.ToLower(format))
+		if format == "" {
+			continue
+		}
+		if !strings.HasPrefix(format, ".") {
+			format = "." + format
+		}
+		formats = append(formats, format)
+	}
+	d.AllowedFormats = formats
+
+	c.Webhook.Mode = strings.ToLower(c.Webhook.Mode)
+	c.Webhook.Enabled = c.Webhook.Mode == "webhook"
+}
+
+// Secrets returns the configured tokens and passwords, which must never be logged
+func (c *Config) Secrets() []string {
+	var secrets []string
+	for _, f := range schemaFields(c) {
+		if f.secret {
+			secrets = append(secrets, f.secrets()...)
+		}
+	}
+	return secrets
+}
+
+// Cipher returns the cipher encrypting the state in the data folder, or nil
+// if no encryption key is configured
+func (c *Config) Cipher() (*storage.Cipher, error) {
+	if c.EncryptionKey == "" {
+		return nil, nil
+	}
+
+	key, err := storage.ParseKey(c.EncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+
+	var previous [][]byte
+	for i, encoded := range c.EncryptionOldKeys {
+		old, err := storage.ParseKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid previous encryption key %d: %w", i+1, err)
+		}
+		previous = append(previous, old)
+	}
+
+	return storage.NewCipher(key, previous...)
 }
 
 func parseUserIDs(userIDsStr string) ([]int64, error) {
@@ -128,55 +424,126 @@ func parseUserIDs(userIDsStr string) ([]int64, error) {
 	return userIDs, nil
 }
 
-func loadBookloreConfig() *BookloreConfig {
-	// Get Booklore API configuration from environment
-	apiURL := os.Getenv("BOOKLORE_API_URL")
-	if apiURL == "" {
-		apiURL = "https://booklore.brauni.dev"
+// parseLibraryAccess parses "userID:libID,libID;userID:libID" into a map of allowed library IDs per user
+func parseLibraryAccess(accessStr string) (map[int64][]int64, error) {
+	access := make(map[int64][]int64)
+	if strings.TrimSpace(accessStr) == "" {
+		return access, nil
 	}
 
-	apiToken := os.Getenv("BOOKLORE_API_TOKEN")
-	autoImportStr := os.Getenv("BOOKLORE_AUTO_IMPORT")
-	retryAttemptsStr := os.Getenv("BOOKLORE_RETRY_ATTEMPTS")
-	retryDelayStr := os.Getenv("BOOKLORE_RETRY_DELAY")
+	for _, entry := range strings.Split(accessStr, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		userPart, librariesPart, found := strings.Cut(entry, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid entry '%s', expected userID:libraryID,libraryID", entry)
+		}
 
-	// Only enable Booklore integration if API token is provided
-	enabled := apiToken != ""
+		userID, err := strconv.ParseInt(strings.TrimSpace(userPart), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user ID '%s': %w", userPart, err)
+		}
 
-	// Parse auto-import setting (default to true if enabled)
-	autoImport := enabled
-	if autoImportStr != "" {
-		autoImport = strings.ToLower(autoImportStr) == "true"
+		libraryIDs, err := parseUserIDs(librariesPart)
+		if err != nil {
+			return nil, fmt.Errorf("invalid library IDs for user %d: %w", userID, err)
+		}
+		access[userID] = libraryIDs
 	}
 
-	// Parse retry attempts (default to 3)
-	retryAttempts := 3
-	if retryAttemptsStr != "" {
-		if attempts, err := strconv.Atoi(retryAttemptsStr); err == nil && attempts > 0 {
-			retryAttempts = attempts
+	return access, nil
+}
+
+// parseCaptionTags parses "tag=lib:<library>[/path:<path>];tag=..." into a map keyed by lower-case tag
+func parseCaptionTags(tagsStr string) (map[string]string, error) {
+	tags := make(map[string]string)
+
+	for _, entry := range strings.Split(tagsStr, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
 		}
+
+		tag, target, found := strings.Cut(entry, "=")
+		tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+		target = strings.TrimSpace(target)
+		if !found || tag == "" || !strings.HasPrefix(strings.ToLower(target), "lib:") {
+			return nil, fmt.Errorf("invalid entry '%s', expected tag=lib:<library>[/path:<path>]", entry)
+		}
+		tags[tag] = target
 	}
 
-	// Parse retry delay (default to 3 seconds)
-	retryDelay := 3
-	if retryDelayStr != "" {
-		if delay, err := strconv.Atoi(retryDelayStr); err == nil && delay > 0 {
-			retryDelay = delay
+	return tags, nil
+}
+
+// parseChatDefaults parses "chatID=lib:<library>[/path:<path>];chatID=..." into a map keyed by chat ID
+func parseChatDefaults(defaultsStr string) (map[int64]string, error) {
+	defaults := make(map[int64]string)
+
+	for _, entry := range strings.Split(defaultsStr, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		chatStr, target, found := strings.Cut(entry, "=")
+		target = strings.TrimSpace(target)
+		if !found || !strings.HasPrefix(strings.ToLower(target), "lib:") {
+			return nil, fmt.Errorf("invalid entry '%s', expected chatID=lib:<library>[/path:<path>]", entry)
 		}
+
+		chatID, err := strconv.ParseInt(strings.TrimSpace(chatStr), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chat ID in entry '%s': %w", entry, err)
+		}
+		defaults[chatID] = target
+	}
+
+	return defaults, nil
+}
+
+// logHashKey returns the key for hashed identities. By default it is derived
+// from the bot token, so hashes stay stable across restarts without anyone
+// being able to recompute them.
+func logHashKey(key, botToken string) string {
+	if key != "" {
+		return key
+	}
+	sum := sha256.Sum256([]byte("log-hash:" + botToken))
+	return hex.EncodeToString(sum[:])
+}
+
+// loadTracingConfig enables tracing when an OTLP endpoint is configured, unless
+// the SDK is disabled with OTEL_SDK_DISABLED
+func loadTracingConfig() *TracingConfig {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" {
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+
+	serviceName := os.Getenv("OTEL_SERVICE_NAME")
+	if serviceName == "" {
+		serviceName = "booklore-tg-bot"
 	}
 
-	// Get library configuration
-	defaultLibraryID := os.Getenv("BOOKLORE_DEFAULT_LIBRARY_ID")
-	defaultPathID := os.Getenv("BOOKLORE_DEFAULT_PATH_ID")
+	return &TracingConfig{
+		Enabled:     endpoint != "" && strings.ToLower(os.Getenv("OTEL_SDK_DISABLED")) != "true",
+		ServiceName: serviceName,
+	}
+}
 
-	return &BookloreConfig{
-		APIURL:          strings.TrimSuffix(apiURL, "/"),
-		APIToken:        apiToken,
-		AutoImport:      autoImport,
-		Enabled:         enabled,
-		RetryAttempts:   retryAttempts,
-		RetryDelay:      retryDelay,
-		DefaultLibraryID: defaultLibraryID,
-		DefaultPathID:   defaultPathID,
+// AdminListenAddr returns the address of the admin server from ADMIN_LISTEN_ADDR,
+// ":9090" by default or empty if set to "off"
+func AdminListenAddr() string {
+	addr := os.Getenv("ADMIN_LISTEN_ADDR")
+	switch strings.ToLower(addr) {
+	case "":
+		return ":9090"
+	case "off", "false":
+		return ""
 	}
+	return addr
 }
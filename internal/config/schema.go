@@ -0,0 +1,249 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// redacted replaces secrets in printed configurations
+const redacted = "[REDACTED]"
+
+// field is a single setting of the config schema
+type field struct {
+	// key is the setting's path in the config file, e.g. "booklore.api_token"
+	key string
+	// env is the variable overriding the setting
+	env string
+	// sep separates list entries in the env variable, "," by default
+	sep    string
+	secret bool
+	reload bool
+	value  reflect.Value
+}
+
+// name identifies the setting in messages by its file key and env variable
+func (f field) name() string {
+	if f.env == "" {
+		return f.key
+	}
+	return fmt.Sprintf("%s (%s)", f.key, f.env)
+}
+
+// envParsers parse env variables that use a custom format
+var envParsers = map[string]func(string) (any, error){
+	"LIBRARY_ACCESS":         func(s string) (any, error) { return parseLibraryAccess(s) },
+	"BOOKLORE_CAPTION_TAGS":  func(s string) (any, error) { return parseCaptionTags(s) },
+	"BOOKLORE_CHAT_DEFAULTS": func(s string) (any, error) { return parseChatDefaults(s) },
+}
+
+// schemaFields lists the settings of cfg, following its sections
+func schemaFields(cfg *Config) []field {
+	var fields []field
+	collectFields(reflect.ValueOf(cfg).Elem(), "", &fields)
+	return fields
+}
+
+func collectFields(v reflect.Value, prefix string, fields *[]field) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key := strings.Split(sf.Tag.Get("yaml"), ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Pointer && fv.Type().Elem().Kind() == reflect.Struct {
+			if !fv.IsNil() {
+				collectFields(fv.Elem(), prefix+key+".", fields)
+			}
+			continue
+		}
+
+		sep := sf.Tag.Get("sep")
+		if sep == "" {
+			sep = ","
+		}
+		*fields = append(*fields, field{
+			key:    prefix + key,
+			env:    sf.Tag.Get("env"),
+			sep:    sep,
+			secret: sf.Tag.Get("secret") == "true",
+			reload: sf.Tag.Get("reload") == "true",
+			value:  fv,
+		})
+	}
+}
+
+// settingName returns how the setting read from env is named in messages
+func settingName(env string) string {
+	for _, f := range schemaFields(Default()) {
+		if f.env == env {
+			return f.name()
+		}
+	}
+	return env
+}
+
+// applyEnv overrides settings with the env variables that are set. Empty
+// variables count as unset, as compose files pass them on that way.
+func applyEnv(cfg *Config, getenv func(string) string) []string {
+	var problems []string
+	for _, f := range schemaFields(cfg) {
+		if f.env == "" {
+			continue
+		}
+		raw := getenv(f.env)
+		if raw == "" {
+			continue
+		}
+		if err := f.set(raw); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", f.name(), err))
+		}
+	}
+	return problems
+}
+
+// set parses a value from its env form
+func (f field) set(raw string) error {
+	if parse, ok := envParsers[f.env]; ok {
+		parsed, err := parse(raw)
+		if err != nil {
+			return err
+		}
+		f.value.Set(reflect.ValueOf(parsed))
+		return nil
+	}
+
+	switch f.value.Kind() {
+	case reflect.String:
+		f.value.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("expected true or false, got '%s'", raw)
+		}
+		f.value.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+		if err != nil {
+			return fmt.Errorf("expected a whole number, got '%s'", raw)
+		}
+		f.value.SetInt(n)
+	case reflect.Slice:
+		var entries []string
+		for _, entry := range strings.Split(raw, f.sep) {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				entries = append(entries, entry)
+			}
+		}
+		switch f.value.Type().Elem().Kind() {
+		case reflect.String:
+			f.value.Set(reflect.ValueOf(entries))
+		case reflect.Int64:
+			ids, err := parseUserIDs(strings.Join(entries, ","))
+			if err != nil {
+				return err
+			}
+			f.value.Set(reflect.ValueOf(ids))
+		default:
+			return fmt.Errorf("unsupported list type %s", f.value.Type())
+		}
+	default:
+		return fmt.Errorf("unsupported setting type %s", f.value.Type())
+	}
+	return nil
+}
+
+// clone copies the config and its sections, so changing the copy leaves c untouched
+func (c *Config) clone() *Config {
+	return cloneStruct(reflect.ValueOf(c)).Interface().(*Config)
+}
+
+func cloneStruct(ptr reflect.Value) reflect.Value {
+	copied := reflect.New(ptr.Type().Elem())
+	copied.Elem().Set(ptr.Elem())
+
+	// Sections are copied too; other pointers like the logger are shared
+	v := copied.Elem()
+	for i := 0; i < v.NumField(); i++ {
+		fv := v.Field(i)
+		if v.Type().Field(i).Tag.Get("yaml") != "-" && fv.Kind() == reflect.Pointer && !fv.IsNil() {
+			fv.Set(cloneStruct(fv))
+		}
+	}
+	return copied
+}
+
+// secrets returns the non-empty values of a secret string or list of strings
+func (f field) secrets() []string {
+	var values []string
+	switch f.value.Kind() {
+	case reflect.String:
+		values = []string{f.value.String()}
+	case reflect.Slice:
+		values = f.value.Interface().([]string)
+	}
+
+	var secrets []string
+	for _, value := range values {
+		if value != "" {
+			secrets = append(secrets, value)
+		}
+	}
+	return secrets
+}
+
+// Redacted returns a copy of the config with secrets replaced, for printing
+func (c *Config) Redacted() *Config {
+	copied := c.clone()
+	for _, f := range schemaFields(copied) {
+		if !f.secret || len(f.secrets()) == 0 {
+			continue
+		}
+		if f.value.Kind() == reflect.Slice {
+			f.value.Set(reflect.ValueOf([]string{redacted}))
+		} else {
+			f.value.SetString(redacted)
+		}
+	}
+	return copied
+}
+
+// RestartRequired lists the settings that differ in next but can't change
+// while the bot runs
+func (c *Config) RestartRequired(next *Config) []string {
+	return c.changedSettings(next, false)
+}
+
+// ReloadedSettings lists the settings that differ in next and are applied on a reload
+func (c *Config) ReloadedSettings(next *Config) []string {
+	return c.changedSettings(next, true)
+}
+
+func (c *Config) changedSettings(next *Config, reload bool) []string {
+	current, updated := schemaFields(c), schemaFields(next)
+
+	var changed []string
+	for i, f := range current {
+		if f.reload == reload && !reflect.DeepEqual(f.value.Interface(), updated[i].value.Interface()) {
+			changed = append(changed, f.key)
+		}
+	}
+	return changed
+}
+
+// WithReloaded returns a copy of the config that takes the settings that may
+// change while the bot runs from next and keeps all others
+func (c *Config) WithReloaded(next *Config) *Config {
+	copied := c.clone()
+	updated := schemaFields(next)
+	for i, f := range schemaFields(copied) {
+		if f.reload {
+			f.value.Set(updated[i].value)
+		}
+	}
+	return copied
+}
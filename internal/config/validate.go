@@ -0,0 +1,132 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/brauni/booklore-tg-bot/internal/storage"
+	"go.uber.org/zap/zapcore"
+)
+
+// ValidationError lists every problem found in the configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// validator collects problems, naming settings by their file key and env variable
+type validator struct {
+	problems []string
+}
+
+func (v *validator) check(ok bool, env string, format string, args ...any) {
+	if !ok {
+		v.problems = append(v.problems, settingName(env)+": "+fmt.Sprintf(format, args...))
+	}
+}
+
+// validate returns every problem of the configuration
+func (c *Config) validate() []string {
+	v := &validator{}
+
+	v.check(c.BotToken != "", "TELEGRAM_BOT_TOKEN", "is required")
+	// Bot tokens look like "123456789:ABCdefGHIjklMNOpqrsTUVwxyz"
+	v.check(c.BotToken == "" || (len(c.BotToken) >= 20 && strings.Contains(c.BotToken, ":")),
+		"TELEGRAM_BOT_TOKEN", "invalid bot token format - token should be in format 'BOT_ID:BOT_TOKEN'")
+	v.check(len(c.AllowedUserIDs) > 0, "ALLOWED_USER_IDS", "is required")
+	v.check(c.MaxFileSizeMB > 0, "MAX_FILE_SIZE_MB", "must be positive, got %d", c.MaxFileSizeMB)
+	v.check(c.MaxUploadSizeMB > 0, "TELEGRAM_MAX_UPLOAD_MB", "must be positive, got %d", c.MaxUploadSizeMB)
+	v.check(c.AuditRetentionDays >= 0, "AUDIT_RETENTION_DAYS", "must not be negative, got %d", c.AuditRetentionDays)
+
+	_, err := zapcore.ParseLevel(c.Logging.Level)
+	v.check(err == nil, "LOG_LEVEL", "expected debug, info, warn or error, got '%s'", c.Logging.Level)
+	switch strings.ToLower(c.Logging.Format) {
+	case "json", "console", "development":
+	default:
+		v.check(false, "LOG_FORMAT", "expected json or console, got '%s'", c.Logging.Format)
+	}
+	for _, pattern := range c.Logging.RedactPatterns {
+		_, err := regexp.Compile(pattern)
+		v.check(err == nil, "LOG_REDACT_PATTERNS", "invalid pattern '%s': %v", pattern, err)
+	}
+
+	if c.EncryptionKey != "" {
+		_, err := storage.ParseKey(c.EncryptionKey)
+		v.check(err == nil, "ENCRYPTION_KEY", "%v", err)
+	}
+	for i, key := range c.EncryptionOldKeys {
+		_, err := storage.ParseKey(key)
+		v.check(err == nil, "ENCRYPTION_PREVIOUS_KEYS", "key %d: %v", i+1, err)
+	}
+	v.check(len(c.EncryptionOldKeys) == 0 || c.EncryptionKey != "", "ENCRYPTION_PREVIOUS_KEYS", "requires ENCRYPTION_KEY")
+	v.check(!c.BookloreAPI.UserAccounts || c.EncryptionKey != "", "ENCRYPTION_KEY", "is required with BOOKLORE_USER_ACCOUNTS to store credentials encrypted")
+
+	c.BookloreAPI.validate(v)
+	if c.Delivery.Enabled {
+		c.Delivery.validate(v)
+	}
+	c.Webhook.validate(v)
+
+	return v.problems
+}
+
+func (b *BookloreConfig) validate(v *validator) {
+	v.check(b.RetryAttempts > 0, "BOOKLORE_RETRY_ATTEMPTS", "must be positive, got %d", b.RetryAttempts)
+	v.check(b.RetryDelay > 0, "BOOKLORE_RETRY_DELAY", "must be positive, got %d", b.RetryDelay)
+	v.check(b.LibraryCacheTTL > 0, "BOOKLORE_LIBRARY_CACHE_TTL", "must be positive, got %d", b.LibraryCacheTTL)
+	v.check(b.WatchInterval > 0, "BOOKDROP_POLL_INTERVAL", "must be positive, got %d", b.WatchInterval)
+	v.check(b.Username == "" || b.Password != "", "BOOKLORE_PASSWORD", "is required with BOOKLORE_USERNAME")
+	v.check(b.Password == "" || b.Username != "", "BOOKLORE_USERNAME", "is required with BOOKLORE_PASSWORD")
+	v.check(b.Username == "" || b.APIToken == "", "BOOKLORE_API_TOKEN", "set either an API token or BOOKLORE_USERNAME, not both")
+	v.check(b.DefaultPathID == "" || b.DefaultLibraryID != "", "BOOKLORE_DEFAULT_PATH_ID", "requires BOOKLORE_DEFAULT_LIBRARY_ID")
+
+	for tag, target := range b.CaptionTags {
+		v.check(tag != "" && strings.HasPrefix(strings.ToLower(target), "lib:"), "BOOKLORE_CAPTION_TAGS",
+			"invalid entry '%s=%s', expected tag=lib:<library>[/path:<path>]", tag, target)
+	}
+	for chatID, target := range b.ChatDefaults {
+		v.check(strings.HasPrefix(strings.ToLower(strings.TrimSpace(target)), "lib:"), "BOOKLORE_CHAT_DEFAULTS",
+			"invalid entry '%d=%s', expected chatID=lib:<library>[/path:<path>]", chatID, target)
+	}
+}
+
+func (d *DeliveryConfig) validate(v *validator) {
+	v.check(d.SMTPPort > 0 && d.SMTPPort <= 65535, "SMTP_PORT", "invalid port %d", d.SMTPPort)
+	switch d.SMTPTLSMode {
+	case "starttls", "tls", "none":
+	default:
+		v.check(false, "SMTP_TLS", "expected starttls, tls or none, got '%s'", d.SMTPTLSMode)
+	}
+	v.check(d.MaxAttempts > 0, "DELIVERY_MAX_ATTEMPTS", "must be positive, got %d", d.MaxAttempts)
+	v.check(d.RetryDelay > 0, "DELIVERY_RETRY_DELAY", "must be positive, got %d", d.RetryDelay)
+	v.check(d.MaxAttachmentMB > 0, "DELIVERY_MAX_ATTACHMENT_MB", "must be positive, got %d", d.MaxAttachmentMB)
+	// Go's SMTP client refuses to send a password unencrypted except to localhost
+	v.check(d.SMTPTLSMode != "none" || d.SMTPUsername == "" || isLocalhost(d.SMTPHost),
+		"SMTP_TLS", "none can't be used with SMTP_USERNAME unless SMTP_HOST is localhost, use starttls or tls")
+}
+
+// isLocalhost reports whether a host is the local machine, as net/smtp decides it
+func isLocalhost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
+
+func (w *WebhookConfig) validate(v *validator) {
+	switch w.Mode {
+	case "polling", "webhook":
+	default:
+		v.check(false, "TELEGRAM_MODE", "expected polling or webhook, got '%s'", w.Mode)
+	}
+	if !w.Enabled {
+		return
+	}
+
+	v.check(strings.HasPrefix(w.URL, "https://"), "WEBHOOK_URL", "must be an https:// URL in webhook mode")
+	// Telegram only accepts letters, digits, "_" and "-" in secret tokens
+	v.check(len(w.SecretToken) <= 256 && strings.Trim(w.SecretToken, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789_-") == "",
+		"WEBHOOK_SECRET", "may only contain A-Z, a-z, 0-9, _ and - and be at most 256 characters")
+	v.check((w.TLSCertFile == "") == (w.TLSKeyFile == ""), "WEBHOOK_TLS_CERT", "must be set together with WEBHOOK_TLS_KEY")
+}
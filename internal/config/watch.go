@@ -0,0 +1,68 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// watchDebounce waits for editors to finish writing before the file is read
+const watchDebounce = time.Second
+
+// Watch calls reload whenever the content of a config or secret file changes, until the
+// context is cancelled. The file's folder is watched, so files replaced by
+// editors or mounted from a Kubernetes ConfigMap or Secret are noticed too.
+func Watch(ctx context.Context, path string, logger *zap.Logger, reload func()) error {
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer fsWatcher.Close()
+
+	if err := fsWatcher.Add(filepath.Dir(path)); err != nil {
+		return err
+	}
+
+	lastSum := fileSum(path)
+	debounce := time.NewTimer(watchDebounce)
+	debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-fsWatcher.Events:
+			debounce.Reset(watchDebounce)
+
+		case err := <-fsWatcher.Errors:
+			logger.Warn("Config file watcher error",
+				zap.Error(err))
+
+		case <-debounce.C:
+			sum := fileSum(path)
+			if sum == lastSum {
+				continue
+			}
+			lastSum = sum
+
+			logger.Info("File changed, reloading configuration",
+				zap.String("path", path))
+			reload()
+		}
+	}
+}
+
+// fileSum hashes the file content, a missing file hashes to zero
+func fileSum(path string) [sha256.Size]byte {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return [sha256.Size]byte{}
+	}
+	return sha256.Sum256(data)
+}
@@ -0,0 +1,114 @@
+package delivery
+
+import (
+	"fmt"
+	"net/mail"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/brauni/booklore-tg-bot/internal/storage"
+	"go.uber.org/zap"
+)
+
+// DeviceStore keeps the e-mail address of each user's reading device
+type DeviceStore struct {
+	addresses map[int64]string
+	mutex     sync.RWMutex
+	file      *storage.JSONFile
+	logger    *zap.Logger
+}
+
+// NewDeviceStore creates a device store backed by the given file. An empty
+// path keeps the addresses in memory only.
+func NewDeviceStore(logger *zap.Logger, storagePath string) *DeviceStore {
+	d := &DeviceStore{
+		addresses: make(map[int64]string),
+		logger:    logger,
+	}
+
+	if storagePath == "" {
+		return d
+	}
+
+	d.file = storage.NewJSONFile(storagePath)
+	if _, err := d.file.Load(&d.addresses); err != nil {
+		logger.Error("Failed to load device addresses",
+			zap.String("path", storagePath),
+			zap.Error(err))
+		d.addresses = make(map[int64]string)
+	}
+
+	return d
+}
+
+// Get returns the device address of a user
+func (d *DeviceStore) Get(userID int64) (string, bool) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	address, ok := d.addresses[userID]
+	return address, ok
+}
+
+// Set validates and stores the device address of a user
+func (d *DeviceStore) Set(userID int64, address string) error {
+	parsed, err := mail.ParseAddress(strings.TrimSpace(address))
+	if err != nil {
+		return fmt.Errorf("invalid e-mail address: %w", err)
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.addresses[userID] = parsed.Address
+	d.save()
+	return nil
+}
+
+// Clear removes the device address of a user
+func (d *DeviceStore) Clear(userID int64) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	delete(d.addresses, userID)
+	d.save()
+}
+
+// save writes the addresses to disk; callers must hold the write lock
+func (d *DeviceStore) save() {
+	if d.file == nil {
+		return
+	}
+
+	if err := d.file.Save(d.addresses); err != nil {
+		d.logger.Error("Failed to save device addresses",
+			zap.String("path", d.file.Path()),
+			zap.Error(err))
+	}
+}
+
+// IsFormatAllowed reports whether a file can be sent to a device given the
+// allowed extensions. An empty list allows every format.
+func IsFormatAllowed(fileName string, allowedFormats []string) bool {
+	if len(allowedFormats) == 0 {
+		return true
+	}
+
+	ext := strings.ToLower(filepath.Ext(fileName))
+	for _, allowed := range allowedFormats {
+		if ext == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// MaskAddress hides most of the local part of an e-mail address for display
+func MaskAddress(address string) string {
+	local, domain, found := strings.Cut(address, "@")
+	if !found || len(local) <= 2 {
+		return address
+	}
+	return local[:2] + strings.Repeat("*", len(local)-2) + "@" + domain
+}
@@ -0,0 +1,209 @@
+package delivery
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// TLS modes for the SMTP connection
+const (
+	TLSModeStartTLS = "starttls"
+	TLSModeImplicit = "tls"
+	TLSModeNone     = "none"
+)
+
+// Message is an e-mail with a single attachment
+type Message struct {
+	From           string
+	To             string
+	Subject        string
+	Body           string
+	AttachmentName string
+	Attachment     io.Reader
+}
+
+// Sender delivers e-mail messages
+type Sender interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// SMTPConfig configures the SMTP connection
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	TLSMode  string
+}
+
+// SMTPSender sends e-mail through an SMTP server
+type SMTPSender struct {
+	config SMTPConfig
+}
+
+// NewSMTPSender creates an SMTP sender
+func NewSMTPSender(config SMTPConfig) *SMTPSender {
+	return &SMTPSender{config: config}
+}
+
+// Send delivers the message over SMTP
+func (s *SMTPSender) Send(ctx context.Context, msg *Message) error {
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	var conn net.Conn
+	var err error
+	if s.config.TLSMode == TLSModeImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: s.config.Host})
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+
+	// Bound the whole conversation by the context deadline
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if s.config.TLSMode == TLSModeStartTLS {
+		if err := client.StartTLS(&tls.Config{ServerName: s.config.Host}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+
+	if s.config.Username != "" {
+		auth := smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(msg.From); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM rejected: %w", err)
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return fmt.Errorf("SMTP RCPT TO rejected: %w", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA rejected: %w", err)
+	}
+	if err := writeMessage(w, msg); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("SMTP server rejected message: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// writeMessage writes msg as a MIME multipart e-mail
+func writeMessage(w io.Writer, msg *Message) error {
+	mw := multipart.NewWriter(w)
+
+	headers := []string{
+		"From: " + msg.From,
+		"To: " + msg.To,
+		"Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/mixed; boundary=" + mw.Boundary(),
+	}
+	for _, h := range headers {
+		if _, err := io.WriteString(w, h+"\r\n"); err != nil {
+			return err
+		}
+	}
+	if _, err := io.WriteString(w, "\r\n"); err != nil {
+		return err
+	}
+
+	textPart, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"8bit"},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(textPart, msg.Body); err != nil {
+		return err
+	}
+
+	fileName := mime.QEncoding.Encode("utf-8", msg.AttachmentName)
+	contentType := mime.TypeByExtension(filepath.Ext(msg.AttachmentName))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	attachmentPart, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {fmt.Sprintf("%s; name=\"%s\"", contentType, fileName)},
+		"Content-Disposition":       {fmt.Sprintf("attachment; filename=\"%s\"", fileName)},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return err
+	}
+
+	encoder := base64.NewEncoder(base64.StdEncoding, &lineWrapper{w: attachmentPart})
+	if _, err := io.Copy(encoder, msg.Attachment); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+
+	return mw.Close()
+}
+
+// lineWrapper breaks base64 output into 76 character lines as required by RFC 2045
+type lineWrapper struct {
+	w       io.Writer
+	lineLen int
+}
+
+func (l *lineWrapper) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := 76 - l.lineLen
+		if n > len(p) {
+			n = len(p)
+		}
+		if _, err := l.w.Write(p[:n]); err != nil {
+			return written, err
+		}
+		written += n
+		l.lineLen += n
+		p = p[n:]
+
+		if l.lineLen == 76 {
+			if _, err := l.w.Write([]byte("\r\n")); err != nil {
+				return written, err
+			}
+			l.lineLen = 0
+		}
+	}
+	return written, nil
+}
@@ -0,0 +1,418 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/brauni/booklore-tg-bot/internal/storage"
+	"go.uber.org/zap"
+)
+
+// Status is the delivery state of an outbox entry
+type Status string
+
+const (
+	StatusQueued Status = "QUEUED"
+	StatusSent   Status = "SENT"
+	StatusFailed Status = "FAILED"
+)
+
+// SourceKind identifies where the attachment of an entry comes from
+type SourceKind string
+
+const (
+	// SourceBook is a book in the Booklore library, Ref is the book ID
+	SourceBook SourceKind = "book"
+	// SourceUpload is a file uploaded through the bot, Ref is the history record ID
+	SourceUpload SourceKind = "upload"
+)
+
+// pollInterval is how often the outbox checks for due entries
+const pollInterval = 15 * time.Second
+
+// maxRetryDelay caps the exponential backoff between attempts
+const maxRetryDelay = time.Hour
+
+// Entry is a queued e-mail delivery
+type Entry struct {
+	ID            int64      `json:"id"`
+	UserID        int64      `json:"userId"`
+	ChatID        int64      `json:"chatId"`
+	To            string     `json:"to"`
+	Kind          SourceKind `json:"kind"`
+	Ref           int64      `json:"ref"`
+	Title         string     `json:"title"`
+	Status        Status     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"lastError,omitempty"`
+	NextAttemptAt time.Time  `json:"nextAttemptAt"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+// Attachment is an opened file to be sent
+type Attachment struct {
+	Name   string
+	Reader io.ReadCloser
+	Size   int64 // -1 if unknown
+}
+
+// AttachmentOpener opens the file an entry refers to
+type AttachmentOpener func(ctx context.Context, entry Entry) (*Attachment, error)
+
+// StatusNotifier is called when an entry was sent or permanently failed
+type StatusNotifier func(entry Entry)
+
+// OutboxConfig configures delivery behaviour
+type OutboxConfig struct {
+	From               string
+	MaxAttempts        int
+	RetryDelay         time.Duration
+	MaxAttachmentBytes int64
+	AllowedFormats     []string
+}
+
+// PermanentError marks a delivery failure that retrying won't fix
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// storedOutbox is the on-disk representation of the outbox
+type storedOutbox struct {
+	NextID  int64    `json:"nextId"`
+	Entries []*Entry `json:"entries"`
+}
+
+// Outbox persists e-mail deliveries and sends them with retries
+type Outbox struct {
+	entries  []*Entry
+	nextID   int64
+	mutex    sync.Mutex
+	file     *storage.JSONFile
+	sender   Sender
+	opener   AttachmentOpener
+	notifier StatusNotifier
+	config   OutboxConfig
+	wake     chan struct{}
+	logger   *zap.Logger
+}
+
+// NewOutbox creates an outbox backed by the given file. An empty path keeps
+// the outbox in memory only.
+func NewOutbox(logger *zap.Logger, storagePath string, sender Sender, opener AttachmentOpener, config OutboxConfig) *Outbox {
+	o := &Outbox{
+		nextID: 1,
+		sender: sender,
+		opener: opener,
+		config: config,
+		wake:   make(chan struct{}, 1),
+		logger: logger,
+	}
+
+	if storagePath == "" {
+		return o
+	}
+
+	o.file = storage.NewJSONFile(storagePath)
+
+	var stored storedOutbox
+	found, err := o.file.Load(&stored)
+	if err != nil {
+		logger.Error("Failed to load delivery outbox",
+			zap.String("path", storagePath),
+			zap.Error(err))
+		return o
+	}
+	if found {
+		o.entries = stored.Entries
+		o.nextID = stored.NextID
+		logger.Info("Loaded delivery outbox from file",
+			zap.String("path", storagePath),
+			zap.Int("entry_count", len(o.entries)),
+			zap.Int("pending", o.pending()))
+	}
+
+	return o
+}
+
+// SetNotifier registers a callback for final delivery outcomes
+func (o *Outbox) SetNotifier(notifier StatusNotifier) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.notifier = notifier
+}
+
+// Enqueue adds a delivery to the outbox and returns its ID
+func (o *Outbox) Enqueue(entry Entry) int64 {
+	o.mutex.Lock()
+	now := time.Now().UTC()
+	entry.ID = o.nextID
+	entry.Status = StatusQueued
+	entry.Attempts = 0
+	entry.NextAttemptAt = now
+	entry.CreatedAt = now
+	entry.UpdatedAt = now
+	o.nextID++
+	o.entries = append(o.entries, &entry)
+	o.save()
+	o.mutex.Unlock()
+
+	o.logger.Info("Queued e-mail delivery",
+		zap.Int64("delivery_id", entry.ID),
+		zap.Int64("user_id", entry.UserID),
+		zap.String("kind", string(entry.Kind)),
+		zap.Int64("ref", entry.Ref))
+
+	// Wake the worker so the delivery starts right away
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+
+	return entry.ID
+}
+
+// List returns a user's deliveries, newest first
+func (o *Outbox) List(userID int64, limit int) []Entry {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	var result []Entry
+	for _, e := range o.entries {
+		if e.UserID == userID {
+			result = append(result, *e)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID > result[j].ID
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// Pending returns the number of deliveries that haven't been sent or failed yet
+func (o *Outbox) Pending() int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return o.pending()
+}
+
+func (o *Outbox) pending() int {
+	count := 0
+	for _, e := range o.entries {
+		if e.Status == StatusQueued {
+			count++
+		}
+	}
+	return count
+}
+
+// Run processes the outbox until the context is cancelled
+func (o *Outbox) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		o.processDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-o.wake:
+		}
+	}
+}
+
+// processDue attempts every queued delivery whose next attempt is due
+func (o *Outbox) processDue(ctx context.Context) {
+	o.mutex.Lock()
+	var due []Entry
+	now := time.Now()
+	for _, e := range o.entries {
+		if e.Status == StatusQueued && !e.NextAttemptAt.After(now) {
+			due = append(due, *e)
+		}
+	}
+	o.mutex.Unlock()
+
+	for _, entry := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		o.attempt(ctx, entry)
+	}
+}
+
+// attempt sends a single delivery and records the outcome
+func (o *Outbox) attempt(ctx context.Context, entry Entry) {
+	sendCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	err := o.send(sendCtx, entry)
+
+	o.mutex.Lock()
+	stored := o.find(entry.ID)
+	if stored == nil {
+		o.mutex.Unlock()
+		return
+	}
+
+	stored.Attempts++
+	stored.UpdatedAt = time.Now().UTC()
+
+	var permanent *PermanentError
+	switch {
+	case err == nil:
+		stored.Status = StatusSent
+		stored.LastError = ""
+	case errors.As(err, &permanent) || stored.Attempts >= o.config.MaxAttempts:
+		stored.Status = StatusFailed
+		stored.LastError = err.Error()
+	default:
+		stored.LastError = err.Error()
+		stored.NextAttemptAt = time.Now().UTC().Add(o.retryDelay(stored.Attempts))
+	}
+
+	o.save()
+	result := *stored
+	notifier := o.notifier
+	o.mutex.Unlock()
+
+	if err != nil {
+		o.logger.Warn("E-mail delivery attempt failed",
+			zap.Int64("delivery_id", result.ID),
+			zap.Int("attempt", result.Attempts),
+			zap.String("status", string(result.Status)),
+			zap.Error(err))
+	} else {
+		o.logger.Info("E-mail delivered",
+			zap.Int64("delivery_id", result.ID),
+			zap.Int("attempt", result.Attempts))
+	}
+
+	if result.Status != StatusQueued && notifier != nil {
+		notifier(result)
+	}
+}
+
+// send opens the attachment, checks the device constraints and sends the e-mail
+func (o *Outbox) send(ctx context.Context, entry Entry) error {
+	attachment, err := o.opener(ctx, entry)
+	if err != nil {
+		return err
+	}
+	defer attachment.Reader.Close()
+
+	if !IsFormatAllowed(attachment.Name, o.config.AllowedFormats) {
+		return &PermanentError{Err: fmt.Errorf("format of '%s' is not accepted by the device (allowed: %v)",
+			attachment.Name, o.config.AllowedFormats)}
+	}
+
+	if o.config.MaxAttachmentBytes > 0 && attachment.Size > o.config.MaxAttachmentBytes {
+		return &PermanentError{Err: fmt.Errorf("file is %.1f MB, the e-mail limit is %.1f MB",
+			float64(attachment.Size)/1024/1024, float64(o.config.MaxAttachmentBytes)/1024/1024)}
+	}
+
+	// Without a known size the limit is enforced while the file is sent
+	var reader io.Reader = attachment.Reader
+	var limited *limitedReader
+	if o.config.MaxAttachmentBytes > 0 && attachment.Size < 0 {
+		limited = &limitedReader{r: attachment.Reader, remaining: o.config.MaxAttachmentBytes}
+		reader = limited
+	}
+
+	err = o.sender.Send(ctx, &Message{
+		From:           o.config.From,
+		To:             entry.To,
+		Subject:        entry.Title,
+		Body:           fmt.Sprintf("%s\n\nSent from Booklore by the Telegram bot.", entry.Title),
+		AttachmentName: attachment.Name,
+		Attachment:     reader,
+	})
+	if limited != nil && limited.exceeded {
+		return &PermanentError{Err: fmt.Errorf("file is larger than the e-mail limit of %.1f MB",
+			float64(o.config.MaxAttachmentBytes)/1024/1024)}
+	}
+	return err
+}
+
+// errAttachmentTooLarge aborts sending an attachment that exceeds the limit
+var errAttachmentTooLarge = errors.New("attachment exceeds the size limit")
+
+// limitedReader fails once more than remaining bytes were read
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	exceeded  bool
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	// Read one byte past the limit to tell a file of exactly the limit from a larger one
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) > l.remaining {
+		n, l.remaining, l.exceeded = int(l.remaining), 0, true
+		return n, errAttachmentTooLarge
+	}
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// retryDelay returns the exponential backoff after the given number of attempts
+func (o *Outbox) retryDelay(attempts int) time.Duration {
+	delay := o.config.RetryDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxRetryDelay {
+			return maxRetryDelay
+		}
+	}
+	return delay
+}
+
+func (o *Outbox) find(id int64) *Entry {
+	for _, e := range o.entries {
+		if e.ID == id {
+			return e
+		}
+	}
+	return nil
+}
+
+// save writes the outbox to disk; callers must hold the lock
+func (o *Outbox) save() {
+	if o.file == nil {
+		return
+	}
+
+	stored := storedOutbox{
+		NextID:  o.nextID,
+		Entries: o.entries,
+	}
+	if err := o.file.Save(stored); err != nil {
+		o.logger.Error("Failed to save delivery outbox",
+			zap.String("path", o.file.Path()),
+			zap.Error(err))
+	}
+}
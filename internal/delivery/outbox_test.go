@@ -0,0 +1,216 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zaptest"
+)
+
+// fakeSender fails with the scripted errors in turn, then succeeds
+type fakeSender struct {
+	errs        []error
+	calls       int
+	attachments []string
+}
+
+func (s *fakeSender) Send(ctx context.Context, msg *Message) error {
+	s.calls++
+	data, err := io.ReadAll(msg.Attachment)
+	if err != nil {
+		return err
+	}
+	s.attachments = append(s.attachments, string(data))
+
+	if len(s.errs) > 0 {
+		err, s.errs = s.errs[0], s.errs[1:]
+		return err
+	}
+	return nil
+}
+
+// openString opens every entry as a file with the given name and content
+func openString(name, content string, size int64) AttachmentOpener {
+	return func(ctx context.Context, entry Entry) (*Attachment, error) {
+		return &Attachment{Name: name, Reader: io.NopCloser(strings.NewReader(content)), Size: size}, nil
+	}
+}
+
+func newTestOutbox(t *testing.T, sender Sender, opener AttachmentOpener) (*Outbox, *[]Entry) {
+	t.Helper()
+	o := NewOutbox(zaptest.NewLogger(t), "", sender, opener, OutboxConfig{
+		From:               "bot@example.com",
+		MaxAttempts:        3,
+		RetryDelay:         time.Minute,
+		MaxAttachmentBytes: 10,
+		AllowedFormats:     []string{".epub"},
+	})
+
+	var notified []Entry
+	o.SetNotifier(func(entry Entry) {
+		notified = append(notified, entry)
+	})
+	return o, &notified
+}
+
+// get returns a copy of an entry
+func get(o *Outbox, id int64) Entry {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return *o.find(id)
+}
+
+// makeDue moves an entry's next attempt to now, as if the backoff passed
+func makeDue(o *Outbox, id int64) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.find(id).NextAttemptAt = time.Now().UTC()
+}
+
+func TestOutboxRetriesWithBackoff(t *testing.T) {
+	sender := &fakeSender{errs: []error{errors.New("connection refused"), errors.New("timeout")}}
+	o, notified := newTestOutbox(t, sender, openString("book.epub", "content", 7))
+	id := o.Enqueue(Entry{UserID: 1, To: "reader@kindle.com", Title: "Book"})
+	ctx := context.Background()
+
+	// Each failure waits twice as long as the one before
+	for attempt, wantDelay := range []time.Duration{time.Minute, 2 * time.Minute} {
+		before := time.Now().UTC()
+		o.processDue(ctx)
+
+		entry := get(o, id)
+		if entry.Status != StatusQueued || entry.Attempts != attempt+1 {
+			t.Fatalf("after attempt %d: status %s with %d attempts, want queued with %d", attempt+1, entry.Status, entry.Attempts, attempt+1)
+		}
+		if delay := entry.NextAttemptAt.Sub(before); delay < wantDelay || delay > wantDelay+time.Second {
+			t.Errorf("after attempt %d: next attempt in %v, want %v", attempt+1, delay, wantDelay)
+		}
+
+		// Not due yet, nothing is sent
+		o.processDue(ctx)
+		if sender.calls != attempt+1 {
+			t.Fatalf("sent %d times before the backoff passed, want %d", sender.calls, attempt+1)
+		}
+		makeDue(o, id)
+	}
+
+	o.processDue(ctx)
+	entry := get(o, id)
+	if entry.Status != StatusSent || entry.Attempts != 3 || entry.LastError != "" {
+		t.Fatalf("final entry = %+v, want sent after 3 attempts", entry)
+	}
+	if len(*notified) != 1 || (*notified)[0].Status != StatusSent {
+		t.Errorf("notified %+v, want one sent notification", *notified)
+	}
+	if sender.attachments[2] != "content" {
+		t.Errorf("attachment = %q, want the file content", sender.attachments[2])
+	}
+}
+
+func TestOutboxFailsAfterMaxAttempts(t *testing.T) {
+	sender := &fakeSender{errs: []error{errors.New("1"), errors.New("2"), errors.New("mailbox unavailable")}}
+	o, notified := newTestOutbox(t, sender, openString("book.epub", "content", 7))
+	id := o.Enqueue(Entry{UserID: 1, To: "reader@kindle.com", Title: "Book"})
+
+	for i := 0; i < 3; i++ {
+		makeDue(o, id)
+		o.processDue(context.Background())
+	}
+
+	entry := get(o, id)
+	if entry.Status != StatusFailed || entry.Attempts != 3 || entry.LastError != "mailbox unavailable" {
+		t.Fatalf("entry = %+v, want failed after 3 attempts with the last error", entry)
+	}
+	if len(*notified) != 1 || (*notified)[0].Status != StatusFailed {
+		t.Errorf("notified %+v, want one failed notification", *notified)
+	}
+	if o.Pending() != 0 {
+		t.Errorf("pending = %d, want 0", o.Pending())
+	}
+}
+
+func TestOutboxPermanentErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		sender    *fakeSender
+		opener    AttachmentOpener
+		wantError string
+		wantSends int
+	}{
+		{
+			name:      "format not accepted",
+			sender:    &fakeSender{},
+			opener:    openString("book.pdf", "content", 7),
+			wantError: "not accepted by the device",
+		},
+		{
+			name:      "known size over the limit",
+			sender:    &fakeSender{},
+			opener:    openString("book.epub", "far too much content", 20),
+			wantError: "the e-mail limit is",
+		},
+		{
+			name:      "unknown size over the limit",
+			sender:    &fakeSender{},
+			opener:    openString("book.epub", "far too much content", -1),
+			wantError: "larger than the e-mail limit",
+			wantSends: 1,
+		},
+		{
+			name:      "sender reports a permanent error",
+			sender:    &fakeSender{errs: []error{&PermanentError{Err: errors.New("recipient rejected")}}},
+			opener:    openString("book.epub", "content", 7),
+			wantError: "recipient rejected",
+			wantSends: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, notified := newTestOutbox(t, tt.sender, tt.opener)
+			id := o.Enqueue(Entry{UserID: 1, To: "reader@kindle.com", Title: "Book"})
+			o.processDue(context.Background())
+
+			entry := get(o, id)
+			if entry.Status != StatusFailed || entry.Attempts != 1 {
+				t.Fatalf("entry = %+v, want failed after the first attempt", entry)
+			}
+			if !strings.Contains(entry.LastError, tt.wantError) {
+				t.Errorf("error = %q, want it to contain %q", entry.LastError, tt.wantError)
+			}
+			if tt.sender.calls != tt.wantSends {
+				t.Errorf("sent %d times, want %d", tt.sender.calls, tt.wantSends)
+			}
+			if len(*notified) != 1 {
+				t.Errorf("notified %d times, want once", len(*notified))
+			}
+		})
+	}
+}
+
+func TestOutboxUnknownSizeWithinLimit(t *testing.T) {
+	sender := &fakeSender{}
+	o, _ := newTestOutbox(t, sender, openString("book.epub", "exactly10b", -1))
+	id := o.Enqueue(Entry{UserID: 1, To: "reader@kindle.com", Title: "Book"})
+	o.processDue(context.Background())
+
+	if entry := get(o, id); entry.Status != StatusSent {
+		t.Fatalf("entry = %+v, want sent", entry)
+	}
+	if sender.attachments[0] != "exactly10b" {
+		t.Errorf("attachment = %q, want the whole file", sender.attachments[0])
+	}
+}
+
+func TestRetryDelayIsCapped(t *testing.T) {
+	o := &Outbox{config: OutboxConfig{RetryDelay: time.Minute}}
+	for attempts, want := range map[int]time.Duration{1: time.Minute, 3: 4 * time.Minute, 7: maxRetryDelay, 50: maxRetryDelay} {
+		if got := o.retryDelay(attempts); got != want {
+			t.Errorf("retryDelay(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
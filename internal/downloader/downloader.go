@@ -1,13 +1,22 @@
 package downloader
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
+	"github.com/brauni/booklore-tg-bot/internal/metrics"
+	"github.com/brauni/booklore-tg-bot/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 )
 
@@ -15,9 +24,17 @@ type Downloader struct {
 	downloadFolder   string
 	allowedFileTypes []string
 	maxFileSizeMB    int64
+	mutex            sync.RWMutex
 	logger           *zap.Logger
 }
 
+// DownloadResult describes a file saved to the download folder
+type DownloadResult struct {
+	Path   string
+	Size   int64
+	SHA256 string
+}
+
 func NewDownloader(downloadFolder string, allowedFileTypes []string, maxFileSizeMB int64, logger *zap.Logger) *Downloader {
 	return &Downloader{
 		downloadFolder:   downloadFolder,
@@ -27,13 +44,31 @@ func NewDownloader(downloadFolder string, allowedFileTypes []string, maxFileSize
 	}
 }
 
+// SetLimits replaces the allowed file types and the maximum file size, e.g. after a config reload
+func (d *Downloader) SetLimits(allowedFileTypes []string, maxFileSizeMB int64) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.allowedFileTypes = allowedFileTypes
+	d.maxFileSizeMB = maxFileSizeMB
+}
+
+// limits returns the allowed file types and the maximum file size
+func (d *Downloader) limits() ([]string, int64) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	return d.allowedFileTypes, d.maxFileSizeMB
+}
+
 func (d *Downloader) IsFileTypeAllowed(filename string) bool {
-	if len(d.allowedFileTypes) == 0 {
+	allowedFileTypes, _ := d.limits()
+	if len(allowedFileTypes) == 0 {
 		return true // No restrictions if no types specified
 	}
 
 	ext := strings.ToLower(filepath.Ext(filename))
-	for _, allowedExt := range d.allowedFileTypes {
+	for _, allowedExt := range allowedFileTypes {
 		if ext == allowedExt {
 			return true
 		}
@@ -42,12 +77,13 @@ func (d *Downloader) IsFileTypeAllowed(filename string) bool {
 	d.logger.Info("File type not allowed",
 		zap.String("filename", filename),
 		zap.String("extension", ext),
-		zap.Strings("allowed_extensions", d.allowedFileTypes))
+		zap.Strings("allowed_extensions", allowedFileTypes))
 	return false
 }
 
 func (d *Downloader) IsFileSizeAllowed(fileSize int64) bool {
-	maxSizeBytes := d.maxFileSizeMB * 1024 * 1024
+	_, maxFileSizeMB := d.limits()
+	maxSizeBytes := maxFileSizeMB * 1024 * 1024
 	if fileSize > maxSizeBytes {
 		d.logger.Info("File size exceeds limit",
 			zap.Int64("file_size", fileSize),
@@ -57,26 +93,48 @@ func (d *Downloader) IsFileSizeAllowed(fileSize int64) bool {
 	return true
 }
 
-func (d *Downloader) DownloadFile(fileURL, filename string) (string, error) {
+func (d *Downloader) DownloadFile(ctx context.Context, fileURL, filename string) (*DownloadResult, error) {
+	ctx, span := tracing.Start(ctx, "downloader.DownloadFile",
+		attribute.String("file.name", filename))
+
+	result, err := d.downloadFile(ctx, fileURL, filename)
+	if result != nil {
+		span.SetAttributes(attribute.Int64("file.size", result.Size))
+		metrics.ObserveDownload(result.Size, err)
+	} else {
+		metrics.ObserveDownload(0, err)
+	}
+	tracing.End(span, err)
+	return result, err
+}
+
+func (d *Downloader) downloadFile(ctx context.Context, fileURL, filename string) (*DownloadResult, error) {
+	_, maxFileSizeMB := d.limits()
+
 	// Validate file type
 	if !d.IsFileTypeAllowed(filename) {
-		return "", fmt.Errorf("file type not allowed: %s", filename)
+		return nil, fmt.Errorf("file type not allowed: %s", filename)
 	}
 
 	// Download the file
-	resp, err := http.Get(fileURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
 	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		err = WithoutURL(err)
 		d.logger.Error("Failed to download file",
-			zap.String("url", fileURL),
+			zap.String("filename", filename),
 			zap.Error(err))
-		return "", fmt.Errorf("failed to download file: %w", err)
+		return nil, fmt.Errorf("failed to download file: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Check file size
 	if resp.ContentLength > 0 && !d.IsFileSizeAllowed(resp.ContentLength) {
-		return "", fmt.Errorf("file size %d bytes exceeds maximum allowed size %d MB",
-			resp.ContentLength, d.maxFileSizeMB)
+		return nil, fmt.Errorf("file size %d bytes exceeds maximum allowed size %d MB",
+			resp.ContentLength, maxFileSizeMB)
 	}
 
 	// Create the file path
@@ -91,24 +149,25 @@ func (d *Downloader) DownloadFile(fileURL, filename string) (string, error) {
 		d.logger.Error("Failed to create file",
 			zap.String("path", uniqueFilePath),
 			zap.Error(err))
-		return "", fmt.Errorf("failed to create file: %w", err)
+		return nil, fmt.Errorf("failed to create file: %w", err)
 	}
 	defer file.Close()
 
-	// Copy the file content
-	bytesWritten, err := io.Copy(file, resp.Body)
+	// Copy the file content while hashing it
+	hasher := sha256.New()
+	bytesWritten, err := io.Copy(io.MultiWriter(file, hasher), resp.Body)
 	if err != nil {
 		d.logger.Error("Failed to save file",
 			zap.String("path", uniqueFilePath),
 			zap.Error(err))
-		return "", fmt.Errorf("failed to save file: %w", err)
+		return nil, fmt.Errorf("failed to save file: %w", err)
 	}
 
 	// Final size check
 	if !d.IsFileSizeAllowed(bytesWritten) {
 		os.Remove(uniqueFilePath)
-		return "", fmt.Errorf("downloaded file size %d bytes exceeds maximum allowed size %d MB",
-			bytesWritten, d.maxFileSizeMB)
+		return nil, fmt.Errorf("downloaded file size %d bytes exceeds maximum allowed size %d MB",
+			bytesWritten, maxFileSizeMB)
 	}
 
 	d.logger.Info("File downloaded successfully",
@@ -116,7 +175,11 @@ func (d *Downloader) DownloadFile(fileURL, filename string) (string, error) {
 		zap.String("path", uniqueFilePath),
 		zap.Int64("size", bytesWritten))
 
-	return uniqueFilePath, nil
+	return &DownloadResult{
+		Path:   uniqueFilePath,
+		Size:   bytesWritten,
+		SHA256: hex.EncodeToString(hasher.Sum(nil)),
+	}, nil
 }
 
 func (d *Downloader) getUniqueFilePath(filePath string) string {
@@ -142,3 +205,13 @@ func (d *Downloader) getUniqueFilePath(filePath string) string {
 func (d *Downloader) GetDownloadFolder() string {
 	return d.downloadFolder
 }
+
+// WithoutURL drops the request URL from HTTP client errors. Telegram file URLs
+// contain the bot token, and these errors end up in logs and chat messages.
+func WithoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s request failed: %w", urlErr.Op, urlErr.Err)
+	}
+	return err
+}
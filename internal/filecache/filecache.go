@@ -0,0 +1,102 @@
+package filecache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/brauni/booklore-tg-bot/internal/storage"
+	"go.uber.org/zap"
+)
+
+// Entry is a Telegram file that was already uploaded for a Booklore book
+type Entry struct {
+	FileID       string    `json:"fileId"`
+	FileUniqueID string    `json:"fileUniqueId"`
+	FileName     string    `json:"fileName"`
+	Size         int64     `json:"size"`
+	CachedAt     time.Time `json:"cachedAt"`
+}
+
+// Cache maps Booklore book IDs to Telegram file IDs so books are only uploaded once
+type Cache struct {
+	entries map[int64]Entry
+	mutex   sync.RWMutex
+	file    *storage.JSONFile
+	logger  *zap.Logger
+}
+
+// NewCache creates a file ID cache backed by the given file. An empty path
+// keeps the cache in memory only.
+func NewCache(logger *zap.Logger, storagePath string) *Cache {
+	c := &Cache{
+		entries: make(map[int64]Entry),
+		logger:  logger,
+	}
+
+	if storagePath == "" {
+		return c
+	}
+
+	c.file = storage.NewJSONFile(storagePath)
+	found, err := c.file.Load(&c.entries)
+	if err != nil {
+		logger.Error("Failed to load Telegram file cache",
+			zap.String("path", storagePath),
+			zap.Error(err))
+		c.entries = make(map[int64]Entry)
+		return c
+	}
+	if found {
+		logger.Info("Loaded Telegram file cache from file",
+			zap.String("path", storagePath),
+			zap.Int("entry_count", len(c.entries)))
+	}
+
+	return c
+}
+
+// Get returns the cached Telegram file for a book
+func (c *Cache) Get(bookID int64) (Entry, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	entry, ok := c.entries[bookID]
+	return entry, ok
+}
+
+// Put caches the Telegram file uploaded for a book
+func (c *Cache) Put(bookID int64, entry Entry) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if entry.CachedAt.IsZero() {
+		entry.CachedAt = time.Now().UTC()
+	}
+	c.entries[bookID] = entry
+	c.save()
+}
+
+// Delete removes a stale cache entry, e.g. when Telegram rejects the file ID
+func (c *Cache) Delete(bookID int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, ok := c.entries[bookID]; !ok {
+		return
+	}
+	delete(c.entries, bookID)
+	c.save()
+}
+
+// save writes the cache to disk; callers must hold the write lock
+func (c *Cache) save() {
+	if c.file == nil {
+		return
+	}
+
+	if err := c.file.Save(c.entries); err != nil {
+		c.logger.Error("Failed to save Telegram file cache",
+			zap.String("path", c.file.Path()),
+			zap.Error(err))
+	}
+}
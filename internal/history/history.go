@@ -0,0 +1,249 @@
+package history
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/brauni/booklore-tg-bot/internal/storage"
+	"go.uber.org/zap"
+)
+
+// ImportStatus describes what happened to an upload after it was downloaded
+type ImportStatus string
+
+const (
+	// ImportPending means the file was saved and the Booklore import has not finished yet
+	ImportPending ImportStatus = "PENDING"
+	// ImportSkipped means no import was attempted (auto-import disabled or no library configured)
+	ImportSkipped ImportStatus = "SKIPPED"
+	// ImportImported means Booklore imported the file into a library
+	ImportImported ImportStatus = "IMPORTED"
+	// ImportFailed means the Booklore import failed or imported nothing
+	ImportFailed ImportStatus = "FAILED"
+	// ImportDeleted means the file was removed from the bookdrop
+	ImportDeleted ImportStatus = "DELETED"
+)
+
+// Record describes a single file uploaded through the bot
+type Record struct {
+	ID             int64        `json:"id"`
+	UserID         int64        `json:"userId"`
+	Username       string       `json:"username"`
+	ChatID         int64        `json:"chatId"`
+	OriginalName   string       `json:"originalName"`
+	SavedPath      string       `json:"savedPath"`
+	SHA256         string       `json:"sha256"`
+	Size           int64        `json:"size"`
+	TelegramFileID string       `json:"telegramFileId"`
+	BookloreFileID int64        `json:"bookloreFileId,omitempty"`
+	ImportStatus   ImportStatus `json:"importStatus"`
+	ImportMessage  string       `json:"importMessage,omitempty"`
+	CreatedAt      time.Time    `json:"createdAt"`
+	UpdatedAt      time.Time    `json:"updatedAt"`
+
+	// TargetLibraryID and TargetPathID are set when the upload was sent to a specific library
+	TargetLibraryID int64 `json:"targetLibraryId,omitempty"`
+	TargetPathID    int64 `json:"targetPathId,omitempty"`
+	// MimeType is the type Telegram reported for the upload, used by routing rules
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+// storedHistory is the on-disk representation of the history
+type storedHistory struct {
+	NextID  int64     `json:"nextId"`
+	Records []*Record `json:"records"`
+}
+
+// Store keeps the upload history with persistent storage
+type Store struct {
+	records []*Record
+	nextID  int64
+	mutex   sync.RWMutex
+	file    *storage.JSONFile
+	logger  *zap.Logger
+}
+
+// NewStore creates a history store backed by the given file. An empty path
+// keeps the history in memory only.
+func NewStore(logger *zap.Logger, storagePath string) *Store {
+	s := &Store{
+		nextID: 1,
+		logger: logger,
+	}
+
+	if storagePath == "" {
+		logger.Info("No history storage path provided, using in-memory storage only")
+		return s
+	}
+
+	s.file = storage.NewJSONFile(storagePath)
+
+	var stored storedHistory
+	found, err := s.file.Load(&stored)
+	if err != nil {
+		logger.Error("Failed to load upload history",
+			zap.String("path", storagePath),
+			zap.Error(err))
+		return s
+	}
+	if !found {
+		logger.Info("Upload history file does not exist, starting fresh",
+			zap.String("path", storagePath))
+		return s
+	}
+
+	s.records = stored.Records
+	s.nextID = stored.NextID
+	for _, rec := range s.records {
+		if rec.ID >= s.nextID {
+			s.nextID = rec.ID + 1
+		}
+	}
+
+	logger.Info("Loaded upload history from file",
+		zap.String("path", storagePath),
+		zap.Int("record_count", len(s.records)))
+
+	return s
+}
+
+// Add stores a new record and returns its assigned ID
+func (s *Store) Add(rec Record) int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now().UTC()
+	rec.ID = s.nextID
+	rec.CreatedAt = now
+	rec.UpdatedAt = now
+	if rec.ImportStatus == "" {
+		rec.ImportStatus = ImportPending
+	}
+
+	s.nextID++
+	s.records = append(s.records, &rec)
+	s.save()
+
+	return rec.ID
+}
+
+// Get returns a copy of the record with the given ID
+func (s *Store) Get(id int64) (Record, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if rec := s.find(id); rec != nil {
+		return *rec, true
+	}
+	return Record{}, false
+}
+
+// Update applies fn to the record with the given ID and persists the change
+func (s *Store) Update(id int64, fn func(rec *Record)) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	rec := s.find(id)
+	if rec == nil {
+		return fmt.Errorf("history record %d not found", id)
+	}
+
+	fn(rec)
+	rec.UpdatedAt = time.Now().UTC()
+	s.save()
+
+	return nil
+}
+
+// SetImportResult records the outcome of a Booklore import for an upload
+func (s *Store) SetImportResult(id int64, status ImportStatus, message string) error {
+	return s.Update(id, func(rec *Record) {
+		rec.ImportStatus = status
+		rec.ImportMessage = message
+	})
+}
+
+// FindByBookloreFileID returns the upload that became the given bookdrop file
+func (s *Store) FindByBookloreFileID(fileID int64) (Record, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	// Newest first, in case the same file was uploaded again
+	for i := len(s.records) - 1; i >= 0; i-- {
+		if s.records[i].BookloreFileID == fileID {
+			return *s.records[i], true
+		}
+	}
+	return Record{}, false
+}
+
+// FindBySavedName returns the newest upload saved under the given file name
+func (s *Store) FindBySavedName(name string) (Record, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for i := len(s.records) - 1; i >= 0; i-- {
+		if filepath.Base(s.records[i].SavedPath) == name {
+			return *s.records[i], true
+		}
+	}
+	return Record{}, false
+}
+
+// List returns records newest first. A userID of 0 lists every user's uploads.
+// The second return value is the total number of matching records.
+func (s *Store) List(userID int64, offset, limit int) ([]Record, int) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var matching []Record
+	for _, rec := range s.records {
+		if userID == 0 || rec.UserID == userID {
+			matching = append(matching, *rec)
+		}
+	}
+
+	sort.SliceStable(matching, func(i, j int) bool {
+		return matching[i].ID > matching[j].ID
+	})
+
+	total := len(matching)
+	if offset >= total {
+		return nil, total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+
+	return matching[offset:end], total
+}
+
+func (s *Store) find(id int64) *Record {
+	for _, rec := range s.records {
+		if rec.ID == id {
+			return rec
+		}
+	}
+	return nil
+}
+
+// save writes the history to disk; callers must hold the write lock
+func (s *Store) save() {
+	if s.file == nil {
+		return
+	}
+
+	stored := storedHistory{
+		NextID:  s.nextID,
+		Records: s.records,
+	}
+	if err := s.file.Save(stored); err != nil {
+		s.logger.Error("Failed to save upload history",
+			zap.String("path", s.file.Path()),
+			zap.Error(err))
+	}
+}
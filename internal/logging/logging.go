@@ -0,0 +1,82 @@
+package logging
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Config configures the bot's logger
+type Config struct {
+	// Level is the minimum level logged: debug, info, warn or error
+	Level string
+	// Format is "json" for production or "console" for human-readable development logs
+	Format string
+	// Secrets are values that never appear in logs, e.g. API tokens
+	Secrets []string
+	// RedactPatterns are additional regular expressions whose matches are redacted
+	RedactPatterns []string
+	// HashIdentities replaces user IDs and usernames with keyed hashes
+	HashIdentities bool
+	// HashKey keys the identity hashes so they can't be reversed by hashing every user ID
+	HashKey string
+}
+
+// builtinPatterns catch secrets that aren't known up front: Telegram bot tokens,
+// e.g. in file URLs, and bearer tokens in headers
+var builtinPatterns = []string{
+	`\d{5,}:[A-Za-z0-9_-]{30,}`,
+	`(?i)bearer\s+[A-Za-z0-9._~+/=-]+`,
+}
+
+// New creates a logger that redacts secrets from every message and field
+func New(cfg Config) (*zap.Logger, error) {
+	level, err := zapcore.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOG_LEVEL '%s': %w", cfg.Level, err)
+	}
+
+	var zapConfig zap.Config
+	switch strings.ToLower(cfg.Format) {
+	case "", "json":
+		zapConfig = zap.NewProductionConfig()
+	case "console", "development":
+		zapConfig = zap.NewDevelopmentConfig()
+	default:
+		return nil, fmt.Errorf("invalid LOG_FORMAT '%s', expected json or console", cfg.Format)
+	}
+	zapConfig.Level = zap.NewAtomicLevelAt(level)
+
+	redactor, err := newRedactor(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return zapConfig.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &redactingCore{Core: core, redactor: redactor}
+	}))
+}
+
+// SetSecrets replaces the secrets a logger created by New redacts, e.g. after a
+// token was rotated. Loggers derived from it with With or Named follow along.
+func SetSecrets(logger *zap.Logger, secrets []string) {
+	if core, ok := logger.Core().(*redactingCore); ok {
+		core.redactor.setSecrets(secrets)
+	}
+}
+
+// compilePatterns compiles the built-in and configured redaction patterns
+func compilePatterns(extra []string) ([]*regexp.Regexp, error) {
+	var patterns []*regexp.Regexp
+	for _, pattern := range append(append([]string(nil), builtinPatterns...), extra...) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern '%s': %w", pattern, err)
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns, nil
+}
@@ -0,0 +1,214 @@
+package logging
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// redacted replaces secrets in logs
+const redacted = "[REDACTED]"
+
+// identityKeys are the fields hashed when identities are hidden
+var identityKeys = map[string]bool{
+	"user_id":  true,
+	"chat_id":  true,
+	"username": true,
+}
+
+// redactor scrubs secrets and identities from log entries
+type redactor struct {
+	// secrets is replaced when the configuration is reloaded with new tokens
+	secrets  atomic.Pointer[strings.Replacer]
+	patterns []*regexp.Regexp
+	hashKey  []byte
+}
+
+func newRedactor(cfg Config) (*redactor, error) {
+	patterns, err := compilePatterns(cfg.RedactPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &redactor{patterns: patterns}
+	r.setSecrets(cfg.Secrets)
+
+	if cfg.HashIdentities {
+		r.hashKey = []byte(cfg.HashKey)
+	}
+
+	return r, nil
+}
+
+// setSecrets replaces the values redacted from every entry
+func (r *redactor) setSecrets(secrets []string) {
+	var pairs []string
+	for _, secret := range secrets {
+		// Very short values would redact half of every log line
+		if len(secret) >= 8 {
+			pairs = append(pairs, secret, redacted)
+		}
+	}
+	if len(pairs) == 0 {
+		r.secrets.Store(nil)
+		return
+	}
+	r.secrets.Store(strings.NewReplacer(pairs...))
+}
+
+// scrub removes secrets from a string
+func (r *redactor) scrub(s string) string {
+	if secrets := r.secrets.Load(); secrets != nil {
+		s = secrets.Replace(s)
+	}
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, redacted)
+	}
+	return s
+}
+
+// hash replaces an identity with a keyed hash that stays the same across log lines
+func (r *redactor) hash(value string) string {
+	mac := hmac.New(sha256.New, r.hashKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))[:12]
+}
+
+// field returns a copy of the field without secrets
+func (r *redactor) field(f zapcore.Field) zapcore.Field {
+	if r.hashKey != nil && identityKeys[f.Key] {
+		switch f.Type {
+		case zapcore.Int64Type, zapcore.Int32Type, zapcore.Uint64Type:
+			return zap.String(f.Key, r.hash(fmt.Sprint(f.Integer)))
+		case zapcore.StringType:
+			if f.String == "" {
+				return f
+			}
+			return zap.String(f.Key, r.hash(f.String))
+		}
+	}
+
+	switch f.Type {
+	case zapcore.StringType:
+		f.String = r.scrub(f.String)
+		return f
+	case zapcore.ErrorType:
+		if err, ok := f.Interface.(error); ok {
+			return zap.String(f.Key, r.scrub(err.Error()))
+		}
+		return f
+	case zapcore.StringerType:
+		if s, ok := f.Interface.(fmt.Stringer); ok {
+			return zap.String(f.Key, r.scrub(s.String()))
+		}
+		return f
+	case zapcore.ByteStringType:
+		if b, ok := f.Interface.([]byte); ok {
+			return zap.String(f.Key, r.scrub(string(b)))
+		}
+		return f
+	case zapcore.ArrayMarshalerType, zapcore.ObjectMarshalerType, zapcore.ReflectType, zapcore.InlineMarshalerType:
+		// Encode structured values to find strings nested inside them
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		changed := false
+		for key, value := range enc.Fields {
+			scrubbed, valueChanged := r.value(value)
+			if valueChanged {
+				enc.Fields[key] = scrubbed
+				changed = true
+			}
+		}
+		if !changed {
+			return f
+		}
+		if f.Type == zapcore.InlineMarshalerType {
+			return zap.Reflect(f.Key, enc.Fields)
+		}
+		return zap.Reflect(f.Key, enc.Fields[f.Key])
+	default:
+		return f
+	}
+}
+
+// value scrubs strings nested in an encoded value and reports whether any changed
+func (r *redactor) value(v any) (any, bool) {
+	switch v := v.(type) {
+	case string:
+		scrubbed := r.scrub(v)
+		return scrubbed, scrubbed != v
+	case map[string]any:
+		changed := false
+		for key, nested := range v {
+			if scrubbed, nestedChanged := r.value(nested); nestedChanged {
+				v[key] = scrubbed
+				changed = true
+			}
+		}
+		return v, changed
+	case []any:
+		changed := false
+		for i, nested := range v {
+			if scrubbed, nestedChanged := r.value(nested); nestedChanged {
+				v[i] = scrubbed
+				changed = true
+			}
+		}
+		return v, changed
+	default:
+		// Reflected values are kept as they are, so they're checked in their JSON form
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return v, false
+		}
+		scrubbed := r.scrub(string(encoded))
+		if scrubbed == string(encoded) {
+			return v, false
+		}
+		if json.Valid([]byte(scrubbed)) {
+			return json.RawMessage(scrubbed), true
+		}
+		return scrubbed, true
+	}
+}
+
+func (r *redactor) fields(fields []zapcore.Field) []zapcore.Field {
+	scrubbed := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		scrubbed[i] = r.field(f)
+	}
+	return scrubbed
+}
+
+// redactingCore scrubs every entry before passing it to the wrapped core
+type redactingCore struct {
+	zapcore.Core
+	redactor *redactor
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{
+		Core:     c.Core.With(c.redactor.fields(fields)),
+		redactor: c.redactor,
+	}
+}
+
+func (c *redactingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = c.redactor.scrub(entry.Message)
+	return c.Core.Write(entry, c.redactor.fields(fields))
+}
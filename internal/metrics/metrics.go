@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "booklore_bot"
+
+// registry holds the bot's metrics; it is separate from the default registry
+// so packages can't register metrics behind the bot's back
+var registry = prometheus.NewRegistry()
+
+var (
+	downloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "downloads_total",
+		Help:      "Files downloaded from Telegram by result.",
+	}, []string{"result"})
+
+	downloadBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "download_bytes_total",
+		Help:      "Bytes of files downloaded from Telegram.",
+	})
+
+	imports = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "imports_total",
+		Help:      "Booklore imports of uploads by outcome.",
+	}, []string{"outcome"})
+
+	apiRequests = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "booklore_request_duration_seconds",
+		Help:      "Latency of Booklore API requests by endpoint.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "endpoint", "code"})
+
+	apiErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "booklore_errors_total",
+		Help:      "Booklore API errors by type.",
+	}, []string{"type"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		downloads,
+		downloadBytes,
+		imports,
+		apiRequests,
+		apiErrors,
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObserveDownload counts a download attempt and, if it succeeded, its size
+func ObserveDownload(size int64, err error) {
+	if err != nil {
+		downloads.WithLabelValues("failed").Inc()
+		return
+	}
+	downloads.WithLabelValues("success").Inc()
+	downloadBytes.Add(float64(size))
+}
+
+// ObserveImport counts the outcome of an import, e.g. "IMPORTED" or "FAILED"
+func ObserveImport(outcome string) {
+	imports.WithLabelValues(outcome).Inc()
+}
+
+// ObserveAPIRequest records the latency of a Booklore API request. code is the
+// HTTP status code, or 0 if no response was received.
+func ObserveAPIRequest(method, endpoint string, code int, duration time.Duration) {
+	apiRequests.WithLabelValues(method, endpoint, statusLabel(code)).Observe(duration.Seconds())
+}
+
+// ObserveAPIError counts a Booklore API error by its type
+func ObserveAPIError(errorType string) {
+	apiErrors.WithLabelValues(errorType).Inc()
+}
+
+// RegisterQueue reports the depth of a queue, read whenever metrics are scraped
+func RegisterQueue(name string, depth func() int) {
+	registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "queue_depth",
+		Help:        "Items waiting in a queue.",
+		ConstLabels: prometheus.Labels{"queue": name},
+	}, func() float64 {
+		return float64(depth())
+	}))
+}
+
+// statusLabel turns a status code into a label value
+func statusLabel(code int) string {
+	if code == 0 {
+		return "none"
+	}
+	return strconv.Itoa(code)
+}
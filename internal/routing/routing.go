@@ -0,0 +1,310 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brauni/booklore-tg-bot/internal/booklore"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// reloadInterval is how often the rules file is checked for changes
+const reloadInterval = 10 * time.Second
+
+// Rule sends uploads matching all its conditions to a target
+type Rule struct {
+	Name   string `yaml:"name"`
+	Match  Match  `yaml:"match"`
+	Target string `yaml:"target"`
+
+	spec booklore.TargetSpec
+}
+
+// Match lists the conditions of a rule. Every non-empty condition must match;
+// within a list any entry may match.
+type Match struct {
+	// Extensions such as ".cbz"
+	Extensions []string `yaml:"extensions"`
+	// MIMETypes such as "application/epub+zip"; "application/*" matches a whole family
+	MIMETypes []string `yaml:"mimeTypes"`
+	// FileName is a glob such as "*manga*", matched case-insensitively
+	FileName string `yaml:"fileName"`
+	// Languages such as "de"; "de" also matches "de-AT"
+	Languages []string `yaml:"languages"`
+	// Series are globs matched case-insensitively against the series name
+	Series []string `yaml:"series"`
+	// Uploaders are Telegram user IDs
+	Uploaders []int64 `yaml:"uploaders"`
+	// Chats are Telegram chat IDs, e.g. a family group
+	Chats []int64 `yaml:"chats"`
+}
+
+// rulesFile is the layout of the rules file
+type rulesFile struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Input describes an upload to route
+type Input struct {
+	FileName string
+	MIMEType string
+	UserID   int64
+	ChatID   int64
+	// Language and Series come from the metadata Booklore parsed, if available yet
+	Language string
+	Series   string
+}
+
+// Decision is the rule an upload matched
+type Decision struct {
+	Rule   string
+	Target booklore.TargetSpec
+}
+
+// Router picks import targets for uploads from rules in a YAML file and
+// reloads the file when it changes
+type Router struct {
+	path    string
+	rules   []Rule
+	modTime time.Time
+	missing bool
+	mutex   sync.RWMutex
+	logger  *zap.Logger
+}
+
+// NewRouter loads rules from a file. A missing file means no rules until it is created.
+func NewRouter(logger *zap.Logger, rulesPath string) (*Router, error) {
+	r := &Router{
+		path:   rulesPath,
+		logger: logger,
+	}
+
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Path returns the rules file path
+func (r *Router) Path() string {
+	return r.path
+}
+
+// Rules returns the loaded rules
+func (r *Router) Rules() []Rule {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.rules
+}
+
+// Route returns the first rule matching the upload
+func (r *Router) Route(in Input) (*Decision, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, rule := range r.rules {
+		if rule.Match.matches(in) {
+			return &Decision{Rule: rule.Name, Target: rule.spec}, true
+		}
+		if rule.Match.missesMetadata(in) {
+			r.logger.Debug("Routing rule skipped, the upload's metadata is missing",
+				zap.String("rule", rule.Name),
+				zap.String("file_name", in.FileName),
+				zap.Bool("has_language", in.Language != ""),
+				zap.Bool("has_series", in.Series != ""))
+		}
+	}
+	return nil, false
+}
+
+// Run reloads the rules whenever the file changes until the context is cancelled.
+// A broken file is logged and the previous rules stay active.
+func (r *Router) Run(ctx context.Context) {
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.reload(); err != nil {
+				r.logger.Error("Failed to reload routing rules, keeping previous rules",
+					zap.String("path", r.path),
+					zap.Error(err))
+			}
+		}
+	}
+}
+
+// reload reads the rules file if it changed since the last load
+func (r *Router) reload() error {
+	info, err := os.Stat(r.path)
+	if errors.Is(err, os.ErrNotExist) {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		if !r.missing {
+			r.logger.Info("Routing rules file not found, no rules active",
+				zap.String("path", r.path))
+		}
+		r.rules = nil
+		r.modTime = time.Time{}
+		r.missing = true
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat routing rules: %w", err)
+	}
+
+	r.mutex.RLock()
+	unchanged := info.ModTime().Equal(r.modTime)
+	r.mutex.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	rules, err := loadRules(r.path)
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	r.rules = rules
+	r.modTime = info.ModTime()
+	r.missing = false
+	r.mutex.Unlock()
+
+	r.logger.Info("Loaded routing rules",
+		zap.String("path", r.path),
+		zap.Int("rule_count", len(rules)))
+	return nil
+}
+
+// loadRules parses and validates a rules file
+func loadRules(rulesPath string) ([]Rule, error) {
+	data, err := os.ReadFile(rulesPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read routing rules: %w", err)
+	}
+
+	var file rulesFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse routing rules: %w", err)
+	}
+
+	for i := range file.Rules {
+		rule := &file.Rules[i]
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+
+		rule.spec, err = booklore.ParseTargetSpec(rule.Target)
+		if err != nil {
+			return nil, fmt.Errorf("routing rule '%s': %w", rule.Name, err)
+		}
+
+		if rule.Match.FileName != "" {
+			if _, err := path.Match(rule.Match.FileName, ""); err != nil {
+				return nil, fmt.Errorf("routing rule '%s': invalid fileName pattern: %w", rule.Name, err)
+			}
+		}
+		for _, pattern := range rule.Match.Series {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("routing rule '%s': invalid series pattern: %w", rule.Name, err)
+			}
+		}
+	}
+
+	return file.Rules, nil
+}
+
+func (m *Match) matches(in Input) bool {
+	if len(m.Extensions) > 0 && !matchesAny(m.Extensions, func(ext string) bool {
+		return strings.EqualFold("."+strings.TrimPrefix(ext, "."), filepath.Ext(in.FileName))
+	}) {
+		return false
+	}
+
+	if len(m.MIMETypes) > 0 && !matchesAny(m.MIMETypes, func(mimeType string) bool {
+		if family, ok := strings.CutSuffix(mimeType, "/*"); ok {
+			return strings.HasPrefix(strings.ToLower(in.MIMEType), strings.ToLower(family)+"/")
+		}
+		return strings.EqualFold(mimeType, in.MIMEType)
+	}) {
+		return false
+	}
+
+	if m.FileName != "" && !globMatch(m.FileName, in.FileName) {
+		return false
+	}
+
+	if len(m.Languages) > 0 && !matchesAny(m.Languages, func(lang string) bool {
+		return strings.EqualFold(lang, in.Language) ||
+			strings.HasPrefix(strings.ToLower(in.Language), strings.ToLower(lang)+"-")
+	}) {
+		return false
+	}
+
+	if len(m.Series) > 0 && !matchesAny(m.Series, func(pattern string) bool {
+		return in.Series != "" && globMatch(pattern, in.Series)
+	}) {
+		return false
+	}
+
+	if len(m.Uploaders) > 0 && !containsID(m.Uploaders, in.UserID) {
+		return false
+	}
+
+	if len(m.Chats) > 0 && !containsID(m.Chats, in.ChatID) {
+		return false
+	}
+
+	return true
+}
+
+// missesMetadata reports whether a rule failed only because the language or
+// series it needs isn't known
+func (m *Match) missesMetadata(in Input) bool {
+	missing := (len(m.Languages) > 0 && in.Language == "") || (len(m.Series) > 0 && in.Series == "")
+	if !missing {
+		return false
+	}
+
+	rest := *m
+	rest.Languages = nil
+	rest.Series = nil
+	return rest.matches(in)
+}
+
+func matchesAny(values []string, match func(string) bool) bool {
+	for _, v := range values {
+		if match(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// globMatch matches a shell pattern case-insensitively; patterns were validated on load
+func globMatch(pattern, value string) bool {
+	matched, _ := path.Match(strings.ToLower(pattern), strings.ToLower(value))
+	return matched
+}
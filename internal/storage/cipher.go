@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// KeySize is the length of encryption keys, selecting AES-256
+const KeySize = 32
+
+// cipherVersion starts sealed data that names the key it was encrypted with
+const cipherVersion byte = 1
+
+// keyIDSize is the length of the key fingerprint stored with sealed data
+const keyIDSize = 4
+
+// Cipher encrypts data kept on disk with AES-GCM, so it can't be read or
+// tampered with without the key. Data is encrypted with the current key and
+// can be decrypted with previous keys too, so keys can be rotated.
+type Cipher struct {
+	currentID []byte
+	keys      map[string]cipher.AEAD
+	// order lists the keys current first, for data that doesn't name its key
+	order []cipher.AEAD
+}
+
+// ParseKey decodes a base64 encoded key, e.g. created with "openssl rand -base64 32"
+func ParseKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("key is not valid base64: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", KeySize, len(key))
+	}
+	return key, nil
+}
+
+// NewCipher creates a cipher encrypting with key and also decrypting data
+// encrypted with one of the previous keys. Keys are KeySize bytes.
+func NewCipher(key []byte, previous ...[]byte) (*Cipher, error) {
+	c := &Cipher{
+		currentID: keyID(key),
+		keys:      make(map[string]cipher.AEAD),
+	}
+
+	for _, k := range append([][]byte{key}, previous...) {
+		if len(k) != KeySize {
+			return nil, fmt.Errorf("key must be %d bytes, got %d", KeySize, len(k))
+		}
+
+		block, err := aes.NewCipher(k)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cipher: %w", err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cipher: %w", err)
+		}
+
+		c.keys[string(keyID(k))] = aead
+		c.order = append(c.order, aead)
+	}
+
+	return c, nil
+}
+
+// keyID fingerprints a key, so sealed data can name its key without revealing it
+func keyID(key []byte) []byte {
+	sum := sha256.Sum256(key)
+	return sum[:keyIDSize]
+}
+
+// Encrypt seals plaintext with the current key. The result starts with the
+// format version, the key's fingerprint and a random nonce.
+func (c *Cipher) Encrypt(plaintext []byte) ([]byte, error) {
+	aead := c.keys[string(c.currentID)]
+
+	header := append([]byte{cipherVersion}, c.currentID...)
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := append(header, nonce...)
+	return aead.Seal(sealed, nonce, plaintext, header), nil
+}
+
+// Decrypt opens data sealed by Encrypt with the current or a previous key
+func (c *Cipher) Decrypt(data []byte) ([]byte, error) {
+	headerSize := 1 + keyIDSize
+	if len(data) > headerSize && data[0] == cipherVersion {
+		if aead, ok := c.keys[string(data[1:headerSize])]; ok {
+			return open(aead, data[headerSize:], data[:headerSize])
+		}
+	}
+
+	// Data sealed before keys were named only holds the nonce and ciphertext
+	for _, aead := range c.order {
+		if plaintext, err := open(aead, data, nil); err == nil {
+			return plaintext, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to decrypt, wrong key or corrupted data")
+}
+
+func open(aead cipher.AEAD, data, additional []byte) ([]byte, error) {
+	size := aead.NonceSize()
+	if len(data) < size {
+		return nil, fmt.Errorf("encrypted data is too short")
+	}
+
+	plaintext, err := aead.Open(nil, data[:size], data[size:], additional)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt, wrong key or corrupted data: %w", err)
+	}
+	return plaintext, nil
+}
+
+// IsCurrent reports whether data was sealed with the current key, so data
+// sealed with a previous key can be encrypted again
+func (c *Cipher) IsCurrent(data []byte) bool {
+	return len(data) > 1+keyIDSize && data[0] == cipherVersion && bytes.Equal(data[1:1+keyIDSize], c.currentID)
+}
+
+// EncryptString seals a value for a text format like JSON
+func (c *Cipher) EncryptString(value string) (string, error) {
+	data, err := c.Encrypt([]byte(value))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// DecryptString opens a value sealed by EncryptString
+func (c *Cipher) DecryptString(value string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", fmt.Errorf("encrypted value is not valid base64: %w", err)
+	}
+
+	plaintext, err := c.Decrypt(data)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// IsCurrentString reports whether a value sealed by EncryptString uses the current key
+func (c *Cipher) IsCurrentString(value string) bool {
+	data, err := base64.StdEncoding.DecodeString(value)
+	return err == nil && c.IsCurrent(data)
+}
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// encryptedPrefix starts every encrypted file or line. Plain JSON never does.
+const encryptedPrefix = "enc:"
+
+// fileCipher encrypts the state files, nil keeps them as plain JSON
+var fileCipher atomic.Pointer[Cipher]
+
+// UseCipher encrypts every file written from now on with c, or stops
+// encrypting if c is nil. Plain files are still read and are encrypted the
+// next time they are written. Call it before the stores are created, so files
+// are migrated while they are loaded.
+func UseCipher(c *Cipher) {
+	fileCipher.Store(c)
+}
+
+// seal encrypts data if a cipher is in use
+func seal(data []byte) ([]byte, error) {
+	c := fileCipher.Load()
+	if c == nil {
+		return data, nil
+	}
+
+	sealed, err := c.Encrypt(data)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(encryptedPrefix + base64.StdEncoding.EncodeToString(sealed)), nil
+}
+
+// unseal returns the plain content of a file or line. stale reports that it
+// should be written again, because it isn't encrypted with the current key.
+func unseal(data []byte) (plain []byte, stale bool, err error) {
+	c := fileCipher.Load()
+
+	trimmed := bytes.TrimSpace(data)
+	if !bytes.HasPrefix(trimmed, []byte(encryptedPrefix)) {
+		return data, c != nil && len(trimmed) > 0, nil
+	}
+	if c == nil {
+		return nil, false, fmt.Errorf("content is encrypted but no encryption key is configured")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(string(trimmed[len(encryptedPrefix):]))
+	if err != nil {
+		return nil, false, fmt.Errorf("encrypted content is not valid base64: %w", err)
+	}
+	plain, err = c.Decrypt(sealed)
+	if err != nil {
+		return nil, false, err
+	}
+	return plain, !c.IsCurrent(sealed), nil
+}
+
+// stateFile opens a file of the state folder by its extension
+func stateFile(path string) (interface{ Reencrypt() error }, bool) {
+	switch filepath.Ext(path) {
+	case ".json":
+		return NewJSONFile(path), true
+	case ".jsonl":
+		return NewJSONLinesFile(path), true
+	}
+	return nil, false
+}
+
+// stateFiles lists the JSON and JSON lines files directly in dir
+func stateFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		// Skip temporary files of interrupted writes
+		if _, ok := stateFile(path); ok && entry.Type().IsRegular() && !strings.Contains(entry.Name(), ".tmp-") {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// VerifyDir checks that every state file in dir can be read with the current
+// cipher. Stores start empty when their file can't be read, so starting with a
+// missing or wrong key would overwrite the encrypted state.
+func VerifyDir(dir string) error {
+	paths, err := stateFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		// JSON lines files may mix lines written before and after encryption was enabled
+		parts := [][]byte{data}
+		if filepath.Ext(path) == ".jsonl" {
+			parts = bytes.Split(data, []byte("\n"))
+		}
+		for _, part := range parts {
+			if _, _, err := unseal(part); err != nil {
+				return fmt.Errorf("failed to read %s: %w", path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ReencryptDir writes every state file in dir again with the current cipher,
+// e.g. after rotating the key, and returns how many files were written. The bot
+// must not be running.
+func ReencryptDir(dir string) (int, error) {
+	paths, err := stateFiles(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, path := range paths {
+		file, _ := stateFile(path)
+		if err := file.Reencrypt(); err != nil {
+			return i, err
+		}
+	}
+	return len(paths), nil
+}
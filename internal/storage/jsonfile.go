@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// JSONFile persists a single JSON document on disk
+type JSONFile struct {
+	path string
+}
+
+// NewJSONFile creates a JSON file store for the given path
+func NewJSONFile(path string) *JSONFile {
+	return &JSONFile{path: path}
+}
+
+// Path returns the location of the file on disk
+func (f *JSONFile) Path() string {
+	return f.path
+}
+
+// Load decodes the file into v. It returns false if the file does not exist yet.
+// A plain file, or one encrypted with a previous key, is encrypted with the
+// current key right away.
+func (f *JSONFile) Load(v interface{}) (bool, error) {
+	data, stale, err := f.read()
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to parse %s: %w", f.path, err)
+	}
+
+	// If this fails the file is still readable and is encrypted on the next save
+	if stale {
+		f.write(data)
+	}
+
+	return true, nil
+}
+
+// Save encodes v and replaces the file atomically
+func (f *JSONFile) Save(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", f.path, err)
+	}
+
+	return f.write(data)
+}
+
+// Reencrypt writes the file again with the current cipher
+func (f *JSONFile) Reencrypt() error {
+	data, _, err := f.read()
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return f.write(data)
+}
+
+// read returns the plain content of the file and whether it should be encrypted again
+func (f *JSONFile) read() ([]byte, bool, error) {
+	data, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return nil, false, err
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read %s: %w", f.path, err)
+	}
+
+	plain, stale, err := unseal(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read %s: %w", f.path, err)
+	}
+	return plain, stale, nil
+}
+
+// write encrypts data if a cipher is in use and replaces the file
+func (f *JSONFile) write(data []byte) error {
+	sealed, err := seal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt %s: %w", f.path, err)
+	}
+	return writeFileAtomic(f.path, sealed)
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into place
+// so readers never observe a partially written file
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(tmpPath, 0600); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to set permissions on %s: %w", path, err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+
+	return nil
+}
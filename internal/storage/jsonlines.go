@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// maxLineSize bounds a single record in a JSON lines file
+const maxLineSize = 1 << 20
+
+// JSONLinesFile persists records as one JSON document per line. Records are
+// only ever appended, except when old ones are removed with Filter. With a
+// cipher in use each line is encrypted on its own.
+type JSONLinesFile struct {
+	path  string
+	mutex sync.Mutex
+}
+
+// NewJSONLinesFile creates a JSON lines store for the given path
+func NewJSONLinesFile(path string) *JSONLinesFile {
+	return &JSONLinesFile{path: path}
+}
+
+// Path returns the location of the file on disk
+func (f *JSONLinesFile) Path() string {
+	return f.path
+}
+
+// Append encodes v and adds it as a new line
+func (f *JSONLinesFile) Append(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s record: %w", f.path, err)
+	}
+	data, err = seal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt %s record: %w", f.path, err)
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", f.path, err)
+	}
+
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", f.path, err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to append to %s: %w", f.path, err)
+	}
+	return file.Sync()
+}
+
+// Each calls fn with every line, oldest first. A missing file has no lines.
+func (f *JSONLinesFile) Each(fn func(line []byte) error) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	_, err := f.each(fn)
+	return err
+}
+
+// Migrate encrypts the file with the current key if any line is plain or was
+// encrypted with a previous key. Logs call it once when they are opened.
+func (f *JSONLinesFile) Migrate() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	stale, err := f.each(func(line []byte) error { return nil })
+	if err != nil || !stale {
+		return err
+	}
+	_, err = f.rewrite(func(line []byte) bool { return true }, true)
+	return err
+}
+
+// Filter rewrites the file with only the lines keep accepts and returns how many were removed
+func (f *JSONLinesFile) Filter(keep func(line []byte) bool) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.rewrite(keep, false)
+}
+
+// Reencrypt writes every line again with the current cipher
+func (f *JSONLinesFile) Reencrypt() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	_, err := f.rewrite(func(line []byte) bool { return true }, true)
+	return err
+}
+
+// rewrite replaces the file with the lines keep accepts if any were removed,
+// or always if force is set; callers must hold the lock
+func (f *JSONLinesFile) rewrite(keep func(line []byte) bool, force bool) (int, error) {
+	var kept bytes.Buffer
+	removed := 0
+	_, err := f.each(func(line []byte) error {
+		if !keep(line) {
+			removed++
+			return nil
+		}
+
+		sealed, err := seal(line)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt %s record: %w", f.path, err)
+		}
+		kept.Write(sealed)
+		kept.WriteByte('\n')
+		return nil
+	})
+	if err != nil || (removed == 0 && !force) {
+		return 0, err
+	}
+
+	if err := writeFileAtomic(f.path, kept.Bytes()); err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
+// each reads the lines and reports whether any of them is not encrypted with
+// the current key; callers must hold the lock
+func (f *JSONLinesFile) each(fn func(line []byte) error) (bool, error) {
+	file, err := os.Open(f.path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to open %s: %w", f.path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	stale := false
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		line, lineStale, err := unseal(line)
+		if err != nil {
+			return false, fmt.Errorf("failed to read %s: %w", f.path, err)
+		}
+		stale = stale || lineStale
+		if err := fn(line); err != nil {
+			return false, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", f.path, err)
+	}
+	return stale, nil
+}
@@ -0,0 +1,197 @@
+package subscriptions
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brauni/booklore-tg-bot/internal/storage"
+	"go.uber.org/zap"
+)
+
+// Subscription holds a user's bookdrop notification settings
+type Subscription struct {
+	Enabled bool `json:"enabled"`
+	// QuietHours holds notifications back, e.g. "22:00-07:00"; empty means never
+	QuietHours string `json:"quietHours,omitempty"`
+}
+
+// QuietHours is a daily time window in minutes after midnight. The window may wrap past midnight.
+type QuietHours struct {
+	Start int
+	End   int
+}
+
+// ParseQuietHours parses "HH:MM-HH:MM" or "HH-HH"
+func ParseQuietHours(value string) (QuietHours, error) {
+	startStr, endStr, found := strings.Cut(strings.TrimSpace(value), "-")
+	if !found {
+		return QuietHours{}, fmt.Errorf("invalid quiet hours '%s', expected HH:MM-HH:MM", value)
+	}
+
+	start, err := parseClock(startStr)
+	if err != nil {
+		return QuietHours{}, err
+	}
+	end, err := parseClock(endStr)
+	if err != nil {
+		return QuietHours{}, err
+	}
+	if start == end {
+		return QuietHours{}, fmt.Errorf("quiet hours '%s' start and end at the same time", value)
+	}
+
+	return QuietHours{Start: start, End: end}, nil
+}
+
+// parseClock parses "HH:MM" or "HH" into minutes after midnight
+func parseClock(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	hourStr, minuteStr, hasMinutes := strings.Cut(value, ":")
+
+	hour, err := strconv.Atoi(hourStr)
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, fmt.Errorf("invalid time '%s'", value)
+	}
+
+	minute := 0
+	if hasMinutes {
+		minute, err = strconv.Atoi(minuteStr)
+		if err != nil || minute < 0 || minute > 59 {
+			return 0, fmt.Errorf("invalid time '%s'", value)
+		}
+	}
+
+	return hour*60 + minute, nil
+}
+
+// Contains reports whether t falls inside the window
+func (q QuietHours) Contains(t time.Time) bool {
+	minutes := t.Hour()*60 + t.Minute()
+	if q.Start < q.End {
+		return minutes >= q.Start && minutes < q.End
+	}
+	return minutes >= q.Start || minutes < q.End
+}
+
+// String formats the window as "HH:MM-HH:MM"
+func (q QuietHours) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", q.Start/60, q.Start%60, q.End/60, q.End%60)
+}
+
+// Store keeps the notification settings of every user with persistent storage
+type Store struct {
+	subscriptions map[int64]*Subscription
+	mutex         sync.RWMutex
+	file          *storage.JSONFile
+	logger        *zap.Logger
+}
+
+// NewStore creates a subscription store backed by the given file. An empty
+// path keeps the subscriptions in memory only.
+func NewStore(logger *zap.Logger, storagePath string) *Store {
+	s := &Store{
+		subscriptions: make(map[int64]*Subscription),
+		logger:        logger,
+	}
+
+	if storagePath == "" {
+		return s
+	}
+
+	s.file = storage.NewJSONFile(storagePath)
+	if _, err := s.file.Load(&s.subscriptions); err != nil {
+		logger.Error("Failed to load subscriptions",
+			zap.String("path", storagePath),
+			zap.Error(err))
+		s.subscriptions = make(map[int64]*Subscription)
+	}
+
+	return s
+}
+
+// Get returns a user's settings
+func (s *Store) Get(userID int64) Subscription {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if sub, ok := s.subscriptions[userID]; ok {
+		return *sub
+	}
+	return Subscription{}
+}
+
+// Toggle switches notifications on or off and returns the new state
+func (s *Store) Toggle(userID int64) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	sub := s.subscription(userID)
+	sub.Enabled = !sub.Enabled
+	s.save()
+	return sub.Enabled
+}
+
+// SetQuietHours stores a user's quiet hours; an empty value removes them
+func (s *Store) SetQuietHours(userID int64, quietHours string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.subscription(userID).QuietHours = quietHours
+	s.save()
+}
+
+// Subscribers returns the users with notifications enabled, sorted by ID
+func (s *Store) Subscribers() []int64 {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var userIDs []int64
+	for userID, sub := range s.subscriptions {
+		if sub.Enabled {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+	return userIDs
+}
+
+// IsQuiet reports whether a user's quiet hours include t
+func (s *Store) IsQuiet(userID int64, t time.Time) bool {
+	sub := s.Get(userID)
+	if sub.QuietHours == "" {
+		return false
+	}
+
+	quiet, err := ParseQuietHours(sub.QuietHours)
+	if err != nil {
+		return false
+	}
+	return quiet.Contains(t)
+}
+
+// subscription returns a user's settings for modification; callers must hold the write lock
+func (s *Store) subscription(userID int64) *Subscription {
+	sub, ok := s.subscriptions[userID]
+	if !ok {
+		sub = &Subscription{}
+		s.subscriptions[userID] = sub
+	}
+	return sub
+}
+
+// save writes the subscriptions to disk; callers must hold the write lock
+func (s *Store) save() {
+	if s.file == nil {
+		return
+	}
+
+	if err := s.file.Save(s.subscriptions); err != nil {
+		s.logger.Error("Failed to save subscriptions",
+			zap.String("path", s.file.Path()),
+			zap.Error(err))
+	}
+}
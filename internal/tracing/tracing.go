@@ -0,0 +1,120 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// instrumentationName identifies the bot's spans
+const instrumentationName = "github.com/brauni/booklore-tg-bot"
+
+// Config configures tracing
+type Config struct {
+	// Enabled exports spans over OTLP/HTTP; the exporter reads the standard
+	// OTEL_EXPORTER_OTLP_* variables for its endpoint, headers and TLS
+	Enabled     bool
+	ServiceName string
+}
+
+// Setup installs an OTLP exporter if tracing is enabled. The returned function
+// flushes pending spans and must be called on shutdown.
+func Setup(ctx context.Context, cfg Config, logger *zap.Logger) (func(context.Context) error, error) {
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	provider := Install(exporter, cfg.ServiceName)
+	logger.Info("Tracing enabled",
+		zap.String("service_name", cfg.ServiceName))
+
+	return provider.Shutdown, nil
+}
+
+// Install makes the exporter receive every span. Tests can pass an in-memory
+// exporter from go.opentelemetry.io/otel/sdk/trace/tracetest.
+func Install(exporter sdktrace.SpanExporter, serviceName string) *sdktrace.TracerProvider {
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(serviceName),
+		)),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return provider
+}
+
+// Start starts a span as a child of the span in ctx, if any
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartRequest starts a client span for an outgoing HTTP request and adds the
+// trace context to its headers, so the server can continue the trace. route
+// names the endpoint without IDs, e.g. "/api/v1/books/{id}".
+func StartRequest(req *http.Request, route string) (*http.Request, trace.Span) {
+	ctx, span := otel.Tracer(instrumentationName).Start(req.Context(), req.Method+" "+route,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.URLTemplate(route),
+			semconv.ServerAddress(req.URL.Hostname()),
+		))
+
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	return req, span
+}
+
+// EndRequest records the response status, or the error if there was no response, and ends the span
+func EndRequest(span trace.Span, resp *http.Response, err error) {
+	if resp != nil {
+		span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+		if resp.StatusCode >= 400 && err == nil {
+			span.SetStatus(codes.Error, resp.Status)
+		}
+	}
+	End(span, err)
+}
+
+// End records err on the span, if any, and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceID returns a log field with the trace ID of the span in ctx, so log lines
+// can be matched with their trace
+func TraceID(ctx context.Context) zap.Field {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return zap.Skip()
+	}
+	return zap.String("trace_id", spanContext.TraceID().String())
+}
@@ -0,0 +1,201 @@
+package watcher
+
+import (
+	"context"
+	"time"
+
+	"github.com/brauni/booklore-tg-bot/internal/booklore"
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// ChangeKind describes why a bookdrop file is reported
+type ChangeKind string
+
+const (
+	// ChangeNew means a file appeared in the bookdrop
+	ChangeNew ChangeKind = "NEW"
+	// ChangeFailed means Booklore failed to process a file
+	ChangeFailed ChangeKind = "FAILED"
+	// ChangePendingReview means a file waits for its metadata to be reviewed
+	ChangePendingReview ChangeKind = "PENDING_REVIEW"
+)
+
+// Change is a bookdrop file that needs attention
+type Change struct {
+	Kind ChangeKind
+	File booklore.BookdropFile
+}
+
+// settleDelay lets Booklore pick up files written to the folder before the bookdrop is checked
+const settleDelay = 10 * time.Second
+
+// Config configures a BookdropWatcher
+type Config struct {
+	// PollInterval is how often the bookdrop is checked without other triggers
+	PollInterval time.Duration
+	// Folder is the mounted bookdrop folder to watch for new files; empty disables folder watching
+	Folder string
+}
+
+// BookdropWatcher reports files that reach the bookdrop, however they got there.
+// It checks the bookdrop periodically, when files are written to the bookdrop
+// folder and when Booklore announces bookdrop changes.
+type BookdropWatcher struct {
+	client   *booklore.Client
+	events   *booklore.EventStream
+	config   Config
+	notifier func(changes []Change)
+	// seen holds the last known status of every file; nil until the first check
+	seen        map[int64]string
+	lastSummary *booklore.BookdropNotification
+	logger      *zap.Logger
+}
+
+// NewBookdropWatcher creates a watcher. events may be nil if Booklore events are disabled.
+func NewBookdropWatcher(client *booklore.Client, events *booklore.EventStream, logger *zap.Logger, config Config) *BookdropWatcher {
+	return &BookdropWatcher{
+		client: client,
+		events: events,
+		config: config,
+		logger: logger,
+	}
+}
+
+// SetNotifier registers the function that receives changes
+func (w *BookdropWatcher) SetNotifier(notifier func(changes []Change)) {
+	w.notifier = notifier
+}
+
+// Run watches the bookdrop until the context is cancelled. Files already in the
+// bookdrop when it starts are not reported.
+func (w *BookdropWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.config.PollInterval)
+	defer ticker.Stop()
+
+	var events <-chan booklore.Event
+	if w.events != nil {
+		var unsubscribe func()
+		events, unsubscribe = w.events.Subscribe()
+		defer unsubscribe()
+	}
+
+	var folderEvents <-chan fsnotify.Event
+	if w.config.Folder != "" {
+		fsWatcher, err := fsnotify.NewWatcher()
+		if err == nil {
+			err = fsWatcher.Add(w.config.Folder)
+		}
+		if err != nil {
+			w.logger.Warn("Failed to watch bookdrop folder, relying on polling",
+				zap.String("folder", w.config.Folder),
+				zap.Error(err))
+		} else {
+			defer fsWatcher.Close()
+			folderEvents = fsWatcher.Events
+		}
+	}
+
+	// Folder writes come in bursts, so checks wait for the folder to settle
+	settle := time.NewTimer(0)
+	<-settle.C
+	defer settle.Stop()
+
+	w.check(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.check(ctx)
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			if _, isBookdrop := event.(booklore.BookdropEvent); isBookdrop {
+				// Booklore reports a change, so the summary must not short-cut the check
+				w.lastSummary = nil
+				w.check(ctx)
+			}
+		case event, ok := <-folderEvents:
+			if !ok {
+				folderEvents = nil
+				continue
+			}
+			if event.Has(fsnotify.Create) || event.Has(fsnotify.Write) || event.Has(fsnotify.Rename) {
+				settle.Reset(settleDelay)
+			}
+		case <-settle.C:
+			w.lastSummary = nil
+			w.check(ctx)
+		}
+	}
+}
+
+// check compares the bookdrop with the last check and reports new or failing files
+func (w *BookdropWatcher) check(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	// The summary is cheap, so the file list is only fetched when it changed
+	summary, err := w.client.GetBookdropNotification(ctx)
+	if err != nil {
+		w.logger.Warn("Failed to get bookdrop summary",
+			zap.Error(err))
+		return
+	}
+	if w.seen != nil && w.lastSummary != nil && *summary == *w.lastSummary {
+		return
+	}
+
+	files, err := w.client.GetBookdropFilesNoStatus(ctx, 0, 1000)
+	if err != nil {
+		w.logger.Warn("Failed to list bookdrop files",
+			zap.Error(err))
+		return
+	}
+	w.lastSummary = summary
+
+	first := w.seen == nil
+	seen := make(map[int64]string, len(files.Content))
+	var changes []Change
+	for _, file := range files.Content {
+		seen[file.ID] = file.Status
+		if first {
+			continue
+		}
+
+		previous, known := w.seen[file.ID]
+		if known && previous == file.Status {
+			continue
+		}
+
+		switch file.Status {
+		case "FAILED":
+			changes = append(changes, Change{Kind: ChangeFailed, File: file})
+		case "PENDING_REVIEW":
+			changes = append(changes, Change{Kind: ChangePendingReview, File: file})
+		default:
+			if !known {
+				changes = append(changes, Change{Kind: ChangeNew, File: file})
+			}
+		}
+	}
+	w.seen = seen
+
+	if first {
+		w.logger.Info("Watching bookdrop",
+			zap.Int("file_count", len(seen)),
+			zap.Duration("poll_interval", w.config.PollInterval),
+			zap.String("folder", w.config.Folder))
+		return
+	}
+
+	if len(changes) > 0 && w.notifier != nil {
+		w.logger.Info("Bookdrop changed",
+			zap.Int("change_count", len(changes)))
+		w.notifier(changes)
+	}
+}